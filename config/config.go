@@ -3,18 +3,137 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ServerConfig represents a single server configuration
 type ServerConfig struct {
-	Name      string `json:"name"`
-	IP        string `json:"ip"`
-	User      string `json:"user"`
-	Port      int    `json:"port"`
-	Interface string `json:"interface"`
+	Name       string       `json:"name"`
+	IP         string       `json:"ip"`
+	User       string       `json:"user"`
+	Port       int          `json:"port"`
+	Interfaces []string     `json:"interfaces,omitempty"`
+	Interface  string       `json:"interface,omitempty"` // Legacy single interface, migrated to Interfaces on load
+	Quota      *QuotaConfig `json:"quota,omitempty"`
+	Collector  string       `json:"collector,omitempty"` // How traffic is read, see Collector* (default vnstat)
+	URL        string       `json:"url,omitempty"`       // vnStat JSON endpoint for the http collector
+	Group      string       `json:"group,omitempty"`     // e.g. a customer or region, totals are computed per group
+	Tags       []string     `json:"tags,omitempty"`      // Free-form labels for filtering, e.g. "eu" or "tier=gold"
+}
+
+// Traffic collectors
+const (
+	CollectorVnStat     = "vnstat"     // vnStat JSON over SSH
+	CollectorProcNetDev = "procnetdev" // /proc/net/dev counters over SSH, no packages needed
+	CollectorLocal      = "local"      // /proc/net/dev of the host running the monitor
+	CollectorHTTP       = "http"       // vnStat JSON fetched from URL
+)
+
+// ParseCollector validates a collector name. An empty name selects vnStat.
+func ParseCollector(name string) (string, error) {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "":
+		return CollectorVnStat, nil
+	case CollectorVnStat, CollectorProcNetDev, CollectorLocal, CollectorHTTP:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown collector '%s' (use %s, %s, %s or %s)", name,
+		CollectorVnStat, CollectorProcNetDev, CollectorLocal, CollectorHTTP)
+}
+
+// UsesSSH reports whether the server's collector logs in over SSH
+func (s ServerConfig) UsesSSH() bool {
+	switch s.GetCollector() {
+	case CollectorLocal, CollectorHTTP:
+		return false
+	}
+	return true
+}
+
+// SourceHost returns the address of a server whose collector does not log
+// in over SSH, used when no IP is given
+func (s ServerConfig) SourceHost() string {
+	switch s.GetCollector() {
+	case CollectorLocal:
+		return "127.0.0.1"
+	case CollectorHTTP:
+		if u, err := url.Parse(s.URL); err == nil {
+			return u.Hostname()
+		}
+	}
+	return ""
+}
+
+// ValidateSource checks that the server has what its collector needs
+func (s ServerConfig) ValidateSource() error {
+	if s.GetCollector() != CollectorHTTP {
+		return nil
+	}
+	if s.URL == "" {
+		return fmt.Errorf("the http collector needs a URL")
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL '%s', expected http:// or https://", s.URL)
+	}
+	return nil
+}
+
+// GetCollector returns the collector of the server, vnStat by default
+func (s ServerConfig) GetCollector() string {
+	if s.Collector == "" {
+		return CollectorVnStat
+	}
+	return s.Collector
+}
+
+// GetInterfaces returns the network interfaces to monitor, falling back to
+// the legacy single interface field
+func (s ServerConfig) GetInterfaces() []string {
+	if len(s.Interfaces) > 0 {
+		return s.Interfaces
+	}
+	if s.Interface != "" {
+		return []string{s.Interface}
+	}
+	return nil
+}
+
+// QuotaConfig describes a monthly data-transfer allowance
+type QuotaConfig struct {
+	LimitGB   float64 `json:"limit_gb"`            // 1 GB = 1024^3 bytes
+	ResetDay  int     `json:"reset_day,omitempty"` // Day of month the billing cycle starts (1-31, default 1)
+	Direction string  `json:"direction,omitempty"` // rx, tx or both (default both)
+}
+
+// Quota directions
+const (
+	QuotaRx   = "rx"
+	QuotaTx   = "tx"
+	QuotaBoth = "both"
+)
+
+// Validate checks the quota settings
+func (q QuotaConfig) Validate() error {
+	if q.LimitGB <= 0 {
+		return fmt.Errorf("quota limit must be greater than zero")
+	}
+	if q.ResetDay < 0 || q.ResetDay > 31 {
+		return fmt.Errorf("quota reset day must be between 1 and 31")
+	}
+	switch q.Direction {
+	case "", QuotaRx, QuotaTx, QuotaBoth:
+	default:
+		return fmt.Errorf("quota direction must be rx, tx or both")
+	}
+	return nil
 }
 
 // SettingsConfig represents global application settings
@@ -23,15 +142,76 @@ type SettingsConfig struct {
 	ListenPort       int    `json:"listen_port"`
 	PollInterval     int    `json:"poll_interval"`
 	AuthUser         string `json:"auth_user"`
-	AuthPass         string `json:"auth_pass"`
+	AuthPass         string `json:"auth_pass,omitempty"` // Legacy plaintext password, migrated to Users on load
 	AuthEnabled      bool   `json:"auth_enabled"`
+	DataDir          string `json:"data_dir,omitempty"`
+	MetricsToken     string `json:"metrics_token,omitempty"`
+	SessionLifetime  string `json:"session_lifetime,omitempty"` // Go duration, default 12h
+	SessionSecret    string `json:"session_secret,omitempty"`   // Key for signing session cookies, generated on first start
+	TLSEnabled       bool   `json:"tls_enabled,omitempty"`
+	TLSCert          string `json:"tls_cert,omitempty"`           // PEM certificate; a self-signed one is generated when unset
+	TLSKey           string `json:"tls_key,omitempty"`            // PEM private key
+	HTTPRedirectPort int    `json:"http_redirect_port,omitempty"` // Plain HTTP port redirecting to HTTPS, 0 disables
+	BillingWindow    string `json:"billing_window,omitempty"`     // Window of percentile billing: "month" (default) or days like "30d"
+	BillingResetDay  int    `json:"billing_reset_day,omitempty"`  // Day of month billing months start on, default 1
+
+	// Anomaly detection against each server's hour-of-week baseline
+	AnomalyZScore   float64 `json:"anomaly_zscore,omitempty"`    // Standard deviations from the baseline that flag a rate, default 4
+	AnomalyRatio    float64 `json:"anomaly_ratio,omitempty"`     // Also flag rates this many times above or below the baseline, 0 disables
+	AnomalyMinDelta uint64  `json:"anomaly_min_delta,omitempty"` // Ignore deviations smaller than this many bytes per second
+
+	// Reachability tracking
+	DownAfter     int    `json:"down_after,omitempty"`     // Consecutive failed polls before a server is marked down, default 3
+	FlapThreshold int    `json:"flap_threshold,omitempty"` // Up/down changes within the flap window that mark a server as flapping, default 4
+	FlapWindow    string `json:"flap_window,omitempty"`    // Go duration, default 1h
+}
+
+// AlertRule describes a threshold alert evaluated after each aggregate update
+type AlertRule struct {
+	Name       string  `json:"name"`
+	Server     string  `json:"server,omitempty"` // Server name, "*" for every server, empty for the aggregate
+	Field      string  `json:"field"`            // e.g. rx, tx, peak_rx, total_rx, online
+	Operator   string  `json:"operator"`         // >, >=, <, <=, ==, !=
+	Threshold  float64 `json:"threshold"`
+	For        string  `json:"for,omitempty"`        // How long the condition must hold before firing, e.g. "5m"
+	Hysteresis float64 `json:"hysteresis,omitempty"` // Margin past the threshold required to resolve
+	Severity   string  `json:"severity,omitempty"`
+}
+
+// NotificationChannel configures a destination for alert notifications
+type NotificationChannel struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // webhook, slack or smtp
+
+	// webhook and slack
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// smtp
+	SMTPHost string   `json:"smtp_host,omitempty"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	SMTPUser string   `json:"smtp_user,omitempty"`
+	SMTPPass string   `json:"smtp_pass,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+
+	// Go text/template strings; defaults are used when empty
+	SubjectTemplate string `json:"subject_template,omitempty"`
+	BodyTemplate    string `json:"body_template,omitempty"`
+
+	MaxRetries int `json:"max_retries,omitempty"`  // Default 3
+	MaxPerHour int `json:"max_per_hour,omitempty"` // Zero means unlimited
 }
 
 // Config holds the application configuration
 type Config struct {
-	Settings SettingsConfig `json:"settings"`
-	Servers  []ServerConfig `json:"servers"`
-	mu       sync.RWMutex
+	Settings      SettingsConfig        `json:"settings"`
+	Servers       []ServerConfig        `json:"servers"`
+	Alerts        []AlertRule           `json:"alerts,omitempty"`
+	Notifications []NotificationChannel `json:"notifications,omitempty"`
+	Users         []UserConfig          `json:"users,omitempty"`
+	Tokens        []APIToken            `json:"tokens,omitempty"`
+	mu            sync.RWMutex
 }
 
 // OldConfig for migration purposes
@@ -42,16 +222,15 @@ type OldConfig struct {
 const (
 	ConfigDir      = "/etc/bandwidth-monitor"
 	ConfigFilePath = "/etc/bandwidth-monitor/config.json"
+	DefaultDataDir = "/etc/bandwidth-monitor/data"
+
+	DefaultTLSCertPath = "/etc/bandwidth-monitor/tls.crt"
+	DefaultTLSKeyPath  = "/etc/bandwidth-monitor/tls.key"
 )
 
-// Load loads the configuration from file
-func Load() (*Config, error) {
-	// Ensure config directory exists
-	// We ignore error here because we might be running as non-root just to check version or help
-	// checking permissions is done in main.go
-	_ = os.MkdirAll(ConfigDir, 0755)
-	
-	config := &Config{
+// newDefaultConfig returns the configuration used before anything is saved
+func newDefaultConfig() *Config {
+	return &Config{
 		Settings: SettingsConfig{
 			DashboardEnabled: true,
 			ListenPort:       8080,
@@ -61,6 +240,16 @@ func Load() (*Config, error) {
 		},
 		Servers: []ServerConfig{},
 	}
+}
+
+// Load loads the configuration from file
+func Load() (*Config, error) {
+	// Ensure config directory exists
+	// We ignore error here because we might be running as non-root just to check version or help
+	// checking permissions is done in main.go
+	_ = os.MkdirAll(ConfigDir, 0755)
+	
+	config := newDefaultConfig()
 
 	// 1. Check for local config.json (Migration from Split-Brain)
 	localConfigPath := "config.json"
@@ -99,10 +288,117 @@ func Load() (*Config, error) {
 	if err := json.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+
+	config.migrateInterfaces()
+
+	// Never keep a plaintext dashboard password on disk
+	migrated, err := config.migrateUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate dashboard password: %w", err)
+	}
+	if migrated {
+		// The hashed users are kept in memory and written by the next Save,
+		// so read-only commands still work on a config owned by root
+		if err := config.Save(); err != nil {
+			log.Printf("Warning: failed to save migrated dashboard users: %v", err)
+		}
+	}
 	
 	return config, nil
 }
 
+// ValidInterfaceName reports whether name is a plausible network interface
+// name. Interface names are passed to vnStat on the remote shell.
+func ValidInterfaceName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':' || r == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ValidTag reports whether name is a valid tag or group name
+func ValidTag(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':' || r == '=' || r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTags parses a comma-separated list of tags, dropping duplicates
+func ParseTags(input string) ([]string, error) {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !ValidTag(tag) {
+			return nil, fmt.Errorf("invalid tag '%s' (use letters, digits and - _ . : = /)", tag)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// migrateInterfaces moves the legacy single "interface" field of each
+// server into the "interfaces" list. The new form is written on next save.
+func (c *Config) migrateInterfaces() {
+	for i := range c.Servers {
+		s := &c.Servers[i]
+		if len(s.Interfaces) == 0 && s.Interface != "" {
+			s.Interfaces = []string{s.Interface}
+		}
+		s.Interface = ""
+	}
+}
+
+// Reload re-reads the configuration file and replaces the current settings,
+// servers, alerts, notification channels, users and API tokens. On error the current
+// configuration is left untouched.
+func (c *Config) Reload() error {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	fresh := newDefaultConfig()
+	if err := json.Unmarshal(data, fresh); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	fresh.migrateInterfaces()
+	if _, err := fresh.migrateUsers(); err != nil {
+		return fmt.Errorf("failed to migrate dashboard password: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Settings = fresh.Settings
+	c.Servers = fresh.Servers
+	c.Alerts = fresh.Alerts
+	c.Notifications = fresh.Notifications
+	c.Users = fresh.Users
+	c.Tokens = fresh.Tokens
+	return nil
+}
+
 func migrateOldConfig(oldPath, newPath string, defaultConfig *Config) (*Config, error) {
 	data, err := os.ReadFile(oldPath)
 	if err != nil {
@@ -115,6 +411,7 @@ func migrateOldConfig(oldPath, newPath string, defaultConfig *Config) (*Config,
 	}
 
 	defaultConfig.Servers = oldConfig.Servers
+	defaultConfig.migrateInterfaces()
 
 	// Save new config
 	newConfigData, err := json.MarshalIndent(defaultConfig, "", "  ")
@@ -170,7 +467,14 @@ func (c *Config) Save() error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 	
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
+	// Write to a temporary file and rename it so that the running service
+	// never reloads a half-written file
+	tmpPath := configPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmpPath, configPath); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 	
@@ -271,6 +575,184 @@ func (c *Config) GetSettings() SettingsConfig {
 	return c.Settings
 }
 
+// GetAlerts returns a copy of all alert rules
+func (c *Config) GetAlerts() []AlertRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	alerts := make([]AlertRule, len(c.Alerts))
+	copy(alerts, c.Alerts)
+	return alerts
+}
+
+// GetNotifications returns a copy of all notification channels
+func (c *Config) GetNotifications() []NotificationChannel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	channels := make([]NotificationChannel, len(c.Notifications))
+	copy(channels, c.Notifications)
+	return channels
+}
+
+// GetDataDir returns the directory for persistent history data
+func (s SettingsConfig) GetDataDir() string {
+	if s.DataDir == "" {
+		return DefaultDataDir
+	}
+	return s.DataDir
+}
+
+// ValidateTLS checks that a custom certificate and key are set together
+func (s SettingsConfig) ValidateTLS() error {
+	if s.TLSCert != "" && s.TLSKey == "" {
+		return fmt.Errorf("tls_key must be set together with tls_cert")
+	}
+	if s.TLSKey != "" && s.TLSCert == "" {
+		return fmt.Errorf("tls_cert must be set together with tls_key")
+	}
+	return nil
+}
+
+// GetTLSFiles returns the certificate and key paths and whether they are the
+// defaults, for which a self-signed certificate may be generated
+func (s SettingsConfig) GetTLSFiles() (cert, key string, generated bool) {
+	if s.TLSCert == "" && s.TLSKey == "" {
+		return DefaultTLSCertPath, DefaultTLSKeyPath, true
+	}
+	return s.TLSCert, s.TLSKey, false
+}
+
+// DefaultSessionLifetime is used when session_lifetime is unset or invalid
+const DefaultSessionLifetime = 12 * time.Hour
+
+// GetSessionLifetime returns how long a dashboard login stays valid
+func (s SettingsConfig) GetSessionLifetime() time.Duration {
+	if d, err := time.ParseDuration(s.SessionLifetime); err == nil && d > 0 {
+		return d
+	}
+	return DefaultSessionLifetime
+}
+
+// MaxBillingDays is the longest rolling billing window. Five-minute history
+// is kept for 90 days, and a window reaching the retention cutoff would be
+// answered from hourly averages instead.
+const MaxBillingDays = 89
+
+// BillingWindow is the period burstable-billing percentiles cover
+type BillingWindow struct {
+	Days     int // Length of a rolling window in days, 0 for billing months
+	ResetDay int // Day of month billing months start on
+}
+
+// ParseBillingWindow parses "month" (or an empty string) and a number of
+// days such as "30d"
+func ParseBillingWindow(window string, resetDay int) (BillingWindow, error) {
+	if resetDay == 0 {
+		resetDay = 1
+	}
+	if resetDay < 1 || resetDay > 31 {
+		return BillingWindow{}, fmt.Errorf("billing reset day must be between 1 and 31")
+	}
+
+	window = strings.ToLower(strings.TrimSpace(window))
+	if window == "" || window == "month" {
+		return BillingWindow{ResetDay: resetDay}, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+	if err != nil || !strings.HasSuffix(window, "d") {
+		return BillingWindow{}, fmt.Errorf("invalid billing window '%s' (use month or a number of days like 30d)", window)
+	}
+	if days < 1 || days > MaxBillingDays {
+		return BillingWindow{}, fmt.Errorf("billing window must be between 1 and %d days", MaxBillingDays)
+	}
+	return BillingWindow{Days: days, ResetDay: resetDay}, nil
+}
+
+// String returns the window in the form ParseBillingWindow accepts
+func (w BillingWindow) String() string {
+	if w.Days > 0 {
+		return strconv.Itoa(w.Days) + "d"
+	}
+	return "month"
+}
+
+// GetBillingWindow returns the configured percentile window, falling back
+// to calendar months when it is invalid
+func (s SettingsConfig) GetBillingWindow() BillingWindow {
+	if w, err := ParseBillingWindow(s.BillingWindow, s.BillingResetDay); err == nil {
+		return w
+	}
+	return BillingWindow{ResetDay: 1}
+}
+
+// Anomaly detection defaults
+const (
+	DefaultAnomalyZScore   = 4.0
+	DefaultAnomalyMinDelta = 125000 // 1 Mbit/s
+)
+
+// AnomalyThresholds decide when a rate deviates from its baseline
+type AnomalyThresholds struct {
+	ZScore   float64
+	Ratio    float64 // 0 when disabled
+	MinDelta uint64  // Bytes per second
+}
+
+// GetAnomalyThresholds returns the anomaly thresholds, using defaults for
+// unset or invalid values
+func (s SettingsConfig) GetAnomalyThresholds() AnomalyThresholds {
+	t := AnomalyThresholds{
+		ZScore:   s.AnomalyZScore,
+		Ratio:    s.AnomalyRatio,
+		MinDelta: s.AnomalyMinDelta,
+	}
+	if t.ZScore <= 0 {
+		t.ZScore = DefaultAnomalyZScore
+	}
+	if t.Ratio <= 1 {
+		t.Ratio = 0
+	}
+	if t.MinDelta == 0 {
+		t.MinDelta = DefaultAnomalyMinDelta
+	}
+	return t
+}
+
+// Reachability defaults
+const (
+	DefaultDownAfter     = 3
+	DefaultFlapThreshold = 4
+	DefaultFlapWindow    = time.Hour
+)
+
+// ReachabilityPolicy decides when a server is down or flapping
+type ReachabilityPolicy struct {
+	DownAfter     int // Consecutive failed polls
+	FlapThreshold int // Up/down changes within FlapWindow
+	FlapWindow    time.Duration
+}
+
+// GetReachabilityPolicy returns the reachability settings, using defaults
+// for unset or invalid values
+func (s SettingsConfig) GetReachabilityPolicy() ReachabilityPolicy {
+	p := ReachabilityPolicy{
+		DownAfter:     s.DownAfter,
+		FlapThreshold: s.FlapThreshold,
+		FlapWindow:    DefaultFlapWindow,
+	}
+	if p.DownAfter <= 0 {
+		p.DownAfter = DefaultDownAfter
+	}
+	if p.FlapThreshold <= 1 {
+		p.FlapThreshold = DefaultFlapThreshold
+	}
+	if d, err := time.ParseDuration(s.FlapWindow); err == nil && d > 0 {
+		p.FlapWindow = d
+	}
+	return p
+}
+
 // UpdateSettings updates the settings
 func (c *Config) UpdateSettings(settings SettingsConfig) {
 	c.mu.Lock()
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestUpdateServer(t *testing.T) {
@@ -60,3 +62,156 @@ func TestUpdateServer(t *testing.T) {
 		t.Errorf("UpdateServer should fail for non-existent server")
 	}
 }
+
+func TestQuotaValidate(t *testing.T) {
+	valid := []QuotaConfig{
+		{LimitGB: 1000},
+		{LimitGB: 0.5, ResetDay: 31, Direction: QuotaRx},
+		{LimitGB: 2000, ResetDay: 15, Direction: QuotaBoth},
+	}
+	for _, q := range valid {
+		if err := q.Validate(); err != nil {
+			t.Errorf("Validate(%+v) failed: %v", q, err)
+		}
+	}
+
+	invalid := []QuotaConfig{
+		{LimitGB: 0},
+		{LimitGB: 100, ResetDay: 32},
+		{LimitGB: 100, ResetDay: -1},
+		{LimitGB: 100, Direction: "up"},
+	}
+	for _, q := range invalid {
+		if err := q.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", q)
+		}
+	}
+}
+
+func TestMigrateInterfaces(t *testing.T) {
+	cfg := &Config{
+		Servers: []ServerConfig{
+			{Name: "legacy", Interface: "eth0"},
+			{Name: "multi", Interfaces: []string{"eth0", "wg0"}},
+			{Name: "both", Interface: "eth1", Interfaces: []string{"ens3"}},
+		},
+	}
+
+	cfg.migrateInterfaces()
+
+	want := map[string][]string{
+		"legacy": {"eth0"},
+		"multi":  {"eth0", "wg0"},
+		"both":   {"ens3"},
+	}
+	for _, s := range cfg.Servers {
+		if s.Interface != "" {
+			t.Errorf("Legacy interface field not cleared for %s", s.Name)
+		}
+		got := s.GetInterfaces()
+		if strings.Join(got, ",") != strings.Join(want[s.Name], ",") {
+			t.Errorf("Interfaces mismatch for %s. Got %v, want %v", s.Name, got, want[s.Name])
+		}
+	}
+
+	legacy := ServerConfig{Interface: "eth0"}
+	if got := legacy.GetInterfaces(); len(got) != 1 || got[0] != "eth0" {
+		t.Errorf("GetInterfaces fallback mismatch. Got %v", got)
+	}
+}
+
+func TestParseBillingWindow(t *testing.T) {
+	valid := []struct {
+		window   string
+		resetDay int
+		want     BillingWindow
+	}{
+		{"", 0, BillingWindow{ResetDay: 1}},
+		{"Month", 15, BillingWindow{ResetDay: 15}},
+		{"30d", 0, BillingWindow{Days: 30, ResetDay: 1}},
+	}
+	for _, c := range valid {
+		got, err := ParseBillingWindow(c.window, c.resetDay)
+		if err != nil || got != c.want {
+			t.Errorf("ParseBillingWindow(%q, %d) = %+v, %v; want %+v", c.window, c.resetDay, got, err, c.want)
+		}
+	}
+
+	for _, window := range []string{"30", "0d", "90d", "week"} {
+		if _, err := ParseBillingWindow(window, 1); err == nil {
+			t.Errorf("ParseBillingWindow(%q) should fail", window)
+		}
+	}
+	if _, err := ParseBillingWindow("month", 32); err == nil {
+		t.Error("Reset day 32 should fail")
+	}
+
+	if w := (SettingsConfig{BillingWindow: "bogus"}).GetBillingWindow(); w.String() != "month" {
+		t.Errorf("Invalid window should fall back to month, got %s", w)
+	}
+}
+
+func TestGetAnomalyThresholds(t *testing.T) {
+	defaults := (SettingsConfig{}).GetAnomalyThresholds()
+	if defaults.ZScore != DefaultAnomalyZScore || defaults.Ratio != 0 || defaults.MinDelta != DefaultAnomalyMinDelta {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+
+	custom := (SettingsConfig{AnomalyZScore: 3, AnomalyRatio: 2.5, AnomalyMinDelta: 1000}).GetAnomalyThresholds()
+	if custom.ZScore != 3 || custom.Ratio != 2.5 || custom.MinDelta != 1000 {
+		t.Errorf("Unexpected thresholds: %+v", custom)
+	}
+
+	// A ratio of 1 or less would flag every rate
+	if got := (SettingsConfig{AnomalyRatio: 0.5}).GetAnomalyThresholds(); got.Ratio != 0 {
+		t.Errorf("Ratio 0.5 should be disabled, got %g", got.Ratio)
+	}
+}
+
+func TestGetReachabilityPolicy(t *testing.T) {
+	defaults := (SettingsConfig{}).GetReachabilityPolicy()
+	if defaults.DownAfter != DefaultDownAfter || defaults.FlapThreshold != DefaultFlapThreshold || defaults.FlapWindow != DefaultFlapWindow {
+		t.Errorf("Unexpected defaults: %+v", defaults)
+	}
+
+	custom := (SettingsConfig{DownAfter: 1, FlapThreshold: 6, FlapWindow: "30m"}).GetReachabilityPolicy()
+	if custom.DownAfter != 1 || custom.FlapThreshold != 6 || custom.FlapWindow != 30*time.Minute {
+		t.Errorf("Unexpected policy: %+v", custom)
+	}
+
+	// A single change is not flapping, and invalid windows fall back
+	if got := (SettingsConfig{FlapThreshold: 1, FlapWindow: "-5m"}).GetReachabilityPolicy(); got.FlapThreshold != DefaultFlapThreshold || got.FlapWindow != DefaultFlapWindow {
+		t.Errorf("Invalid flap settings should use defaults, got %+v", got)
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	for _, s := range []SettingsConfig{{}, {TLSCert: "a.crt", TLSKey: "a.key"}} {
+		if err := s.ValidateTLS(); err != nil {
+			t.Errorf("ValidateTLS(%q, %q) failed: %v", s.TLSCert, s.TLSKey, err)
+		}
+	}
+
+	if err := (SettingsConfig{TLSCert: "a.crt"}).ValidateTLS(); err == nil || !strings.Contains(err.Error(), "tls_key") {
+		t.Errorf("Expected an error naming tls_key, got %v", err)
+	}
+	if err := (SettingsConfig{TLSKey: "a.key"}).ValidateTLS(); err == nil || !strings.Contains(err.Error(), "tls_cert") {
+		t.Errorf("Expected an error naming tls_cert, got %v", err)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags(" eu, tier=gold,,eu ,region/fra ")
+	if err != nil {
+		t.Fatalf("ParseTags failed: %v", err)
+	}
+	if want := []string{"eu", "tier=gold", "region/fra"}; strings.Join(tags, ",") != strings.Join(want, ",") {
+		t.Errorf("ParseTags mismatch. Got %v, want %v", tags, want)
+	}
+
+	for _, input := range []string{"eu,a b", "x;y", strings.Repeat("a", 65)} {
+		if _, err := ParseTags(input); err == nil {
+			t.Errorf("ParseTags(%q) should fail", input)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix starts every generated API token so they are easy to spot in logs and scanners
+const APITokenPrefix = "bmt_"
+
+// APIToken is a named bearer token for machine access to the dashboard API.
+// Only a SHA-256 hash of the token is stored.
+type APIToken struct {
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	ReadOnly  bool       `json:"read_only,omitempty"` // Only GET requests; otherwise the token has admin access
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Never expires when unset
+}
+
+// Expired reports whether the token is past its expiry
+func (t APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseExpiry parses a token lifetime such as "90d", "12h" or "30m".
+// An empty string means no expiry and returns zero.
+func ParseExpiry(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid expiry '%s'", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid expiry '%s'", s)
+	}
+	return d, nil
+}
+
+// GetTokens returns a copy of all API tokens
+func (c *Config) GetTokens() []APIToken {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tokens := make([]APIToken, len(c.Tokens))
+	copy(tokens, c.Tokens)
+	return tokens
+}
+
+// AddToken creates a named API token and returns its secret value, which is
+// shown once and never stored. A zero lifetime means the token never expires.
+func (c *Config) AddToken(name string, readOnly bool, lifetime time.Duration, now time.Time) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("token name cannot be empty")
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := APITokenPrefix + hex.EncodeToString(raw)
+
+	token := APIToken{
+		Name:      name,
+		Hash:      hashToken(secret),
+		ReadOnly:  readOnly,
+		CreatedAt: now.UTC(),
+	}
+	if lifetime > 0 {
+		expires := now.Add(lifetime).UTC()
+		token.ExpiresAt = &expires
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range c.Tokens {
+		if t.Name == name {
+			return "", fmt.Errorf("token '%s' already exists", name)
+		}
+	}
+	c.Tokens = append(c.Tokens, token)
+	return secret, nil
+}
+
+// RemoveToken revokes an API token by name
+func (c *Config) RemoveToken(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, t := range c.Tokens {
+		if t.Name == name {
+			c.Tokens = append(c.Tokens[:i], c.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("token '%s' not found", name)
+}
+
+// FindToken returns the unexpired API token matching secret, or nil
+func (c *Config) FindToken(secret string, now time.Time) *APIToken {
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return nil
+	}
+	hash := []byte(hashToken(secret))
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, t := range c.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), hash) == 1 {
+			if t.Expired(now) {
+				return nil
+			}
+			return &t
+		}
+	}
+	return nil
+}
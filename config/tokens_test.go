@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPITokens(t *testing.T) {
+	cfg := &Config{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	secret, err := cfg.AddToken("grafana", true, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("AddToken failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		t.Errorf("Token missing prefix: %s", secret)
+	}
+	if strings.Contains(cfg.Tokens[0].Hash, strings.TrimPrefix(secret, APITokenPrefix)) {
+		t.Errorf("Token stored in plaintext")
+	}
+	if _, err := cfg.AddToken("grafana", false, 0, now); err == nil {
+		t.Errorf("Expected error for duplicate token name")
+	}
+
+	if tok := cfg.FindToken(secret, now.Add(time.Hour)); tok == nil || tok.Name != "grafana" || !tok.ReadOnly {
+		t.Errorf("Valid token not found: %+v", tok)
+	}
+	if tok := cfg.FindToken(secret, now.Add(24*time.Hour)); tok != nil {
+		t.Errorf("Expired token accepted")
+	}
+	if tok := cfg.FindToken(secret+"x", now); tok != nil {
+		t.Errorf("Wrong token accepted")
+	}
+
+	if err := cfg.RemoveToken("grafana"); err != nil {
+		t.Fatalf("RemoveToken failed: %v", err)
+	}
+	if tok := cfg.FindToken(secret, now); tok != nil {
+		t.Errorf("Revoked token accepted")
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{"", 0, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"0d", 0, true},
+		{"-5m", 0, true},
+		{"soon", 0, true},
+	}
+	for _, c := range cases {
+		got, err := ParseExpiry(c.in)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("ParseExpiry(%q) = %s, %v", c.in, got, err)
+		}
+	}
+}
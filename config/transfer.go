@@ -0,0 +1,689 @@
+package config
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServerFormat is a file format for importing and exporting servers
+type ServerFormat string
+
+// Supported server file formats
+const (
+	FormatCSV     ServerFormat = "csv"
+	FormatYAML    ServerFormat = "yaml"
+	FormatAnsible ServerFormat = "ansible" // INI inventory
+)
+
+// ansibleGroup is the inventory group servers are exported to
+const ansibleGroup = "bandwidth_monitor"
+
+// serverFields are the columns and keys written on export, in order
+var serverFields = []string{"name", "ip", "user", "port", "interfaces", "collector", "url", "group", "tags", "quota_gb", "quota_reset_day", "quota_direction"}
+
+// credentialFields may be given on import to log in to a server for setup
+var credentialFields = []string{"key", "password_file", "password"}
+
+// ansibleVars maps Ansible inventory variables to server fields. Other
+// fields use a "bm_" prefix.
+var ansibleVars = map[string]string{
+	"ansible_host":                 "ip",
+	"ansible_user":                 "user",
+	"ansible_port":                 "port",
+	"ansible_ssh_private_key_file": "key",
+	"ansible_password":             "password",
+}
+
+// ParseServerFormat parses a format name
+func ParseServerFormat(s string) (ServerFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return FormatCSV, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "ansible", "ini", "inventory":
+		return FormatAnsible, nil
+	}
+	return "", fmt.Errorf("unknown format '%s' (use csv, yaml or ansible)", s)
+}
+
+// FormatFromPath guesses the format of a file from its extension
+func FormatFromPath(path string) (ServerFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".ini", ".inventory", "":
+		return FormatAnsible, nil
+	}
+	return "", fmt.Errorf("cannot guess the format of '%s', use --format", path)
+}
+
+// ImportEntry is a server read from an import file together with the
+// credentials used to set it up. Credentials are never written to config.json.
+type ImportEntry struct {
+	Server       ServerConfig
+	Line         int    // Line in the import file, for error messages
+	KeyFile      string // Private key to log in with
+	PasswordFile string // File containing the SSH password
+	Password     string // SSH password given inline (Ansible ansible_password)
+}
+
+// serverRecord is one server as flat string fields
+type serverRecord struct {
+	line   int
+	fields map[string]string
+}
+
+// ReadServers reads servers from r in the given format. Every entry is
+// validated; names must be unique within the file.
+func ReadServers(r io.Reader, format ServerFormat) ([]ImportEntry, error) {
+	var records []serverRecord
+	var err error
+	switch format {
+	case FormatCSV:
+		records, err = readCSVRecords(r)
+	case FormatYAML:
+		records, err = readYAMLRecords(r)
+	case FormatAnsible:
+		records, err = readAnsibleRecords(r)
+	default:
+		return nil, fmt.Errorf("unknown format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ImportEntry, 0, len(records))
+	seen := make(map[string]int)
+	for _, rec := range records {
+		entry, err := rec.entry()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", rec.line, err)
+		}
+		if line, ok := seen[entry.Server.Name]; ok {
+			return nil, fmt.Errorf("line %d: server '%s' is already defined on line %d", rec.line, entry.Server.Name, line)
+		}
+		seen[entry.Server.Name] = rec.line
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// entry validates a record and converts it to an ImportEntry
+func (rec serverRecord) entry() (ImportEntry, error) {
+	known := make(map[string]bool)
+	for _, f := range append(serverFields, credentialFields...) {
+		known[f] = true
+	}
+	for key := range rec.fields {
+		if !known[key] {
+			return ImportEntry{}, fmt.Errorf("unknown field '%s'", key)
+		}
+	}
+
+	f := rec.fields
+	entry := ImportEntry{
+		Line:         rec.line,
+		KeyFile:      f["key"],
+		PasswordFile: f["password_file"],
+		Password:     f["password"],
+		Server: ServerConfig{
+			Name: f["name"],
+			IP:   f["ip"],
+			User: f["user"],
+			Port: 22,
+		},
+	}
+	s := &entry.Server
+
+	if s.Name == "" {
+		return entry, fmt.Errorf("name is required")
+	}
+
+	if v := f["collector"]; v != "" {
+		collector, err := ParseCollector(v)
+		if err != nil {
+			return entry, fmt.Errorf("invalid collector for '%s': %w", s.Name, err)
+		}
+		if collector != CollectorVnStat {
+			s.Collector = collector
+		}
+	}
+	s.URL = f["url"]
+	if err := s.ValidateSource(); err != nil {
+		return entry, fmt.Errorf("invalid source for '%s': %w", s.Name, err)
+	}
+
+	// Servers that are not polled over SSH may leave out their IP
+	if s.IP == "" {
+		s.IP = s.SourceHost()
+	}
+	if s.IP == "" {
+		return entry, fmt.Errorf("ip is required for '%s'", s.Name)
+	}
+	if s.User == "" {
+		s.User = "root"
+	}
+	if v := f["port"]; v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port < 1 || port > 65535 {
+			return entry, fmt.Errorf("invalid port '%s' for '%s'", v, s.Name)
+		}
+		s.Port = port
+	}
+
+	for _, name := range strings.Split(f["interfaces"], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !ValidInterfaceName(name) {
+			return entry, fmt.Errorf("invalid interface name '%s' for '%s'", name, s.Name)
+		}
+		s.Interfaces = append(s.Interfaces, name)
+	}
+
+	if v := f["group"]; v != "" {
+		if !ValidTag(v) {
+			return entry, fmt.Errorf("invalid group '%s' for '%s'", v, s.Name)
+		}
+		s.Group = v
+	}
+	tags, err := ParseTags(f["tags"])
+	if err != nil {
+		return entry, fmt.Errorf("%w for '%s'", err, s.Name)
+	}
+	s.Tags = tags
+
+	if v := f["quota_gb"]; v != "" {
+		limit, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return entry, fmt.Errorf("invalid quota_gb '%s' for '%s'", v, s.Name)
+		}
+		quota := QuotaConfig{LimitGB: limit, Direction: strings.ToLower(f["quota_direction"])}
+		if d := f["quota_reset_day"]; d != "" {
+			if quota.ResetDay, err = strconv.Atoi(d); err != nil {
+				return entry, fmt.Errorf("invalid quota_reset_day '%s' for '%s'", d, s.Name)
+			}
+		}
+		if err := quota.Validate(); err != nil {
+			return entry, fmt.Errorf("invalid quota for '%s': %w", s.Name, err)
+		}
+		s.Quota = &quota
+	}
+	return entry, nil
+}
+
+// serverValues returns the export fields of a server, empty when unset
+func serverValues(s ServerConfig) map[string]string {
+	v := map[string]string{
+		"name":       s.Name,
+		"ip":         s.IP,
+		"user":       s.User,
+		"port":       strconv.Itoa(s.Port),
+		"interfaces": strings.Join(s.GetInterfaces(), ","),
+		"collector":  s.Collector,
+		"url":        s.URL,
+		"group":      s.Group,
+		"tags":       strings.Join(s.Tags, ","),
+	}
+	if s.Quota != nil {
+		v["quota_gb"] = strconv.FormatFloat(s.Quota.LimitGB, 'f', -1, 64)
+		if s.Quota.ResetDay != 0 {
+			v["quota_reset_day"] = strconv.Itoa(s.Quota.ResetDay)
+		}
+		v["quota_direction"] = s.Quota.Direction
+	}
+	return v
+}
+
+// WriteServers writes servers to w in the given format
+func WriteServers(w io.Writer, format ServerFormat, servers []ServerConfig) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, servers)
+	case FormatYAML:
+		return writeYAML(w, servers)
+	case FormatAnsible:
+		return writeAnsible(w, servers)
+	}
+	return fmt.Errorf("unknown format '%s'", format)
+}
+
+func readCSVRecords(r io.Reader) ([]serverRecord, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	var records []serverRecord
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		rec := serverRecord{line: line, fields: make(map[string]string)}
+		for i, value := range row {
+			if value = strings.TrimSpace(value); value != "" {
+				rec.fields[header[i]] = value
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+func writeCSV(w io.Writer, servers []ServerConfig) error {
+	cw := csv.NewWriter(w)
+	cw.Write(serverFields)
+	for _, s := range servers {
+		v := serverValues(s)
+		row := make([]string, len(serverFields))
+		for i, f := range serverFields {
+			row[i] = v[f]
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readYAMLRecords reads the subset of YAML written by writeYAML: an
+// optional top-level "servers:" key holding a list of flat mappings whose
+// values are scalars or lists of scalars
+func readYAMLRecords(r io.Reader) ([]serverRecord, error) {
+	var records []serverRecord
+	var cur *serverRecord
+	itemIndent := -1
+	listKey := "" // Key of a block list being read
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := stripYAMLComment(scanner.Text())
+		line := strings.TrimSpace(raw)
+		if line == "" || line == "---" {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+		if strings.Contains(raw[:indent+1], "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+		if indent == 0 && line == "servers:" {
+			continue
+		}
+
+		if item, ok := cutListItem(line); ok {
+			if listKey != "" && indent > itemIndent {
+				value, err := yamlScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				cur.appendList(listKey, value)
+				continue
+			}
+
+			records = append(records, serverRecord{line: lineNo, fields: make(map[string]string)})
+			cur = &records[len(records)-1]
+			itemIndent = indent
+			listKey = ""
+			if item == "" {
+				continue
+			}
+			line = item
+		} else if cur == nil || indent <= itemIndent {
+			return nil, fmt.Errorf("line %d: expected a list of servers", lineNo)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		listKey = ""
+		switch {
+		case value == "":
+			listKey = key
+			cur.fields[key] = ""
+		case strings.HasPrefix(value, "["):
+			if !strings.HasSuffix(value, "]") {
+				return nil, fmt.Errorf("line %d: unterminated list", lineNo)
+			}
+			cur.fields[key] = ""
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				v, err := yamlScalar(item)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				cur.appendList(key, v)
+			}
+		default:
+			v, err := yamlScalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			cur.fields[key] = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read YAML: %w", err)
+	}
+
+	// Drop empty values such as "quota_gb:" with nothing after it
+	for _, rec := range records {
+		for key, value := range rec.fields {
+			if value == "" {
+				delete(rec.fields, key)
+			}
+		}
+	}
+	return records, nil
+}
+
+// appendList adds a value to a comma-separated list field
+func (rec *serverRecord) appendList(key, value string) {
+	if rec.fields[key] != "" {
+		value = rec.fields[key] + "," + value
+	}
+	rec.fields[key] = value
+}
+
+// cutListItem returns the rest of a "- item" line
+func cutListItem(line string) (string, bool) {
+	if line == "-" {
+		return "", true
+	}
+	if rest, ok := strings.CutPrefix(line, "- "); ok {
+		return strings.TrimSpace(rest), true
+	}
+	return "", false
+}
+
+// stripYAMLComment removes a trailing comment outside of quotes
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
+
+// yamlScalar unquotes a YAML scalar
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s == "~" || s == "null":
+		return "", nil
+	}
+	return s, nil
+}
+
+// yamlQuote quotes a scalar when it would otherwise be read differently
+func yamlQuote(s string) string {
+	if s == "" || s == "~" || s == "null" || strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s, ":#[]{},&*!|>'\"%@`") || strings.HasPrefix(s, "-") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func writeYAML(w io.Writer, servers []ServerConfig) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("servers:\n")
+	for _, s := range servers {
+		v := serverValues(s)
+		prefix := "  - "
+		for _, f := range serverFields {
+			if v[f] == "" {
+				continue
+			}
+			value := yamlQuote(v[f])
+			if f == "interfaces" || f == "tags" {
+				items := strings.Split(v[f], ",")
+				for i := range items {
+					items[i] = yamlQuote(items[i])
+				}
+				value = "[" + strings.Join(items, ", ") + "]"
+			}
+			fmt.Fprintf(bw, "%s%s: %s\n", prefix, f, value)
+			prefix = "    "
+		}
+	}
+	return bw.Flush()
+}
+
+// readAnsibleRecords reads hosts from an INI inventory. Every host in every
+// group is imported; "[group:vars]" sections apply to the hosts of that
+// group and "[all:vars]" to every host. The host alias becomes the server
+// name and ansible_host its IP.
+func readAnsibleRecords(r io.Reader) ([]serverRecord, error) {
+	var records []serverRecord
+	index := make(map[string]int)       // Host -> position in records
+	groups := make(map[string][]string) // Group -> hosts
+	groupVars := make(map[string]map[string]string)
+	section, kind := "ungrouped", "hosts"
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid section header", lineNo)
+			}
+			section, kind = line[1:len(line)-1], "hosts"
+			if name, suffix, ok := strings.Cut(section, ":"); ok {
+				section, kind = name, suffix
+			}
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected 'key=value'", lineNo)
+			}
+			if groupVars[section] == nil {
+				groupVars[section] = make(map[string]string)
+			}
+			groupVars[section][strings.TrimSpace(key)] = unquoteINI(strings.TrimSpace(value))
+		case "hosts":
+			fields, err := splitINIFields(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			host := fields[0]
+			groups[section] = append(groups[section], host)
+			if _, ok := index[host]; !ok {
+				index[host] = len(records)
+				records = append(records, serverRecord{line: lineNo, fields: map[string]string{"name": host}})
+			}
+			rec := records[index[host]]
+			for _, kv := range fields[1:] {
+				key, value, ok := strings.Cut(kv, "=")
+				if !ok {
+					return nil, fmt.Errorf("line %d: expected 'key=value', got '%s'", lineNo, kv)
+				}
+				if err := setAnsibleVar(rec, key, unquoteINI(value)); err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+			}
+		}
+		// Other sections such as [group:children] are ignored
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	// Group variables fill in what the host line did not set. As in
+	// Ansible, named groups take precedence over [all:vars], and of two
+	// named groups the one later in name order wins.
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		if g != "all" {
+			names = append(names, g)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for _, g := range append(names, "all") {
+		vars := groupVars[g]
+		hosts := groups[g]
+		if g == "all" {
+			hosts = make([]string, 0, len(index))
+			for h := range index {
+				hosts = append(hosts, h)
+			}
+		}
+		for _, h := range hosts {
+			rec := records[index[h]]
+			for key, value := range vars {
+				field, ok := ansibleField(key)
+				if !ok || rec.fields[field] != "" {
+					continue
+				}
+				if err := setAnsibleVar(rec, key, value); err != nil {
+					return nil, fmt.Errorf("[%s:vars]: %w", g, err)
+				}
+			}
+		}
+	}
+
+	// Hosts without ansible_host are reached by their name
+	for _, rec := range records {
+		if rec.fields["ip"] == "" {
+			rec.fields["ip"] = rec.fields["name"]
+		}
+	}
+	return records, nil
+}
+
+// ansibleField maps an inventory variable to a server field. Unrelated
+// variables are ignored.
+func ansibleField(key string) (string, bool) {
+	if field, ok := ansibleVars[key]; ok {
+		return field, true
+	}
+	if field, ok := strings.CutPrefix(key, "bm_"); ok {
+		return field, true
+	}
+	return "", false
+}
+
+// setAnsibleVar sets the field of an inventory variable
+func setAnsibleVar(rec serverRecord, key, value string) error {
+	field, ok := ansibleField(key)
+	if !ok {
+		return nil
+	}
+	if field == "name" {
+		return fmt.Errorf("bm_name is not supported, the host alias is the server name")
+	}
+	rec.fields[field] = value
+	return nil
+}
+
+// splitINIFields splits a host line on whitespace, keeping quoted values together
+func splitINIFields(line string) ([]string, error) {
+	var fields []string
+	var b strings.Builder
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			b.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			b.WriteRune(r)
+		case r == ' ' || r == '\t':
+			if b.Len() > 0 {
+				fields = append(fields, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if b.Len() > 0 {
+		fields = append(fields, b.String())
+	}
+	return fields, nil
+}
+
+// unquoteINI removes matching quotes around an inventory value
+func unquoteINI(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func writeAnsible(w io.Writer, servers []ServerConfig) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[%s]\n", ansibleGroup)
+	for _, s := range servers {
+		v := serverValues(s)
+		bw.WriteString(v["name"])
+		fmt.Fprintf(bw, " ansible_host=%s ansible_user=%s ansible_port=%s", v["ip"], v["user"], v["port"])
+		for _, f := range serverFields[4:] {
+			if v[f] != "" {
+				fmt.Fprintf(bw, " bm_%s=%s", f, v[f])
+			}
+		}
+		bw.WriteString("\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerRoundTrip(t *testing.T) {
+	servers := []ServerConfig{
+		{Name: "web1", IP: "203.0.113.10", User: "root", Port: 22, Interfaces: []string{"eth0", "eth1"}, Group: "acme", Tags: []string{"eu", "tier=gold"}},
+		{Name: "db #1", IP: "2001:db8::1", User: "deploy", Port: 2222, Interfaces: []string{"ens3"}, Collector: CollectorProcNetDev,
+			Quota: &QuotaConfig{LimitGB: 1500.5, ResetDay: 15, Direction: QuotaTx}},
+		{Name: "edge", IP: "edge.example.com", User: "root", Port: 22, Collector: CollectorHTTP, URL: "https://edge.example.com:8443/vnstat.json?key=abc"},
+	}
+
+	for _, format := range []ServerFormat{FormatCSV, FormatYAML, FormatAnsible} {
+		if format == FormatAnsible {
+			// Inventory host names cannot contain spaces
+			servers[1].Name = "db1"
+		}
+
+		var buf bytes.Buffer
+		if err := WriteServers(&buf, format, servers); err != nil {
+			t.Fatalf("%s: WriteServers failed: %v", format, err)
+		}
+		entries, err := ReadServers(&buf, format)
+		if err != nil {
+			t.Fatalf("%s: ReadServers failed: %v\n%s", format, err, buf.String())
+		}
+		if len(entries) != len(servers) {
+			t.Fatalf("%s: Expected %d servers, got %d", format, len(servers), len(entries))
+		}
+		for i, e := range entries {
+			if !reflect.DeepEqual(e.Server, servers[i]) {
+				t.Errorf("%s: Server %d = %+v, want %+v", format, i, e.Server, servers[i])
+			}
+		}
+	}
+}
+
+func TestReadServersCSV(t *testing.T) {
+	input := `# onboarding batch
+name,ip,port,interfaces,key
+web1,203.0.113.10,,,/root/.ssh/id_ed25519
+web2,203.0.113.11,2222,"eth0,eth1",
+`
+	entries, err := ReadServers(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("ReadServers failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(entries))
+	}
+	if e := entries[0]; e.Server.User != "root" || e.Server.Port != 22 || e.KeyFile != "/root/.ssh/id_ed25519" || e.Line != 3 {
+		t.Errorf("Unexpected first entry: %+v", e)
+	}
+	if got := entries[1].Server.Interfaces; len(got) != 2 || got[1] != "eth1" {
+		t.Errorf("Unexpected interfaces: %v", got)
+	}
+}
+
+func TestReadServersYAML(t *testing.T) {
+	input := `---
+servers:
+  # Frankfurt
+  - name: web1
+    ip: "203.0.113.10"   # primary
+    interfaces:
+      - eth0
+      - 'wg0'
+    password_file: /root/pass
+  - name: web2
+    ip: 203.0.113.11
+    port: 2222
+`
+	entries, err := ReadServers(strings.NewReader(input), FormatYAML)
+	if err != nil {
+		t.Fatalf("ReadServers failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(entries))
+	}
+	if e := entries[0]; e.Server.IP != "203.0.113.10" || len(e.Server.Interfaces) != 2 || e.PasswordFile != "/root/pass" {
+		t.Errorf("Unexpected first entry: %+v", e)
+	}
+	if e := entries[1]; e.Server.Port != 2222 || e.Server.Interfaces != nil {
+		t.Errorf("Unexpected second entry: %+v", e)
+	}
+}
+
+func TestReadServersAnsible(t *testing.T) {
+	input := `[all:vars]
+ansible_user=admin
+
+[web]
+web1 ansible_host=203.0.113.10 bm_interfaces=eth0
+web2.example.com ansible_port=2222 ansible_user=root ansible_connection=ssh
+
+[web:vars]
+ansible_ssh_private_key_file="/root/.ssh/web key"
+ansible_port=22
+ansible_user=deploy
+
+[db]
+db1 ansible_host=203.0.113.20 ansible_password='s3cret'
+
+[prod:children]
+web
+`
+	entries, err := ReadServers(strings.NewReader(input), FormatAnsible)
+	if err != nil {
+		t.Fatalf("ReadServers failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 servers, got %d", len(entries))
+	}
+
+	web1, web2, db1 := entries[0], entries[1], entries[2]
+	if web1.Server.User != "deploy" || web1.Server.Port != 22 || web1.KeyFile != "/root/.ssh/web key" {
+		t.Errorf("Group vars not applied to web1, or [all:vars] won over them: %+v", web1)
+	}
+	if web2.Server.IP != "web2.example.com" || web2.Server.Port != 2222 || web2.Server.User != "root" {
+		t.Errorf("Host vars should win over group vars: %+v", web2)
+	}
+	if db1.Password != "s3cret" || db1.KeyFile != "" || db1.Server.User != "admin" {
+		t.Errorf("Unexpected db1 entry: %+v", db1)
+	}
+}
+
+func TestReadServersErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		format ServerFormat
+		input  string
+		want   string
+	}{
+		{"missing ip", FormatCSV, "name,ip\nweb1,\n", "ip is required"},
+		{"bad port", FormatCSV, "name,ip,port\nweb1,1.2.3.4,70000\n", "invalid port"},
+		{"http without url", FormatCSV, "name,collector\nweb1,http\n", "needs a URL"},
+		{"bad url", FormatYAML, "- name: a\n  collector: http\n  url: ftp://host/x\n", "invalid URL"},
+		{"bad collector", FormatCSV, "name,ip,collector\nweb1,1.2.3.4,snmp\n", "unknown collector 'snmp'"},
+		{"unknown column", FormatCSV, "name,ip,colour\nweb1,1.2.3.4,red\n", "unknown field 'colour'"},
+		{"duplicate", FormatYAML, "- name: a\n  ip: 1.1.1.1\n- name: a\n  ip: 2.2.2.2\n", "already defined on line 1"},
+		{"bad interface", FormatYAML, "- name: a\n  ip: 1.1.1.1\n  interfaces: [eth0, 'x;y']\n", "invalid interface name"},
+		{"bad group", FormatCSV, "name,ip,group\nweb1,1.2.3.4,acme corp\n", "invalid group"},
+		{"bad tag", FormatYAML, "- name: a\n  ip: 1.1.1.1\n  tags: [eu, 'a b']\n", "invalid tag 'a b'"},
+		{"bad quota", FormatAnsible, "a bm_quota_gb=10 bm_quota_direction=up\n", "invalid quota"},
+		{"not a list", FormatYAML, "name: a\n", "expected a list"},
+	}
+
+	for _, c := range cases {
+		_, err := ReadServers(strings.NewReader(c.input), c.format)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got error %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestReadServersWithoutSSH(t *testing.T) {
+	input := `name,collector,url
+monitor,local,
+edge,http,https://edge.example.com/vnstat.json
+`
+	entries, err := ReadServers(strings.NewReader(input), FormatCSV)
+	if err != nil {
+		t.Fatalf("ReadServers failed: %v", err)
+	}
+	if s := entries[0].Server; s.IP != "127.0.0.1" || s.Collector != CollectorLocal || s.UsesSSH() {
+		t.Errorf("Unexpected local server: %+v", s)
+	}
+	if s := entries[1].Server; s.IP != "edge.example.com" || s.URL != "https://edge.example.com/vnstat.json" || s.UsesSSH() {
+		t.Errorf("Unexpected http server: %+v", s)
+	}
+}
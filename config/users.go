@@ -0,0 +1,203 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role is the access level of a dashboard user
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // Read-only dashboards
+	RoleOperator Role = "operator" // Acknowledge alerts and trigger re-polls
+	RoleAdmin    Role = "admin"    // Manage servers and settings
+)
+
+// roleRanks orders roles so that higher roles include lower ones
+var roleRanks = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleRanks[r]; !ok {
+		return "", fmt.Errorf("unknown role '%s' (use viewer, operator or admin)", s)
+	}
+	return r, nil
+}
+
+// Allows reports whether the role grants the access of required
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required] && roleRanks[r] > 0
+}
+
+// UserConfig is a dashboard account. Only a bcrypt hash of the password is stored.
+type UserConfig struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Role         Role   `json:"role"`
+}
+
+// HashPassword returns the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the user's hash
+func (u UserConfig) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// GetUsers returns a copy of all users
+func (c *Config) GetUsers() []UserConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	users := make([]UserConfig, len(c.Users))
+	copy(users, c.Users)
+	return users
+}
+
+// GetUser retrieves a user by name
+func (c *Config) GetUser(username string) *UserConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, u := range c.Users {
+		if u.Username == username {
+			return &u
+		}
+	}
+	return nil
+}
+
+// AddUser adds a new user with the given password and role
+func (c *Config) AddUser(username, password string, role Role) error {
+	username = strings.TrimSpace(username)
+	if username == "" || strings.ContainsAny(username, ": \t") {
+		return fmt.Errorf("invalid username '%s'", username)
+	}
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("unknown role '%s'", role)
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, u := range c.Users {
+		if u.Username == username {
+			return fmt.Errorf("user '%s' already exists", username)
+		}
+	}
+	c.Users = append(c.Users, UserConfig{Username: username, PasswordHash: hash, Role: role})
+	return nil
+}
+
+// SetUserPassword resets the password of an existing user
+func (c *Config) SetUserPassword(username, password string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Users {
+		if c.Users[i].Username == username {
+			c.Users[i].PasswordHash = hash
+			return nil
+		}
+	}
+	return fmt.Errorf("user '%s' not found", username)
+}
+
+// SetUserRole changes the role of an existing user. The last admin cannot
+// be demoted.
+func (c *Config) SetUserRole(username string, role Role) error {
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("unknown role '%s'", role)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Users {
+		if c.Users[i].Username != username {
+			continue
+		}
+		if c.Users[i].Role == RoleAdmin && role != RoleAdmin && c.countAdmins() == 1 {
+			return fmt.Errorf("cannot demote the last admin")
+		}
+		c.Users[i].Role = role
+		return nil
+	}
+	return fmt.Errorf("user '%s' not found", username)
+}
+
+// RemoveUser removes a user by name. The last admin cannot be removed.
+func (c *Config) RemoveUser(username string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, u := range c.Users {
+		if u.Username != username {
+			continue
+		}
+		if u.Role == RoleAdmin && c.countAdmins() == 1 {
+			return fmt.Errorf("cannot remove the last admin")
+		}
+		c.Users = append(c.Users[:i], c.Users[i+1:]...)
+		return nil
+	}
+	return fmt.Errorf("user '%s' not found", username)
+}
+
+// countAdmins returns the number of admin users. The caller must hold c.mu.
+func (c *Config) countAdmins() int {
+	n := 0
+	for _, u := range c.Users {
+		if u.Role == RoleAdmin {
+			n++
+		}
+	}
+	return n
+}
+
+// migrateUsers replaces the legacy plaintext auth_user/auth_pass pair with
+// a hashed admin account. It reports whether the config was changed.
+func (c *Config) migrateUsers() (bool, error) {
+	if c.Settings.AuthPass == "" {
+		return false, nil
+	}
+	if len(c.Users) == 0 {
+		username := c.Settings.AuthUser
+		if username == "" {
+			username = "admin"
+		}
+		hash, err := HashPassword(c.Settings.AuthPass)
+		if err != nil {
+			return false, err
+		}
+		c.Users = []UserConfig{{Username: username, PasswordHash: hash, Role: RoleAdmin}}
+	}
+	c.Settings.AuthPass = ""
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import "testing"
+
+func TestMigrateUsers(t *testing.T) {
+	cfg := &Config{Settings: SettingsConfig{AuthUser: "root", AuthPass: "secret"}}
+
+	migrated, err := cfg.migrateUsers()
+	if err != nil || !migrated {
+		t.Fatalf("Expected migration, got %v, %v", migrated, err)
+	}
+	if cfg.Settings.AuthPass != "" {
+		t.Errorf("Plaintext password was kept")
+	}
+	if len(cfg.Users) != 1 || cfg.Users[0].Username != "root" || cfg.Users[0].Role != RoleAdmin {
+		t.Fatalf("Unexpected users: %+v", cfg.Users)
+	}
+	if !cfg.Users[0].CheckPassword("secret") || cfg.Users[0].CheckPassword("wrong") {
+		t.Errorf("Migrated hash does not match the old password")
+	}
+
+	// Nothing left to migrate
+	if migrated, _ := cfg.migrateUsers(); migrated {
+		t.Errorf("Expected no second migration")
+	}
+}
+
+func TestUserManagement(t *testing.T) {
+	cfg := &Config{}
+
+	if err := cfg.AddUser("alice", "pw1", RoleAdmin); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := cfg.AddUser("bob", "pw2", RoleViewer); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := cfg.AddUser("bob", "pw3", RoleViewer); err == nil {
+		t.Errorf("Expected error for duplicate user")
+	}
+	if err := cfg.AddUser("carol", "", RoleViewer); err == nil {
+		t.Errorf("Expected error for empty password")
+	}
+
+	if err := cfg.SetUserPassword("bob", "new"); err != nil || !cfg.GetUser("bob").CheckPassword("new") {
+		t.Errorf("Password reset failed: %v", err)
+	}
+
+	// The last admin is protected
+	if err := cfg.RemoveUser("alice"); err == nil {
+		t.Errorf("Expected error removing the last admin")
+	}
+	if err := cfg.SetUserRole("alice", RoleViewer); err == nil {
+		t.Errorf("Expected error demoting the last admin")
+	}
+
+	if err := cfg.SetUserRole("bob", RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if err := cfg.RemoveUser("alice"); err != nil {
+		t.Errorf("RemoveUser failed: %v", err)
+	}
+	if len(cfg.GetUsers()) != 1 {
+		t.Errorf("Wrong user count. Got %d, want %d", len(cfg.GetUsers()), 1)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	cases := []struct {
+		role, required Role
+		want           bool
+	}{
+		{RoleAdmin, RoleOperator, true},
+		{RoleOperator, RoleOperator, true},
+		{RoleViewer, RoleOperator, false},
+		{RoleOperator, RoleAdmin, false},
+		{Role("bogus"), RoleViewer, false},
+	}
+	for _, c := range cases {
+		if got := c.role.Allows(c.required); got != c.want {
+			t.Errorf("%s.Allows(%s) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
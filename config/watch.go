@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch checks the config file
+const DefaultWatchInterval = 2 * time.Second
+
+// Watch polls the config file and calls onChange whenever its modification
+// time or size changes. It returns when stop is closed.
+func Watch(interval time.Duration, stop <-chan struct{}, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(getConfigPath())
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(getConfigPath())
+			if err != nil {
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			onChange()
+		}
+	}
+}
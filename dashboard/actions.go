@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// AckRequest is the body of an alert acknowledgement
+type AckRequest struct {
+	Rule   string `json:"rule"`
+	Server string `json:"server,omitempty"` // Empty for aggregate alerts
+}
+
+// ackAlertHandler handles POST /api/alerts/ack (operator)
+func (d *Dashboard) ackAlertHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AckRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil || req.Rule == "" {
+		d.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user := requestUser(r)
+	if err := d.monitor.AcknowledgeAlert(req.Rule, req.Server, user.Username); err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Printf("Alert %s (%s) acknowledged by %s", req.Rule, req.Server, user.Username)
+
+	d.writeJSONResponse(w, d.alertData())
+}
+
+// pollHandler handles POST /api/servers/{name}/poll (operator), triggering
+// an immediate re-poll of the server
+func (d *Dashboard) pollHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := d.monitor.PollNow(r.PathValue("name")); err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	d.writeJSONResponse(w, map[string]string{"status": "scheduled"})
+}
+
+// removeServerHandler handles DELETE /api/servers/{name} (admin). The server
+// is removed from config.json and its poller is stopped.
+func (d *Dashboard) removeServerHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !d.config.RemoveServer(name) {
+		d.writeJSONError(w, "server '"+name+"' not found", http.StatusNotFound)
+		return
+	}
+	if err := d.config.Save(); err != nil {
+		log.Printf("Failed to save config: %v", err)
+		d.writeJSONError(w, "Failed to save configuration", http.StatusInternalServerError)
+		return
+	}
+	d.monitor.RefreshServers()
+	log.Printf("Server %s removed by %s", name, requestUser(r).Username)
+
+	d.writeJSONResponse(w, map[string]string{"status": "removed"})
+}
@@ -0,0 +1,106 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// BaselineData represents the expected rates of the current hour for API
+type BaselineData struct {
+	Scope    string `json:"scope"`
+	Samples  int    `json:"samples"`
+	Rx       uint64 `json:"rx"`
+	Tx       uint64 `json:"tx"`
+	StdDevRx uint64 `json:"stdDevRx"`
+	StdDevTx uint64 `json:"stdDevTx"`
+}
+
+// AnomalyData represents a deviation from the traffic baseline for API
+type AnomalyData struct {
+	Server   string    `json:"server,omitempty"`
+	Time     time.Time `json:"time"`
+	LastSeen time.Time `json:"lastSeen"`
+	Field    string    `json:"field"`
+	Kind     string    `json:"kind"`
+	Rate     uint64    `json:"rate"`
+	Expected uint64    `json:"expected"`
+	ZScore   float64   `json:"zScore"`
+	Active   bool      `json:"active"`
+}
+
+// baselineData converts a traffic baseline for API
+func baselineData(b *monitor.Baseline) *BaselineData {
+	if b == nil {
+		return nil
+	}
+	return &BaselineData{
+		Scope:    b.Scope,
+		Samples:  b.Samples,
+		Rx:       b.Rx,
+		Tx:       b.Tx,
+		StdDevRx: b.StdDevRx,
+		StdDevTx: b.StdDevTx,
+	}
+}
+
+// anomalyData converts the anomalies of a server for API
+func anomalyData(server string, events []monitor.AnomalyEvent) []AnomalyData {
+	data := make([]AnomalyData, len(events))
+	for i, ev := range events {
+		data[i] = AnomalyData{
+			Server:   server,
+			Time:     ev.Time,
+			LastSeen: ev.LastSeen,
+			Field:    ev.Field,
+			Kind:     ev.Kind,
+			Rate:     ev.Rate,
+			Expected: ev.Expected,
+			ZScore:   ev.ZScore,
+			Active:   ev.Active,
+		}
+	}
+	return data
+}
+
+// anomaliesHandler handles the /api/anomalies endpoint. Anomalies of all
+// servers are returned newest first; "server" limits them to one server,
+// "since" to those seen after a time and "active=true" to ongoing ones.
+func (d *Dashboard) anomaliesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	server := query.Get("server")
+	if server != "" && d.config.GetServer(server) == nil {
+		d.writeJSONError(w, "Server not found", http.StatusNotFound)
+		return
+	}
+	since, err := parseTimeParam(query.Get("since"), time.Time{})
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'since' parameter", http.StatusBadRequest)
+		return
+	}
+	activeOnly := query.Get("active") == "true"
+
+	anomalies := make([]AnomalyData, 0)
+	for name, events := range d.monitor.GetAnomalies() {
+		if server != "" && name != server {
+			continue
+		}
+		for _, a := range anomalyData(name, events) {
+			if (activeOnly && !a.Active) || a.LastSeen.Before(since) {
+				continue
+			}
+			anomalies = append(anomalies, a)
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Time.After(anomalies[j].Time)
+	})
+
+	d.writeJSONResponse(w, anomalies)
+}
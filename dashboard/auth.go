@@ -0,0 +1,250 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/config"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contextKey is the type of request context keys set by the dashboard
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// authUser is the authenticated user of a request
+type authUser struct {
+	Username string
+	Role     config.Role
+	csrf     string // CSRF token of the session, empty for Basic Auth and API tokens
+	readOnly bool   // Read-only API token
+}
+
+// anonymousAdmin is used for every request when auth is disabled
+var anonymousAdmin = &authUser{Role: config.RoleAdmin}
+
+// credentialCache remembers recently verified passwords so that bcrypt runs
+// once per user and password instead of on every request. Entries are keyed
+// by username and become invalid when the stored hash changes.
+type credentialCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedCredential
+}
+
+type cachedCredential struct {
+	hash   string   // PasswordHash the password was verified against
+	digest [32]byte // SHA-256 of the verified password
+}
+
+func newCredentialCache() *credentialCache {
+	return &credentialCache{entries: make(map[string]cachedCredential)}
+}
+
+// verify checks a password against a user's hash, using the cache when possible
+func (c *credentialCache) verify(u *config.UserConfig, password string) bool {
+	digest := sha256.Sum256([]byte(password))
+
+	c.mu.Lock()
+	entry, ok := c.entries[u.Username]
+	c.mu.Unlock()
+	if ok && entry.hash == u.PasswordHash && subtle.ConstantTimeCompare(entry.digest[:], digest[:]) == 1 {
+		return true
+	}
+
+	if !u.CheckPassword(password) {
+		return false
+	}
+
+	c.mu.Lock()
+	c.entries[u.Username] = cachedCredential{hash: u.PasswordHash, digest: digest}
+	c.mu.Unlock()
+	return true
+}
+
+// dummyUser is checked for unknown usernames so that a login takes as long
+// whether or not the user exists
+var (
+	dummyUser     config.UserConfig
+	dummyUserOnce sync.Once
+)
+
+// authenticate checks a username and password against the configured users
+func (d *Dashboard) authenticate(username, password string) *authUser {
+	u := d.config.GetUser(username)
+	if u == nil {
+		dummyUserOnce.Do(func() {
+			dummyUser.PasswordHash, _ = config.HashPassword("dummy-password")
+		})
+		dummyUser.CheckPassword(password)
+		return nil
+	}
+	if !d.credentials.verify(u, password) {
+		return nil
+	}
+	return &authUser{Username: u.Username, Role: u.Role}
+}
+
+// withUser returns a copy of r carrying the authenticated user
+func withUser(r *http.Request, u *authUser) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userContextKey, u))
+}
+
+// requestUser returns the authenticated user of a request, or nil
+func requestUser(r *http.Request) *authUser {
+	u, _ := r.Context().Value(userContextKey).(*authUser)
+	return u
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// tokenUser returns the user of a valid API token
+func (d *Dashboard) tokenUser(secret string) *authUser {
+	t := d.config.FindToken(secret, time.Now())
+	if t == nil {
+		return nil
+	}
+	if t.ReadOnly {
+		return &authUser{Username: "token:" + t.Name, Role: config.RoleViewer, readOnly: true}
+	}
+	return &authUser{Username: "token:" + t.Name, Role: config.RoleAdmin}
+}
+
+// requireAuth wraps a handler so that it is only reachable by an
+// authenticated user, which is stored in the request context. Browsers use
+// the session cookie set by /login; mutating requests must then carry the
+// session's CSRF token. Scripts send an API token as a bearer token, or
+// Basic Auth credentials, which are rate limited like logins. Bad tokens
+// are not counted: they are random and can't be guessed, and a script with
+// a revoked token must not lock its host out of /login. Without auth
+// every visitor is admin, so mutating requests from other sites are
+// rejected.
+func (d *Dashboard) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.authEnabled {
+			if isMutating(r.Method) && !sameOrigin(r) {
+				d.writeJSONError(w, "Cross-origin request rejected", http.StatusForbidden)
+				return
+			}
+			next(w, withUser(r, anonymousAdmin))
+			return
+		}
+
+		if secret, ok := bearerToken(r); ok {
+			u := d.tokenUser(secret)
+			if u == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="Bandwidth Monitor"`)
+				d.writeJSONError(w, "Invalid or expired API token", http.StatusUnauthorized)
+				return
+			}
+			if u.readOnly && isMutating(r.Method) {
+				d.writeJSONError(w, "Forbidden: read-only API token", http.StatusForbidden)
+				return
+			}
+			next(w, withUser(r, u))
+			return
+		}
+
+		if u, _ := d.sessionUser(r); u != nil {
+			if isMutating(r.Method) && !validCSRF(r, u.csrf) {
+				d.writeJSONError(w, "Invalid or missing CSRF token", http.StatusForbidden)
+				return
+			}
+			next(w, withUser(r, u))
+			return
+		}
+
+		if user, pass, ok := r.BasicAuth(); ok {
+			// Browsers may replay cached Basic Auth credentials on
+			// cross-site requests
+			if isMutating(r.Method) && !sameOrigin(r) {
+				d.writeJSONError(w, "Cross-origin request rejected", http.StatusForbidden)
+				return
+			}
+
+			ip := clientIP(r)
+			now := time.Now()
+			if wait := d.limiter.retryAfter(ip, now); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				d.writeJSONError(w, "Too many failed logins, try again later", http.StatusTooManyRequests)
+				return
+			}
+			if u := d.authenticate(user, pass); u != nil {
+				next(w, withUser(r, u))
+				return
+			}
+			d.limiter.fail(ip, now)
+			d.writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			redirectToLogin(w, r)
+			return
+		}
+		d.writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+	}
+}
+
+// validCSRF checks the CSRF token of a request in constant time
+func validCSRF(r *http.Request, expected string) bool {
+	token := r.Header.Get(csrfHeaderName)
+	if token == "" {
+		token = r.PostFormValue(csrfFormField)
+	}
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// sameOrigin reports whether a request has no Origin header or one that
+// matches the requested host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// requireRole wraps a handler so that only users with at least the given
+// role can call it. It must be used inside requireAuth.
+func (d *Dashboard) requireRole(role config.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u := requestUser(r)
+		if u == nil || !u.Role.Allows(role) {
+			d.writeJSONError(w, "Forbidden: requires "+string(role)+" role", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// UserData represents the authenticated user for API
+type UserData struct {
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role"`
+	CSRFToken string `json:"csrfToken,omitempty"` // Send as X-CSRF-Token on POST/DELETE requests
+}
+
+// meHandler handles the /api/me endpoint, returning the current user
+func (d *Dashboard) meHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	u := requestUser(r)
+	if u == nil {
+		d.writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	d.writeJSONResponse(w, UserData{Username: u.Username, Role: string(u.Role), CSRFToken: u.csrf})
+}
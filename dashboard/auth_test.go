@@ -0,0 +1,156 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newAuthDashboard returns a dashboard with an admin, operator and viewer account
+func newAuthDashboard(t *testing.T) *Dashboard {
+	t.Helper()
+
+	cfg := &config.Config{}
+	for _, u := range []struct {
+		name string
+		role config.Role
+	}{{"admin", config.RoleAdmin}, {"op", config.RoleOperator}, {"view", config.RoleViewer}} {
+		if err := cfg.AddUser(u.name, "secret", u.role); err != nil {
+			t.Fatalf("AddUser failed: %v", err)
+		}
+	}
+
+	return &Dashboard{
+		config:      cfg,
+		authEnabled: true,
+		credentials: newCredentialCache(),
+		sessions:    newSessionManager("test-secret"),
+		limiter:     newLoginLimiter(),
+	}
+}
+
+func TestRoleAuthorization(t *testing.T) {
+	d := newAuthDashboard(t)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	viewer := d.requireAuth(ok)
+	operator := d.requireAuth(d.requireRole(config.RoleOperator, ok))
+	admin := d.requireAuth(d.requireRole(config.RoleAdmin, ok))
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		user    string
+		pass    string
+		status  int
+	}{
+		{"viewer reads", viewer, "view", "secret", http.StatusOK},
+		{"wrong password", viewer, "view", "nope", http.StatusUnauthorized},
+		{"unknown user", viewer, "ghost", "secret", http.StatusUnauthorized},
+		{"viewer cannot ack", operator, "view", "secret", http.StatusForbidden},
+		{"operator acks", operator, "op", "secret", http.StatusOK},
+		{"operator cannot manage", admin, "op", "secret", http.StatusForbidden},
+		{"admin manages", admin, "admin", "secret", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.SetBasicAuth(c.user, c.pass)
+		w := httptest.NewRecorder()
+		c.handler(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s: got status %d, want %d", c.name, w.Code, c.status)
+		}
+	}
+
+	// Auth disabled grants admin access
+	d.authEnabled = false
+	w := httptest.NewRecorder()
+	admin(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Auth disabled: got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// but not to other sites
+	r := httptest.NewRequest(http.MethodPost, "http://monitor.local/", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	admin(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Auth disabled, cross-origin: got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestCredentialCacheInvalidation(t *testing.T) {
+	d := newAuthDashboard(t)
+
+	if d.authenticate("view", "secret") == nil {
+		t.Fatal("Expected valid credentials")
+	}
+	if err := d.config.SetUserPassword("view", "changed"); err != nil {
+		t.Fatalf("SetUserPassword failed: %v", err)
+	}
+	if d.authenticate("view", "secret") != nil {
+		t.Error("Cached old password was accepted after reset")
+	}
+	if d.authenticate("view", "changed") == nil {
+		t.Error("New password was rejected")
+	}
+}
+
+func TestAPITokenAuth(t *testing.T) {
+	d := newAuthDashboard(t)
+	now := time.Now()
+	readOnly, _ := d.config.AddToken("grafana", true, 0, now)
+	full, _ := d.config.AddToken("deploy", false, 0, now)
+	expired, _ := d.config.AddToken("old", false, time.Millisecond, now.Add(-time.Hour))
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	viewer := d.requireAuth(ok)
+	admin := d.requireAuth(d.requireRole(config.RoleAdmin, ok))
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		token   string
+		status  int
+	}{
+		{"read-only token reads", viewer, http.MethodGet, readOnly, http.StatusOK},
+		{"read-only token cannot write", viewer, http.MethodPost, readOnly, http.StatusForbidden},
+		{"read-only token is not admin", admin, http.MethodGet, readOnly, http.StatusForbidden},
+		{"full token manages", admin, http.MethodDelete, full, http.StatusOK},
+		{"expired token", viewer, http.MethodGet, expired, http.StatusUnauthorized},
+		{"unknown token", viewer, http.MethodGet, "bmt_nope", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(c.method, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+c.token)
+		w := httptest.NewRecorder()
+		c.handler(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s: got status %d, want %d", c.name, w.Code, c.status)
+		}
+	}
+
+	// Revocation applies immediately
+	d.config.RemoveToken("grafana")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+readOnly)
+	w := httptest.NewRecorder()
+	viewer(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Revoked token: got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	// A script with a stale token doesn't lock its host out of logins
+	for i := 0; i < loginMaxFailures; i++ {
+		viewer(httptest.NewRecorder(), r)
+	}
+	if wait := d.limiter.retryAfter(clientIP(r), time.Now()); wait != 0 {
+		t.Errorf("Bad tokens counted as failed logins, waiting %s", wait)
+	}
+}
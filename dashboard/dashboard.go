@@ -1,12 +1,16 @@
 package dashboard
 
 import (
+	"bandwidth-monitor/config"
 	"bandwidth-monitor/monitor"
+	"bandwidth-monitor/store"
+	"crypto/tls"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -14,14 +18,24 @@ import (
 var staticFiles embed.FS
 
 const staticIndexPath = "static/index.html"
+const staticServerPath = "static/server.html"
 
 // Dashboard represents the web dashboard server
 type Dashboard struct {
 	monitor   *monitor.Monitor
+	config    *config.Config
 	server    *http.Server
-	username  string
-	password  string
 	authEnabled bool
+	metricsToken string
+	stream    *streamHub
+	credentials *credentialCache
+	sessions  *sessionManager
+	limiter   *loginLimiter
+
+	// HTTPS; certs is nil when TLS is disabled
+	certs        *certReloader
+	generateCert bool
+	redirect     *http.Server
 }
 
 // APIResponse represents a standard API response
@@ -38,11 +52,29 @@ type MetricsAPIResponse struct {
 	GrandTotalAvg  uint64                       `json:"grandTotalAvg"`
 	GrandTotalPeak uint64                       `json:"grandTotalPeak"`
 	DominantServer string                       `json:"dominantServer"`
+	Percentiles    *PercentileData              `json:"percentiles,omitempty"` // Billing percentiles of the aggregate
+	Groups         map[string]*GroupData        `json:"groups,omitempty"`      // Totals per group, or per tag with group_by=tag
 	Servers        map[string]*ServerMetricData `json:"servers"`
 	History        []HistoryEntryData           `json:"history"`
+	Alerts         []AlertData                  `json:"alerts"`
 	UpdatedAt      time.Time                    `json:"updatedAt"`
 }
 
+// AlertData represents a pending or firing alert for API
+type AlertData struct {
+	Rule      string     `json:"rule"`
+	Server    string     `json:"server,omitempty"`
+	Field     string     `json:"field"`
+	Operator  string     `json:"operator"`
+	Threshold float64    `json:"threshold"`
+	Value     float64    `json:"value"`
+	Severity  string     `json:"severity,omitempty"`
+	State     string     `json:"state"`
+	Since     time.Time  `json:"since"`
+	AckedBy   string     `json:"ackedBy,omitempty"`
+	AckedAt   *time.Time `json:"ackedAt,omitempty"`
+}
+
 // PeakEventData represents a peak event for API
 type PeakEventData struct {
 	Time string `json:"time"`
@@ -54,9 +86,13 @@ type PeakEventData struct {
 type ServerMetricData struct {
 	Name       string          `json:"name"`
 	IP         string          `json:"ip"`
+	Group      string          `json:"group,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
 	Online     bool            `json:"online"`
 	Rx         uint64          `json:"rx"`
 	Tx         uint64          `json:"tx"`
+	Rx5m       uint64          `json:"rx5m"` // Rate over the latest complete five-minute bucket
+	Tx5m       uint64          `json:"tx5m"`
 	TotalRx    uint64          `json:"totalRx"`
 	TotalTx    uint64          `json:"totalTx"`
 	AvgRx24h   uint64          `json:"avgRx24h"`
@@ -64,10 +100,43 @@ type ServerMetricData struct {
 	PeakRx     uint64          `json:"peakRx"`
 	PeakTx     uint64          `json:"peakTx"`
 	PeakEvents []PeakEventData `json:"peakEvents"`
+	Interfaces []InterfaceData `json:"interfaces"`
+	Quota      *QuotaData      `json:"quota,omitempty"`
+	Percentiles *PercentileData `json:"percentiles,omitempty"`
+	Baseline   *BaselineData   `json:"baseline,omitempty"`
+	Anomalies  []AnomalyData   `json:"anomalies"`
+	Reachability *ReachabilityData `json:"reachability,omitempty"`
 	UpdatedAt  time.Time       `json:"updatedAt"`
 	Error      string          `json:"error,omitempty"`
 }
 
+// InterfaceData represents per-interface metrics for API
+type InterfaceData struct {
+	Name     string `json:"name"`
+	Rx       uint64 `json:"rx"`
+	Tx       uint64 `json:"tx"`
+	Rx5m     uint64 `json:"rx5m"`
+	Tx5m     uint64 `json:"tx5m"`
+	TotalRx  uint64 `json:"totalRx"`
+	TotalTx  uint64 `json:"totalTx"`
+	AvgRx24h uint64 `json:"avgRx24h"`
+	AvgTx24h uint64 `json:"avgTx24h"`
+	PeakRx   uint64 `json:"peakRx"`
+	PeakTx   uint64 `json:"peakTx"`
+}
+
+// QuotaData represents billing-cycle quota usage for API
+type QuotaData struct {
+	Direction        string    `json:"direction"`
+	Limit            uint64    `json:"limit"`
+	Used             uint64    `json:"used"`
+	Percent          float64   `json:"percent"`
+	Projected        uint64    `json:"projected"`
+	ProjectedPercent float64   `json:"projectedPercent"`
+	CycleStart       time.Time `json:"cycleStart"`
+	CycleEnd         time.Time `json:"cycleEnd"`
+}
+
 // HistoryEntryData represents history entry for API
 type HistoryEntryData struct {
 	Timestamp int64  `json:"timestamp"`
@@ -75,20 +144,50 @@ type HistoryEntryData struct {
 	TotalTx   uint64 `json:"totalTx"`
 }
 
-// NewDashboard creates a new dashboard instance
-func NewDashboard(m *monitor.Monitor, port int, username, password string, authEnabled bool) *Dashboard {
-	return &Dashboard{
+// NewDashboard creates a new dashboard instance. The listen port, auth
+// switch and metrics token are read once; users are looked up in cfg on
+// every request so account changes apply without a restart. If a metrics
+// token is set, the Prometheus /metrics endpoint accepts it as a bearer
+// token instead of Basic Auth.
+func NewDashboard(m *monitor.Monitor, cfg *config.Config) *Dashboard {
+	settings := cfg.GetSettings()
+	d := &Dashboard{
 		monitor:    m,
-		username:   username,
-		password:   password,
-		authEnabled: authEnabled,
+		config:     cfg,
+		authEnabled: settings.AuthEnabled,
+		metricsToken: settings.MetricsToken,
+		credentials: newCredentialCache(),
+		sessions:    newSessionManager(settings.SessionSecret),
+		limiter:     newLoginLimiter(),
 		server: &http.Server{
-			Addr:         fmt.Sprintf(":%d", port),
+			Addr:         fmt.Sprintf(":%d", settings.ListenPort),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
 	}
+	d.stream = newStreamHub(func() (*monitor.AggregateMetrics, []AlertData) {
+		return m.GetMetrics(), d.alertData()
+	})
+
+	if settings.TLSEnabled {
+		certPath, keyPath, generate := settings.GetTLSFiles()
+		d.certs = newCertReloader(certPath, keyPath)
+		d.generateCert = generate
+		d.server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: d.certs.GetCertificate,
+		}
+		if settings.HTTPRedirectPort > 0 {
+			d.redirect = &http.Server{
+				Addr:         fmt.Sprintf(":%d", settings.HTTPRedirectPort),
+				Handler:      httpsRedirect(settings.ListenPort),
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+			}
+		}
+	}
+	return d
 }
 
 // Start starts the dashboard server
@@ -97,24 +196,87 @@ func (d *Dashboard) Start() error {
 	mux := http.NewServeMux()
 	
 	// Apply caching middleware and basic auth to all routes
-	mux.HandleFunc("/", d.noCache(d.basicAuth(d.indexHandler)))
-	mux.HandleFunc("/api/metrics", d.noCache(d.basicAuth(d.metricsHandler)))
-	mux.HandleFunc("/api/servers", d.noCache(d.basicAuth(d.serversHandler)))
+	mux.HandleFunc("GET /login", d.noCache(d.loginPageHandler))
+	mux.HandleFunc("POST /login", d.noCache(d.loginHandler))
+	mux.HandleFunc("/logout", d.noCache(d.logoutHandler))
+	mux.HandleFunc("/", d.noCache(d.requireAuth(d.indexHandler)))
+	mux.HandleFunc("/api/metrics", d.noCache(d.requireAuth(d.metricsHandler)))
+	mux.HandleFunc("/api/stream", d.noCache(d.requireAuth(d.streamHandler)))
+	mux.HandleFunc("/api/servers", d.noCache(d.requireAuth(d.serversHandler)))
+	mux.HandleFunc("/api/servers/{name}", d.noCache(d.requireAuth(d.serverDetailHandler)))
+	mux.HandleFunc("DELETE /api/servers/{name}", d.noCache(d.requireAuth(d.requireRole(config.RoleAdmin, d.removeServerHandler))))
+	mux.HandleFunc("/api/servers/{name}/poll", d.noCache(d.requireAuth(d.requireRole(config.RoleOperator, d.pollHandler))))
+	mux.HandleFunc("/server/{name}", d.noCache(d.requireAuth(d.serverPageHandler)))
+	mux.HandleFunc("/api/series", d.noCache(d.requireAuth(d.seriesHandler)))
+	mux.HandleFunc("/api/history", d.noCache(d.requireAuth(d.historyHandler)))
+	mux.HandleFunc("/api/percentiles", d.noCache(d.requireAuth(d.percentilesHandler)))
+	mux.HandleFunc("/api/anomalies", d.noCache(d.requireAuth(d.anomaliesHandler)))
+	mux.HandleFunc("/api/uptime", d.noCache(d.requireAuth(d.uptimeHandler)))
+	mux.HandleFunc("/api/alerts", d.noCache(d.requireAuth(d.alertsHandler)))
+	mux.HandleFunc("/api/alerts/ack", d.noCache(d.requireAuth(d.requireRole(config.RoleOperator, d.ackAlertHandler))))
+	mux.HandleFunc("/api/me", d.noCache(d.requireAuth(d.meHandler)))
+	mux.HandleFunc("/metrics", d.noCache(d.metricsAuth(d.prometheusHandler)))
 	
 	d.server.Handler = mux
+
+	// Publish live deltas to /api/stream clients
+	updates, _ := d.monitor.Subscribe()
+	go d.stream.run(updates)
 	
 	log.Printf("Dashboard starting on %s", d.server.Addr)
 	if d.authEnabled {
-		log.Printf("HTTP Basic Auth enabled (user: %s)", d.username)
+		log.Printf("Login required (%d users)", len(d.config.GetUsers()))
 	} else {
-		log.Println("HTTP Basic Auth disabled")
+		log.Println("Authentication disabled")
 	}
-	
-	return d.server.ListenAndServe()
+	if d.metricsToken != "" {
+		log.Println("Prometheus /metrics requires bearer token")
+	}
+
+	if d.certs == nil {
+		return d.server.ListenAndServe()
+	}
+
+	if d.generateCert {
+		generated, err := EnsureSelfSignedCert(d.certs.certPath, d.certs.keyPath)
+		if err != nil {
+			return err
+		}
+		if generated {
+			log.Printf("Generated self-signed TLS certificate %s", d.certs.certPath)
+		}
+	}
+	if err := d.certs.Reload(); err != nil {
+		return err
+	}
+
+	if d.redirect != nil {
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", d.redirect.Addr)
+			if err := d.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP redirect error: %v", err)
+			}
+		}()
+	}
+
+	log.Println("HTTPS enabled")
+	return d.server.ListenAndServeTLS("", "")
+}
+
+// ReloadCertificates re-reads the TLS certificate and key from disk without
+// restarting the server. It does nothing when TLS is disabled.
+func (d *Dashboard) ReloadCertificates() error {
+	if d.certs == nil {
+		return nil
+	}
+	return d.certs.Reload()
 }
 
 // Stop stops the dashboard server
 func (d *Dashboard) Stop() error {
+	if d.redirect != nil {
+		d.redirect.Close()
+	}
 	if d.server != nil {
 		return d.server.Close()
 	}
@@ -146,59 +308,90 @@ func (d *Dashboard) metricsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	metrics := d.monitor.GetMetrics()
-	
-	// Convert to API response
+	filter, err := parseServerFilter(r.URL.Query())
+	if err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.writeJSONResponse(w, metricsResponse(filter.apply(d.monitor.GetMetrics()), d.alertData()))
+}
+
+// metricsResponse converts aggregate metrics to the metrics API response
+func metricsResponse(metrics *monitor.AggregateMetrics, alerts []AlertData) MetricsAPIResponse {
 	response := MetricsAPIResponse{
 		TotalRx:        metrics.TotalRx,
 		TotalTx:        metrics.TotalTx,
 		GrandTotalAvg:  metrics.GrandTotalAvg,
 		GrandTotalPeak: metrics.GrandTotalPeak,
 		DominantServer: metrics.DominantServer,
+		Percentiles:    percentileData(metrics.Percentiles),
+		Groups:         groupData(metrics.Groups),
 		Servers:        make(map[string]*ServerMetricData),
 		History:        make([]HistoryEntryData, len(metrics.History)),
+		Alerts:         alerts,
 		UpdatedAt:      metrics.UpdatedAt,
 	}
 	
 	// Convert server metrics
 	for name, sm := range metrics.ServerMetrics {
-		peakEvents := make([]PeakEventData, len(sm.PeakEvents))
-		for i, pe := range sm.PeakEvents {
-			peakEvents[i] = PeakEventData{
-				Time: pe.Time.Format("15:04"), // Format HH:MM
-				Rx:   pe.Rx,
-				Tx:   pe.Tx,
-			}
-		}
-
-		response.Servers[name] = &ServerMetricData{
-			Name:       sm.Name,
-			IP:         sm.IP,
-			Online:     sm.Online,
-			Rx:         sm.Rx,
-			Tx:         sm.Tx,
-			TotalRx:    sm.TotalRx,
-			TotalTx:    sm.TotalTx,
-			AvgRx24h:   sm.AvgRx24h,
-			AvgTx24h:   sm.AvgTx24h,
-			PeakRx:     sm.PeakRx,
-			PeakTx:     sm.PeakTx,
-			PeakEvents: peakEvents,
-			UpdatedAt:  sm.UpdatedAt,
-			Error:      sm.Error,
-		}
+		response.Servers[name] = serverMetricData(sm)
 	}
 	
 	// Convert history
 	for i, h := range metrics.History {
-		response.History[i] = HistoryEntryData{
-			Timestamp: h.Timestamp.Unix(),
-			TotalRx:   h.TotalRx,
-			TotalTx:   h.TotalTx,
-		}
+		response.History[i] = historyEntryData(h)
 	}
 	
-	d.writeJSONResponse(w, response)
+	return response
+}
+
+// serverMetricData converts the metrics of one server for API
+func serverMetricData(sm *monitor.ServerMetrics) *ServerMetricData {
+	peakEvents := make([]PeakEventData, len(sm.PeakEvents))
+	for i, pe := range sm.PeakEvents {
+		peakEvents[i] = PeakEventData{
+			Time: pe.Time.Format("15:04"), // Format HH:MM
+			Rx:   pe.Rx,
+			Tx:   pe.Tx,
+		}
+	}
+
+	return &ServerMetricData{
+		Name:       sm.Name,
+		IP:         sm.IP,
+		Group:      sm.Group,
+		Tags:       sm.Tags,
+		Online:     sm.Online,
+		Rx:         sm.Rx,
+		Tx:         sm.Tx,
+		Rx5m:       sm.FiveMinuteRx,
+		Tx5m:       sm.FiveMinuteTx,
+		TotalRx:    sm.TotalRx,
+		TotalTx:    sm.TotalTx,
+		AvgRx24h:   sm.AvgRx24h,
+		AvgTx24h:   sm.AvgTx24h,
+		PeakRx:     sm.PeakRx,
+		PeakTx:     sm.PeakTx,
+		PeakEvents: peakEvents,
+		Interfaces: interfaceData(sm.Interfaces),
+		Quota:      quotaData(sm.Quota),
+		Percentiles: percentileData(sm.Percentiles),
+		Baseline:   baselineData(sm.Baseline),
+		Anomalies:  anomalyData("", sm.Anomalies),
+		Reachability: reachabilityData(sm.Reachability),
+		UpdatedAt:  sm.UpdatedAt,
+		Error:      sm.Error,
+	}
+}
+
+// historyEntryData converts a history entry for API
+func historyEntryData(h monitor.HistoryEntry) HistoryEntryData {
+	return HistoryEntryData{
+		Timestamp: h.Timestamp.Unix(),
+		TotalRx:   h.TotalRx,
+		TotalTx:   h.TotalTx,
+	}
 }
 
 // serversHandler handles the /api/servers endpoint
@@ -208,22 +401,40 @@ func (d *Dashboard) serversHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	filter, err := parseServerFilter(r.URL.Query())
+	if err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	metrics := d.monitor.GetMetrics()
 	
 	servers := make([]map[string]interface{}, 0)
 	for _, sm := range metrics.ServerMetrics {
+		if !filter.matches(sm) {
+			continue
+		}
 		server := map[string]interface{}{
 			"name":      sm.Name,
 			"ip":        sm.IP,
+			"group":     sm.Group,
+			"tags":      sm.Tags,
 			"online":    sm.Online,
 			"rx":        sm.Rx,
 			"tx":        sm.Tx,
+			"rx5m":      sm.FiveMinuteRx,
+			"tx5m":      sm.FiveMinuteTx,
 			"totalRx":   sm.TotalRx,
 			"totalTx":   sm.TotalTx,
 			"avgRx24h":  sm.AvgRx24h,
 			"avgTx24h":  sm.AvgTx24h,
 			"peakRx":    sm.PeakRx,
 			"peakTx":    sm.PeakTx,
+			"interfaces": interfaceData(sm.Interfaces),
+			"quota":     quotaData(sm.Quota),
+			"percentiles": percentileData(sm.Percentiles),
+			"baseline":  baselineData(sm.Baseline),
+			"anomalies": anomalyData("", sm.Anomalies),
+			"reachability": reachabilityData(sm.Reachability),
 			"updatedAt": sm.UpdatedAt,
 			"error":     sm.Error,
 		}
@@ -236,6 +447,245 @@ func (d *Dashboard) serversHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// interfaceData converts per-interface metrics for API
+func interfaceData(interfaces []monitor.InterfaceMetrics) []InterfaceData {
+	data := make([]InterfaceData, len(interfaces))
+	for i, im := range interfaces {
+		data[i] = InterfaceData{
+			Name:     im.Name,
+			Rx:       im.Rx,
+			Tx:       im.Tx,
+			Rx5m:     im.FiveMinuteRx,
+			Tx5m:     im.FiveMinuteTx,
+			TotalRx:  im.TotalRx,
+			TotalTx:  im.TotalTx,
+			AvgRx24h: im.AvgRx24h,
+			AvgTx24h: im.AvgTx24h,
+			PeakRx:   im.PeakRx,
+			PeakTx:   im.PeakTx,
+		}
+	}
+	return data
+}
+
+// quotaData converts quota usage for API
+func quotaData(q *monitor.QuotaUsage) *QuotaData {
+	if q == nil {
+		return nil
+	}
+	return &QuotaData{
+		Direction:        q.Direction,
+		Limit:            q.LimitBytes,
+		Used:             q.UsedBytes,
+		Percent:          q.Percent,
+		Projected:        q.ProjectedBytes,
+		ProjectedPercent: q.ProjectedPercent,
+		CycleStart:       q.CycleStart,
+		CycleEnd:         q.CycleEnd,
+	}
+}
+
+// alertsHandler handles the /api/alerts endpoint
+func (d *Dashboard) alertsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d.writeJSONResponse(w, d.alertData())
+}
+
+// alertData converts the monitor's active alerts for API
+func (d *Dashboard) alertData() []AlertData {
+	alerts := d.monitor.GetAlerts()
+
+	data := make([]AlertData, len(alerts))
+	for i, a := range alerts {
+		data[i] = AlertData{
+			Rule:      a.Rule,
+			Server:    a.Server,
+			Field:     a.Field,
+			Operator:  a.Operator,
+			Threshold: a.Threshold,
+			Value:     a.Value,
+			Severity:  a.Severity,
+			State:     string(a.State),
+			Since:     a.Since,
+			AckedBy:   a.AckedBy,
+			AckedAt:   optionalTime(a.AckedAt),
+		}
+	}
+	return data
+}
+
+// seriesHandler handles the /api/series endpoint, returning persisted history
+// for a server (or the aggregate when no server is given) over an arbitrary range
+func (d *Dashboard) seriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	series := query.Get("server")
+	if series == "" {
+		series = store.AggregateSeries
+	}
+
+	now := time.Now()
+	to, err := parseTimeParam(query.Get("to"), now)
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'to' parameter", http.StatusBadRequest)
+		return
+	}
+	from, err := parseTimeParam(query.Get("from"), to.Add(-24*time.Hour))
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'from' parameter", http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		d.writeJSONError(w, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	step, err := parseStepParam(query.Get("step"))
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'step' parameter", http.StatusBadRequest)
+		return
+	}
+
+	samples, err := d.monitor.QueryHistory(series, from, to, step)
+	if err != nil {
+		log.Printf("Error querying history: %v", err)
+		d.writeJSONError(w, "Failed to query history", http.StatusInternalServerError)
+		return
+	}
+	if samples == nil {
+		samples = []store.Sample{}
+	}
+
+	d.writeJSONResponse(w, samples)
+}
+
+// HistoryAPIResponse represents the history API response
+type HistoryAPIResponse struct {
+	Server     string             `json:"server,omitempty"`
+	From       int64              `json:"from"`
+	To         int64              `json:"to"`
+	Resolution int64              `json:"resolution"` // Seconds per vnStat bucket
+	Step       int64              `json:"step"`       // Seconds per point, 0 when buckets are returned as-is
+	Points     []HistoryPointData `json:"points"`
+}
+
+// HistoryPointData represents transferred volume over one interval for API
+type HistoryPointData struct {
+	Timestamp int64  `json:"timestamp"`
+	Duration  int64  `json:"duration"`
+	Rx        uint64 `json:"rx"`     // Bytes
+	Tx        uint64 `json:"tx"`     // Bytes
+	RxRate    uint64 `json:"rxRate"` // Average bytes per second
+	TxRate    uint64 `json:"txRate"` // Average bytes per second
+}
+
+// historyHandler handles the /api/history endpoint. It serves per-server or
+// aggregate (no server parameter) traffic from vnStat's cached five-minute,
+// hourly, daily and monthly buckets.
+func (d *Dashboard) historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	server := query.Get("server")
+
+	now := time.Now()
+	to, err := parseTimeParam(query.Get("to"), now)
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'to' parameter", http.StatusBadRequest)
+		return
+	}
+	from, err := parseTimeParam(query.Get("from"), to.Add(-24*time.Hour))
+	if err != nil {
+		d.writeJSONError(w, "Invalid 'from' parameter", http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		d.writeJSONError(w, "'from' must be before 'to'", http.StatusBadRequest)
+		return
+	}
+
+	step, err := parseStepParam(query.Get("step"))
+	if err != nil || (step > 0 && step < time.Second) {
+		d.writeJSONError(w, "Invalid 'step' parameter", http.StatusBadRequest)
+		return
+	}
+
+	history, err := d.monitor.QueryTraffic(server, from, to, step)
+	if err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	response := HistoryAPIResponse{
+		Server:     server,
+		From:       from.Unix(),
+		To:         to.Unix(),
+		Resolution: int64(history.Resolution / time.Second),
+		Step:       int64(history.Step / time.Second),
+		Points:     historyPointData(history.Points),
+	}
+
+	d.writeJSONResponse(w, response)
+}
+
+// historyPointData converts traffic points for API
+func historyPointData(points []monitor.TrafficPoint) []HistoryPointData {
+	data := make([]HistoryPointData, len(points))
+	for i, p := range points {
+		seconds := uint64(p.Duration / time.Second)
+		if seconds == 0 {
+			seconds = 1
+		}
+		data[i] = HistoryPointData{
+			Timestamp: p.Time.Unix(),
+			Duration:  int64(p.Duration / time.Second),
+			Rx:        p.Rx,
+			Tx:        p.Tx,
+			RxRate:    p.Rx / seconds,
+			TxRate:    p.Tx / seconds,
+		}
+	}
+	return data
+}
+
+// parseTimeParam parses a Unix timestamp or RFC 3339 time, returning def when empty
+func parseTimeParam(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseStepParam parses a Go duration ("5m") or a number of seconds
+func parseStepParam(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	step, err := time.ParseDuration(value)
+	if err != nil || step < 0 {
+		return 0, fmt.Errorf("invalid step: %s", value)
+	}
+	return step, nil
+}
+
 // noCache is a middleware that disables caching
 func (d *Dashboard) noCache(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -246,25 +696,6 @@ func (d *Dashboard) noCache(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// basicAuth wraps a handler with HTTP Basic Auth
-func (d *Dashboard) basicAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if !d.authEnabled {
-			next(w, r)
-			return
-		}
-		
-		user, pass, ok := r.BasicAuth()
-		if !ok || user != d.username || pass != d.password {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Bandwidth Monitor"`)
-			d.writeJSONError(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		
-		next(w, r)
-	}
-}
-
 // writeJSONResponse writes a JSON response
 func (d *Dashboard) writeJSONResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
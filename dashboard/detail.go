@@ -0,0 +1,98 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ServerDetailData represents the server detail API response
+type ServerDetailData struct {
+	Server        *ServerMetricData   `json:"server,omitempty"` // Absent until the first poll completes
+	VnStatVersion string              `json:"vnstatVersion"`
+	Interfaces    []InterfaceInfoData `json:"interfaces"`
+	Hours         []HistoryPointData  `json:"hours"`
+	Days          []HistoryPointData  `json:"days"`
+	Months        []HistoryPointData  `json:"months"`
+	Top           []HistoryPointData  `json:"top"`
+	LastError     string              `json:"lastError,omitempty"`
+	LastErrorAt   *time.Time          `json:"lastErrorAt,omitempty"`
+	LastSuccessAt *time.Time          `json:"lastSuccessAt,omitempty"`
+}
+
+// InterfaceInfoData represents vnStat interface information for API
+type InterfaceInfoData struct {
+	Name    string    `json:"name"`
+	Alias   string    `json:"alias,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	TotalRx uint64    `json:"totalRx"`
+	TotalTx uint64    `json:"totalTx"`
+}
+
+// serverPageHandler serves the per-server detail page
+func (d *Dashboard) serverPageHandler(w http.ResponseWriter, r *http.Request) {
+	content, err := staticFiles.ReadFile(staticServerPath)
+	if err != nil {
+		http.Error(w, "Failed to load server page", http.StatusInternalServerError)
+		log.Printf("Error reading server.html: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(content)
+}
+
+// serverDetailHandler handles the /api/servers/{name} endpoint
+func (d *Dashboard) serverDetailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	detail, err := d.monitor.GetServerDetail(r.PathValue("name"))
+	if err != nil {
+		d.writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	d.writeJSONResponse(w, serverDetailData(detail))
+}
+
+// serverDetailData converts a server detail for API
+func serverDetailData(detail *monitor.ServerDetail) ServerDetailData {
+	data := ServerDetailData{
+		VnStatVersion: detail.VnStatVersion,
+		Interfaces:    make([]InterfaceInfoData, len(detail.Interfaces)),
+		Hours:         historyPointData(detail.Hours),
+		Days:          historyPointData(detail.Days),
+		Months:        historyPointData(detail.Months),
+		Top:           historyPointData(detail.Top),
+		LastError:     detail.LastError,
+		LastErrorAt:   optionalTime(detail.LastErrorAt),
+		LastSuccessAt: optionalTime(detail.LastSuccessAt),
+	}
+	if detail.Metrics != nil {
+		data.Server = serverMetricData(detail.Metrics)
+	}
+	for i, info := range detail.Interfaces {
+		data.Interfaces[i] = InterfaceInfoData{
+			Name:    info.Name,
+			Alias:   info.Alias,
+			Created: info.Created,
+			Updated: info.Updated,
+			TotalRx: info.TotalRx,
+			TotalTx: info.TotalTx,
+		}
+	}
+	return data
+}
+
+// optionalTime returns nil for the zero time so it is omitted from JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,110 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"fmt"
+	"net/url"
+)
+
+// GroupData represents the totals of a group of servers for API
+type GroupData struct {
+	Name           string   `json:"name"`
+	Servers        []string `json:"servers"`
+	Online         int      `json:"online"`
+	TotalRx        uint64   `json:"totalRx"`
+	TotalTx        uint64   `json:"totalTx"`
+	GrandTotalAvg  uint64   `json:"grandTotalAvg"`
+	GrandTotalPeak uint64   `json:"grandTotalPeak"`
+	DominantServer string   `json:"dominantServer"`
+}
+
+// groupData converts group totals for API
+func groupData(groups map[string]*monitor.GroupMetrics) map[string]*GroupData {
+	if len(groups) == 0 {
+		return nil
+	}
+	data := make(map[string]*GroupData, len(groups))
+	for name, g := range groups {
+		data[name] = &GroupData{
+			Name:           g.Name,
+			Servers:        g.Servers,
+			Online:         g.Online,
+			TotalRx:        g.TotalRx,
+			TotalTx:        g.TotalTx,
+			GrandTotalAvg:  g.GrandTotalAvg,
+			GrandTotalPeak: g.GrandTotalPeak,
+			DominantServer: g.DominantServer,
+		}
+	}
+	return data
+}
+
+// serverFilter selects servers by group and tags
+type serverFilter struct {
+	group   string
+	tags    []string
+	groupBy string // group (default) or tag
+}
+
+// parseServerFilter reads the "group", "tag" (repeatable) and "group_by"
+// query parameters
+func parseServerFilter(query url.Values) (serverFilter, error) {
+	f := serverFilter{
+		group:   query.Get("group"),
+		tags:    query["tag"],
+		groupBy: query.Get("group_by"),
+	}
+	switch f.groupBy {
+	case "", "group", "tag":
+	default:
+		return f, fmt.Errorf("invalid 'group_by' parameter (use group or tag)")
+	}
+	return f, nil
+}
+
+// active reports whether the filter selects a subset of servers
+func (f serverFilter) active() bool {
+	return f.group != "" || len(f.tags) > 0
+}
+
+// matches reports whether a server passes the filter
+func (f serverFilter) matches(sm *monitor.ServerMetrics) bool {
+	return (f.group == "" || sm.Group == f.group) && sm.HasTags(f.tags...)
+}
+
+// apply returns metrics limited to the matching servers with totals and
+// groups computed over them. The history and billing percentiles cover
+// every server, so they are left out when servers are filtered.
+func (f serverFilter) apply(metrics *monitor.AggregateMetrics) *monitor.AggregateMetrics {
+	if !f.active() && f.groupBy != "tag" {
+		return metrics
+	}
+
+	filtered := *metrics
+	if f.active() {
+		filtered.ServerMetrics = make(map[string]*monitor.ServerMetrics)
+		servers := make([]*monitor.ServerMetrics, 0, len(metrics.ServerMetrics))
+		for name, sm := range metrics.ServerMetrics {
+			if f.matches(sm) {
+				filtered.ServerMetrics[name] = sm
+				servers = append(servers, sm)
+			}
+		}
+
+		total := monitor.AggregateServers("", servers)
+		filtered.TotalRx = total.TotalRx
+		filtered.TotalTx = total.TotalTx
+		filtered.GrandTotalAvg = total.GrandTotalAvg
+		filtered.GrandTotalPeak = total.GrandTotalPeak
+		filtered.DominantServer = total.DominantServer
+		filtered.Percentiles = nil
+		filtered.History = nil
+	}
+
+	if f.groupBy == "tag" {
+		filtered.Groups = monitor.GroupServers(filtered.ServerMetrics, monitor.ByTag)
+	} else {
+		filtered.Groups = monitor.GroupServers(filtered.ServerMetrics, monitor.ByGroup)
+	}
+	return &filtered
+}
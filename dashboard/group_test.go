@@ -0,0 +1,54 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"net/url"
+	"testing"
+)
+
+func TestServerFilter(t *testing.T) {
+	metrics := &monitor.AggregateMetrics{
+		TotalRx:     350,
+		Percentiles: &monitor.PercentileUsage{Rx95: 1},
+		History:     []monitor.HistoryEntry{{TotalRx: 350}},
+		ServerMetrics: map[string]*monitor.ServerMetrics{
+			"web1": {Name: "web1", Group: "acme", Tags: []string{"eu"}, Online: true, Rx: 100},
+			"web2": {Name: "web2", Group: "acme", Tags: []string{"us"}, Online: true, Rx: 200},
+			"db1":  {Name: "db1", Group: "globex", Tags: []string{"eu"}, Online: true, Rx: 50},
+		},
+	}
+
+	filter, err := parseServerFilter(url.Values{"tag": {"eu"}})
+	if err != nil {
+		t.Fatalf("parseServerFilter failed: %v", err)
+	}
+	got := filter.apply(metrics)
+	if len(got.ServerMetrics) != 2 || got.TotalRx != 150 || got.DominantServer != "" {
+		t.Errorf("Unexpected filtered metrics: %d servers, rx %d", len(got.ServerMetrics), got.TotalRx)
+	}
+	if got.Percentiles != nil || got.History != nil {
+		t.Error("Fleet-wide history and percentiles should be left out when filtering")
+	}
+	if len(got.Groups) != 2 || got.Groups["acme"].TotalRx != 100 {
+		t.Errorf("Unexpected groups of filtered servers: %+v", got.Groups)
+	}
+
+	filter, _ = parseServerFilter(url.Values{"group": {"acme"}, "group_by": {"tag"}})
+	got = filter.apply(metrics)
+	if len(got.ServerMetrics) != 2 || got.TotalRx != 300 {
+		t.Errorf("Unexpected group filter result: %d servers, rx %d", len(got.ServerMetrics), got.TotalRx)
+	}
+	if len(got.Groups) != 2 || got.Groups["us"].TotalRx != 200 {
+		t.Errorf("Unexpected tag groups: %+v", got.Groups)
+	}
+
+	// Without a filter the monitor's metrics are returned as they are
+	filter, _ = parseServerFilter(url.Values{})
+	if filter.apply(metrics) != metrics {
+		t.Error("Unfiltered metrics should not be copied")
+	}
+
+	if _, err := parseServerFilter(url.Values{"group_by": {"region"}}); err == nil {
+		t.Error("Expected error for an unknown group_by")
+	}
+}
@@ -0,0 +1,97 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/monitor"
+	"bandwidth-monitor/store"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PercentileData represents burstable-billing percentiles for API, in bytes
+// per second
+type PercentileData struct {
+	Server     string    `json:"server,omitempty"`
+	Window     string    `json:"window"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Samples    int       `json:"samples"`
+	Rx95       uint64    `json:"rx95"`
+	Tx95       uint64    `json:"tx95"`
+	Rx99       uint64    `json:"rx99"`
+	Tx99       uint64    `json:"tx99"`
+	Billable95 uint64    `json:"billable95"`
+}
+
+// percentileData converts billing percentiles for API
+func percentileData(p *monitor.PercentileUsage) *PercentileData {
+	if p == nil {
+		return nil
+	}
+	return &PercentileData{
+		Window:     p.Window,
+		From:       p.From,
+		To:         p.To,
+		Samples:    p.Samples,
+		Rx95:       p.Rx95,
+		Tx95:       p.Tx95,
+		Rx99:       p.Rx99,
+		Tx99:       p.Tx99,
+		Billable95: p.Billable95,
+	}
+}
+
+// percentilesHandler handles the /api/percentiles endpoint. It computes the
+// percentiles of a server (or the aggregate without "server") over the
+// configured window, or the one given by "window" and "reset_day".
+func (d *Dashboard) percentilesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	settings := d.config.GetSettings()
+
+	series := query.Get("server")
+	if series == "" {
+		series = store.AggregateSeries
+	} else if d.config.GetServer(series) == nil {
+		d.writeJSONError(w, "Server not found", http.StatusNotFound)
+		return
+	}
+
+	window := settings.GetBillingWindow()
+	if query.Has("window") || query.Has("reset_day") {
+		resetDay := window.ResetDay
+		if v := query.Get("reset_day"); v != "" {
+			day, err := strconv.Atoi(v)
+			if err != nil {
+				d.writeJSONError(w, "Invalid 'reset_day' parameter", http.StatusBadRequest)
+				return
+			}
+			resetDay = day
+		}
+		parsed, err := config.ParseBillingWindow(query.Get("window"), resetDay)
+		if err != nil {
+			d.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+
+	usage, err := d.monitor.Percentiles(series, window, time.Now())
+	if err != nil {
+		log.Printf("Error computing percentiles: %v", err)
+		d.writeJSONError(w, "Failed to compute percentiles", http.StatusInternalServerError)
+		return
+	}
+
+	data := percentileData(usage)
+	if series != store.AggregateSeries {
+		data.Server = series
+	}
+	d.writeJSONResponse(w, data)
+}
@@ -0,0 +1,230 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// promMetric describes a single Prometheus metric family
+type promMetric struct {
+	name  string
+	help  string
+	kind  string // gauge or counter
+	value func(sm *monitor.ServerMetrics, stats monitor.PollStats) float64
+}
+
+// serverMetricFamilies are exported once per server, labelled by name, IP and interface
+var serverMetricFamilies = []promMetric{
+	{"bandwidth_monitor_server_up", "Whether the server is up (1) or marked down after consecutive failed polls (0).", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return boolToFloat(sm.Online) }},
+	{"bandwidth_monitor_server_rx_bytes_per_second", "Current inbound rate in bytes per second.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.Rx) }},
+	{"bandwidth_monitor_server_tx_bytes_per_second", "Current outbound rate in bytes per second.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.Tx) }},
+	{"bandwidth_monitor_server_rx_5m_bytes_per_second", "Inbound rate over the latest complete five-minute interval.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.FiveMinuteRx) }},
+	{"bandwidth_monitor_server_tx_5m_bytes_per_second", "Outbound rate over the latest complete five-minute interval.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.FiveMinuteTx) }},
+	{"bandwidth_monitor_server_rx_today_bytes", "Bytes received today.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.TotalRx) }},
+	{"bandwidth_monitor_server_tx_today_bytes", "Bytes transmitted today.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.TotalTx) }},
+	{"bandwidth_monitor_server_rx_avg_24h_bytes_per_second", "Average inbound rate over the last 24 hours.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.AvgRx24h) }},
+	{"bandwidth_monitor_server_tx_avg_24h_bytes_per_second", "Average outbound rate over the last 24 hours.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.AvgTx24h) }},
+	{"bandwidth_monitor_server_rx_peak_24h_bytes_per_second", "Peak hourly inbound rate over the last 24 hours.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.PeakRx) }},
+	{"bandwidth_monitor_server_tx_peak_24h_bytes_per_second", "Peak hourly outbound rate over the last 24 hours.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 { return float64(sm.PeakTx) }},
+	{"bandwidth_monitor_server_anomaly", "Whether a current rate deviates from the server's hour-of-week baseline (1) or not (0).", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			for _, a := range sm.Anomalies {
+				if a.Active {
+					return 1
+				}
+			}
+			return 0
+		}},
+	{"bandwidth_monitor_server_consecutive_failures", "Failed polls since the last successful one.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			if sm.Reachability == nil {
+				return 0
+			}
+			return float64(sm.Reachability.Failures)
+		}},
+	{"bandwidth_monitor_server_flapping", "Whether the server changes between up and down too often (1) or not (0).", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			return boolToFloat(sm.Reachability != nil && sm.Reachability.Flapping)
+		}},
+	{"bandwidth_monitor_server_last_seen_timestamp_seconds", "Unix time of the last successful poll.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			if sm.Reachability == nil || sm.Reachability.LastSeen.IsZero() {
+				return 0
+			}
+			return float64(sm.Reachability.LastSeen.Unix())
+		}},
+	{"bandwidth_monitor_server_uptime_24h_ratio", "Fraction of the last 24 hours the server was not down.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			return uptimeRatio(sm, func(r *monitor.Reachability) float64 { return r.Uptime24h })
+		}},
+	{"bandwidth_monitor_server_uptime_7d_ratio", "Fraction of the last 7 days the server was not down.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			return uptimeRatio(sm, func(r *monitor.Reachability) float64 { return r.Uptime7d })
+		}},
+	{"bandwidth_monitor_server_uptime_30d_ratio", "Fraction of the last 30 days the server was not down.", "gauge",
+		func(sm *monitor.ServerMetrics, _ monitor.PollStats) float64 {
+			return uptimeRatio(sm, func(r *monitor.Reachability) float64 { return r.Uptime30d })
+		}},
+	{"bandwidth_monitor_server_poll_duration_seconds", "Duration of the most recent poll.", "gauge",
+		func(_ *monitor.ServerMetrics, stats monitor.PollStats) float64 { return stats.LastDuration.Seconds() }},
+	{"bandwidth_monitor_server_polls_total", "Total number of polls.", "counter",
+		func(_ *monitor.ServerMetrics, stats monitor.PollStats) float64 { return float64(stats.Polls) }},
+	{"bandwidth_monitor_server_ssh_errors_total", "Total number of polls that failed with an SSH error.", "counter",
+		func(_ *monitor.ServerMetrics, stats monitor.PollStats) float64 { return float64(stats.SSHErrors) }},
+}
+
+// uptimeRatio returns an uptime percentage of a server as a fraction, 1
+// until it was polled
+func uptimeRatio(sm *monitor.ServerMetrics, uptime func(r *monitor.Reachability) float64) float64 {
+	if sm.Reachability == nil {
+		return 1
+	}
+	return uptime(sm.Reachability) / 100
+}
+
+// prometheusHandler handles the /metrics endpoint
+func (d *Dashboard) prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", prometheusContentType)
+	if err := writePrometheusMetrics(w, d.monitor.GetMetrics(), d.monitor.GetPollStats()); err != nil {
+		log.Printf("Error writing Prometheus metrics: %v", err)
+	}
+}
+
+// metricsAuth protects the /metrics endpoint. When a metrics token is
+// configured, scrapers authenticate with "Authorization: Bearer <token>"
+// using it or an API token; otherwise the regular dashboard auth applies.
+func (d *Dashboard) metricsAuth(next http.HandlerFunc) http.HandlerFunc {
+	if d.metricsToken == "" {
+		return d.requireAuth(next)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(d.metricsToken)) != 1 && d.tokenUser(token) == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="Bandwidth Monitor Metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// writePrometheusMetrics writes metrics in the Prometheus text exposition format
+func writePrometheusMetrics(out io.Writer, metrics *monitor.AggregateMetrics, stats map[string]monitor.PollStats) error {
+	w := bufio.NewWriter(out)
+
+	names := make([]string, 0, len(metrics.ServerMetrics))
+	for name := range metrics.ServerMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, family := range serverMetricFamilies {
+		fmt.Fprintf(w, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", family.name, family.kind)
+		for _, name := range names {
+			sm := metrics.ServerMetrics[name]
+			fmt.Fprintf(w, "%s{server=\"%s\",ip=\"%s\",interface=\"%s\"} %s\n",
+				family.name,
+				escapeLabelValue(sm.Name),
+				escapeLabelValue(sm.IP),
+				escapeLabelValue(sm.Interface),
+				formatFloat(family.value(sm, stats[name])),
+			)
+		}
+	}
+
+	interfaceFamilies := []struct {
+		name  string
+		help  string
+		value func(im monitor.InterfaceMetrics) uint64
+	}{
+		{"bandwidth_monitor_interface_rx_bytes_per_second", "Current inbound rate of a network interface.", func(im monitor.InterfaceMetrics) uint64 { return im.Rx }},
+		{"bandwidth_monitor_interface_tx_bytes_per_second", "Current outbound rate of a network interface.", func(im monitor.InterfaceMetrics) uint64 { return im.Tx }},
+		{"bandwidth_monitor_interface_rx_today_bytes", "Bytes received today on a network interface.", func(im monitor.InterfaceMetrics) uint64 { return im.TotalRx }},
+		{"bandwidth_monitor_interface_tx_today_bytes", "Bytes transmitted today on a network interface.", func(im monitor.InterfaceMetrics) uint64 { return im.TotalTx }},
+	}
+
+	for _, family := range interfaceFamilies {
+		fmt.Fprintf(w, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", family.name)
+		for _, name := range names {
+			for _, im := range metrics.ServerMetrics[name].Interfaces {
+				fmt.Fprintf(w, "%s{server=\"%s\",interface=\"%s\"} %s\n",
+					family.name,
+					escapeLabelValue(name),
+					escapeLabelValue(im.Name),
+					formatFloat(float64(family.value(im))),
+				)
+			}
+		}
+	}
+
+	aggregates := []struct {
+		name  string
+		help  string
+		value float64
+	}{
+		{"bandwidth_monitor_total_rx_bytes_per_second", "Current inbound rate summed over all online servers.", float64(metrics.TotalRx)},
+		{"bandwidth_monitor_total_tx_bytes_per_second", "Current outbound rate summed over all online servers.", float64(metrics.TotalTx)},
+		{"bandwidth_monitor_grand_total_avg_bytes_per_second", "Sum of all servers' 24h average rates.", float64(metrics.GrandTotalAvg)},
+		{"bandwidth_monitor_grand_total_peak_bytes_per_second", "Sum of all servers' 24h peak rates.", float64(metrics.GrandTotalPeak)},
+		{"bandwidth_monitor_last_update_timestamp_seconds", "Unix time of the last aggregate update.", float64(metrics.UpdatedAt.Unix())},
+	}
+	if metrics.UpdatedAt.IsZero() {
+		aggregates[len(aggregates)-1].value = 0
+	}
+
+	for _, a := range aggregates {
+		fmt.Fprintf(w, "# HELP %s %s\n", a.name, a.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", a.name)
+		fmt.Fprintf(w, "%s %s\n", a.name, formatFloat(a.value))
+	}
+
+	return w.Flush()
+}
+
+// escapeLabelValue escapes a label value per the exposition format
+func escapeLabelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
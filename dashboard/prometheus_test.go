@@ -0,0 +1,94 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	metrics := &monitor.AggregateMetrics{
+		TotalRx: 1500,
+		TotalTx: 2500,
+		ServerMetrics: map[string]*monitor.ServerMetrics{
+			"web-1": {Name: "web-1", IP: "10.0.0.1", Interface: "eth0", Online: true, Rx: 1500, Tx: 2500,
+				Interfaces: []monitor.InterfaceMetrics{{Name: "eth0", Rx: 1500, Tx: 2500, TotalRx: 42}},
+				Anomalies:  []monitor.AnomalyEvent{{Field: "rx", Kind: monitor.AnomalySpike, Active: true}},
+				Reachability: &monitor.Reachability{State: monitor.ReachabilityUp, LastSeen: time.Unix(1770387600, 0),
+					Failures: 1, Flapping: true, Uptime24h: 99.5, Uptime7d: 100, Uptime30d: 100}},
+			`odd"name`: {Name: `odd"name`, IP: "10.0.0.2", Interface: "ens3", Online: false},
+		},
+		UpdatedAt: time.Unix(1770387600, 0),
+	}
+	stats := map[string]monitor.PollStats{
+		"web-1": {Polls: 10, SSHErrors: 2, LastDuration: 250 * time.Millisecond},
+	}
+
+	var sb strings.Builder
+	if err := writePrometheusMetrics(&sb, metrics, stats); err != nil {
+		t.Fatalf("writePrometheusMetrics failed: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		"# TYPE bandwidth_monitor_server_up gauge",
+		`bandwidth_monitor_server_up{server="web-1",ip="10.0.0.1",interface="eth0"} 1`,
+		`bandwidth_monitor_server_up{server="odd\"name",ip="10.0.0.2",interface="ens3"} 0`,
+		`bandwidth_monitor_server_rx_bytes_per_second{server="web-1",ip="10.0.0.1",interface="eth0"} 1500`,
+		`bandwidth_monitor_server_anomaly{server="web-1",ip="10.0.0.1",interface="eth0"} 1`,
+		`bandwidth_monitor_server_anomaly{server="odd\"name",ip="10.0.0.2",interface="ens3"} 0`,
+		`bandwidth_monitor_server_consecutive_failures{server="web-1",ip="10.0.0.1",interface="eth0"} 1`,
+		`bandwidth_monitor_server_flapping{server="web-1",ip="10.0.0.1",interface="eth0"} 1`,
+		`bandwidth_monitor_server_last_seen_timestamp_seconds{server="web-1",ip="10.0.0.1",interface="eth0"} 1.7703876e+09`,
+		`bandwidth_monitor_server_uptime_24h_ratio{server="web-1",ip="10.0.0.1",interface="eth0"} 0.995`,
+		`bandwidth_monitor_server_uptime_24h_ratio{server="odd\"name",ip="10.0.0.2",interface="ens3"} 1`,
+		"# TYPE bandwidth_monitor_server_ssh_errors_total counter",
+		`bandwidth_monitor_server_ssh_errors_total{server="web-1",ip="10.0.0.1",interface="eth0"} 2`,
+		`bandwidth_monitor_server_poll_duration_seconds{server="web-1",ip="10.0.0.1",interface="eth0"} 0.25`,
+		`bandwidth_monitor_interface_rx_bytes_per_second{server="web-1",interface="eth0"} 1500`,
+		`bandwidth_monitor_interface_rx_today_bytes{server="web-1",interface="eth0"} 42`,
+		"bandwidth_monitor_total_tx_bytes_per_second 2500",
+		"bandwidth_monitor_last_update_timestamp_seconds 1.7703876e+09",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Missing line %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsAuth(t *testing.T) {
+	d := newAuthDashboard(t)
+	d.metricsToken = "scrape-token"
+	apiToken, _ := d.config.AddToken("prometheus", true, 0, time.Now())
+	handler := d.metricsAuth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		setup  func(r *http.Request)
+		status int
+	}{
+		{"no credentials", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"wrong token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"basic auth is not accepted", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusUnauthorized},
+		{"token without scheme", func(r *http.Request) { r.Header.Set("Authorization", "scrape-token") }, http.StatusUnauthorized},
+		{"api token without scheme", func(r *http.Request) { r.Header.Set("Authorization", apiToken) }, http.StatusUnauthorized},
+		{"valid token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer scrape-token") }, http.StatusOK},
+		{"api token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+apiToken) }, http.StatusOK},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		c.setup(r)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s: got status %d, want %d", c.name, w.Code, c.status)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookieName = "bm_session"
+	csrfHeaderName    = "X-CSRF-Token"
+	csrfFormField     = "csrf_token"
+	staticLoginPath   = "static/login.html"
+)
+
+// Login rate limiting: at most loginMaxFailures failed attempts per IP within loginWindow
+const (
+	loginMaxFailures = 5
+	loginWindow      = 15 * time.Minute
+)
+
+// sessionClaims is the signed payload of a session cookie
+type sessionClaims struct {
+	Username string `json:"u"`
+	Expires  int64  `json:"e"`
+	Nonce    string `json:"n"`
+	Key      string `json:"k"` // Fingerprint of the password hash; a password reset ends the session
+}
+
+// sessionManager issues and verifies HMAC-signed session cookies. Sessions
+// are stateless apart from a list of nonces revoked by logout.
+type sessionManager struct {
+	secret []byte
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // Nonce -> session expiry
+}
+
+func newSessionManager(secret string) *sessionManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		// Sessions will not survive a restart
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &sessionManager{secret: key, revoked: make(map[string]time.Time)}
+}
+
+// passwordKey fingerprints a password hash so that sessions end when it changes
+func passwordKey(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *sessionManager) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// issue creates a signed session value for a user
+func (s *sessionManager) issue(u *config.UserConfig, lifetime time.Duration, now time.Time) (string, sessionClaims, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", sessionClaims{}, fmt.Errorf("failed to generate session nonce: %w", err)
+	}
+
+	claims := sessionClaims{
+		Username: u.Username,
+		Expires:  now.Add(lifetime).Unix(),
+		Nonce:    hex.EncodeToString(nonce),
+		Key:      passwordKey(u.PasswordHash),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", sessionClaims{}, fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload)), claims, nil
+}
+
+// parse verifies a session value and returns its claims
+func (s *sessionManager) parse(value string, now time.Time) (*sessionClaims, bool) {
+	payloadPart, sigPart, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return nil, false
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return nil, false
+	}
+
+	var claims sessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, false
+	}
+	if now.Unix() >= claims.Expires {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	_, revoked := s.revoked[claims.Nonce]
+	s.mu.Unlock()
+	if revoked {
+		return nil, false
+	}
+	return &claims, true
+}
+
+// revoke invalidates a session before it expires
+func (s *sessionManager) revoke(claims *sessionClaims, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for nonce, expires := range s.revoked {
+		if now.Unix() >= expires.Unix() {
+			delete(s.revoked, nonce)
+		}
+	}
+	s.revoked[claims.Nonce] = time.Unix(claims.Expires, 0)
+}
+
+// csrfToken derives the CSRF token of a session
+func (s *sessionManager) csrfToken(claims *sessionClaims) string {
+	return hex.EncodeToString(s.sign([]byte("csrf:" + claims.Nonce)))
+}
+
+// loginLimiter counts failed logins per client IP in a sliding window
+type loginLimiter struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	swept    time.Time // Last time failures of all IPs were pruned
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{failures: make(map[string][]time.Time)}
+}
+
+// recent drops failures outside the window. The caller must hold l.mu.
+func (l *loginLimiter) recent(ip string, now time.Time) []time.Time {
+	kept := l.failures[ip][:0]
+	for _, t := range l.failures[ip] {
+		if now.Sub(t) < loginWindow {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(l.failures, ip)
+		return nil
+	}
+	l.failures[ip] = kept
+	return kept
+}
+
+// retryAfter returns how long ip must wait before trying again, zero if it may try now
+func (l *loginLimiter) retryAfter(ip string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := l.recent(ip, now)
+	if len(failures) < loginMaxFailures {
+		return 0
+	}
+	return failures[len(failures)-loginMaxFailures].Add(loginWindow).Sub(now)
+}
+
+// fail records a failed login. IPs that never try again are forgotten once
+// per window, so that the map stays bounded.
+func (l *loginLimiter) fail(ip string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= loginWindow {
+		for other := range l.failures {
+			l.recent(other, now)
+		}
+		l.swept = now
+	}
+	l.failures[ip] = append(l.recent(ip, now), now)
+}
+
+// reset forgets the failures of ip after a successful login
+func (l *loginLimiter) reset(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+// clientIP returns the address of the connecting client. Proxy headers are
+// ignored so they cannot be used to dodge the login limit.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isSecureRequest reports whether the client connected over HTTPS
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// isMutating reports whether a request method changes state and needs a CSRF token
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// sessionUser returns the user of a valid session cookie
+func (d *Dashboard) sessionUser(r *http.Request) (*authUser, *sessionClaims) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, nil
+	}
+	claims, ok := d.sessions.parse(cookie.Value, time.Now())
+	if !ok {
+		return nil, nil
+	}
+
+	u := d.config.GetUser(claims.Username)
+	if u == nil || subtle.ConstantTimeCompare([]byte(passwordKey(u.PasswordHash)), []byte(claims.Key)) != 1 {
+		return nil, nil
+	}
+	return &authUser{Username: u.Username, Role: u.Role, csrf: d.sessions.csrfToken(claims)}, claims
+}
+
+// loginPageHandler serves the login form
+func (d *Dashboard) loginPageHandler(w http.ResponseWriter, r *http.Request) {
+	if !d.authEnabled {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	content, err := staticFiles.ReadFile(staticLoginPath)
+	if err != nil {
+		http.Error(w, "Failed to load login page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(content)
+}
+
+// LoginRequest is the body of a JSON login
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginHandler handles POST /login with a form or JSON body and sets the session cookie
+func (d *Dashboard) loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ip := clientIP(r)
+	now := time.Now()
+	if wait := d.limiter.retryAfter(ip, now); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		d.writeJSONError(w, "Too many failed logins, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	var req LoginRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			d.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Username = r.PostFormValue("username")
+		req.Password = r.PostFormValue("password")
+	}
+
+	if d.authenticate(req.Username, req.Password) == nil {
+		d.limiter.fail(ip, now)
+		d.writeJSONError(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	d.limiter.reset(ip)
+
+	u := d.config.GetUser(req.Username)
+	if u == nil {
+		d.writeJSONError(w, "Invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	lifetime := d.config.GetSettings().GetSessionLifetime()
+	value, claims, err := d.sessions.issue(u, lifetime, now)
+	if err != nil {
+		d.writeJSONError(w, "Failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Unix(claims.Expires, 0),
+		MaxAge:   int(lifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	d.writeJSONResponse(w, UserData{
+		Username:  u.Username,
+		Role:      string(u.Role),
+		CSRFToken: d.sessions.csrfToken(&claims),
+	})
+}
+
+// logoutHandler handles POST /logout, revoking the session and clearing the cookie
+func (d *Dashboard) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if u, claims := d.sessionUser(r); claims != nil {
+		if !validCSRF(r, u.csrf) {
+			d.writeJSONError(w, "Invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		d.sessions.revoke(claims, time.Now())
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	d.writeJSONResponse(w, map[string]string{"status": "logged out"})
+}
+
+// redirectToLogin sends browsers to the login page, keeping the requested path
+func redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+}
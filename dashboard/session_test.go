@@ -0,0 +1,128 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// login posts credentials and returns the response
+func login(d *Dashboard, user, pass string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"`+user+`","password":"`+pass+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	d.loginHandler(w, r)
+	return w
+}
+
+func TestSessionLogin(t *testing.T) {
+	d := newAuthDashboard(t)
+	ok := d.requireAuth(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	w := login(d, "op", "secret")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Login failed with status %d: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("Unexpected session cookie: %+v", cookies)
+	}
+	session := cookies[0]
+
+	request := func(method, csrf string, c *http.Cookie) int {
+		r := httptest.NewRequest(method, "/api/alerts/ack", nil)
+		r.AddCookie(c)
+		if csrf != "" {
+			r.Header.Set(csrfHeaderName, csrf)
+		}
+		w := httptest.NewRecorder()
+		ok(w, r)
+		return w.Code
+	}
+
+	if code := request(http.MethodGet, "", session); code != http.StatusOK {
+		t.Errorf("GET with session: got status %d, want %d", code, http.StatusOK)
+	}
+	if code := request(http.MethodPost, "", session); code != http.StatusForbidden {
+		t.Errorf("POST without CSRF token: got status %d, want %d", code, http.StatusForbidden)
+	}
+
+	claims, _ := d.sessions.parse(session.Value, time.Now())
+	csrf := d.sessions.csrfToken(claims)
+	if code := request(http.MethodPost, csrf, session); code != http.StatusOK {
+		t.Errorf("POST with CSRF token: got status %d, want %d", code, http.StatusOK)
+	}
+
+	// A tampered cookie is rejected
+	forged := *session
+	forged.Value = strings.Replace(session.Value, ".", "x.", 1)
+	if code := request(http.MethodGet, "", &forged); code != http.StatusUnauthorized {
+		t.Errorf("Tampered cookie: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	// Logout revokes the session
+	r := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	r.AddCookie(session)
+	r.Header.Set(csrfHeaderName, csrf)
+	lw := httptest.NewRecorder()
+	d.logoutHandler(lw, r)
+	if lw.Code != http.StatusOK {
+		t.Fatalf("Logout failed with status %d", lw.Code)
+	}
+	if code := request(http.MethodGet, "", session); code != http.StatusUnauthorized {
+		t.Errorf("GET after logout: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	// A password reset ends existing sessions
+	w = login(d, "view", "secret")
+	session = w.Result().Cookies()[0]
+	d.config.SetUserPassword("view", "changed")
+	if code := request(http.MethodGet, "", session); code != http.StatusUnauthorized {
+		t.Errorf("GET after password reset: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	d := newAuthDashboard(t)
+	now := time.Now()
+
+	value, _, err := d.sessions.issue(d.config.GetUser("admin"), time.Hour, now)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	if _, ok := d.sessions.parse(value, now.Add(59*time.Minute)); !ok {
+		t.Error("Session rejected before expiry")
+	}
+	if _, ok := d.sessions.parse(value, now.Add(time.Hour)); ok {
+		t.Error("Session accepted after expiry")
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	d := newAuthDashboard(t)
+
+	for i := 0; i < loginMaxFailures; i++ {
+		if w := login(d, "admin", "wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: got status %d, want %d", i, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Even the right password is refused while blocked
+	w := login(d, "admin", "secret")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d", w.Code)
+	}
+
+	now := time.Now()
+	if wait := d.limiter.retryAfter("192.0.2.1", now.Add(loginWindow)); wait != 0 {
+		t.Errorf("Expected block to expire after the window, still waiting %s", wait)
+	}
+
+	// Failures of IPs that never come back are swept by later failures
+	d.limiter.fail("192.0.2.2", now.Add(2*loginWindow))
+	if n := len(d.limiter.failures); n != 1 {
+		t.Errorf("Expected stale IPs to be swept, %d left", n)
+	}
+}
@@ -0,0 +1,318 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	streamBufferSize   = 512              // Events kept for Last-Event-ID replay
+	streamHeartbeat    = 15 * time.Second // Comment lines that keep proxies from closing idle streams
+	streamRetry        = 3000             // Client reconnect delay in milliseconds
+	streamWriteTimeout = 10 * time.Second
+)
+
+// AggregateUpdateData is the payload of "aggregate" stream events
+type AggregateUpdateData struct {
+	TotalRx        uint64                `json:"totalRx"`
+	TotalTx        uint64                `json:"totalTx"`
+	GrandTotalAvg  uint64                `json:"grandTotalAvg"`
+	GrandTotalPeak uint64                `json:"grandTotalPeak"`
+	DominantServer string                `json:"dominantServer"`
+	Percentiles    *PercentileData       `json:"percentiles,omitempty"`
+	Groups         map[string]*GroupData `json:"groups,omitempty"`
+	Alerts         []AlertData           `json:"alerts"`
+	History        *HistoryEntryData     `json:"history,omitempty"` // Newest history entry, if one was added
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+// streamEvent is one message of the /api/stream event sequence
+type streamEvent struct {
+	seq  uint64
+	kind string // server, remove or aggregate
+	data []byte
+}
+
+// streamHub turns monitor updates into a sequence of delta events shared by
+// all stream clients. Each event carries an ID of the form "<epoch>-<seq>";
+// the epoch changes on every restart so stale IDs from a previous process
+// are never replayed.
+type streamHub struct {
+	source func() (*monitor.AggregateMetrics, []AlertData)
+	epoch  string
+
+	mu        sync.Mutex
+	seq       uint64
+	events    []streamEvent
+	servers   map[string][]byte // Last published state per server
+	aggregate []byte
+	updatedAt time.Time
+	clients   map[chan struct{}]struct{}
+}
+
+func newStreamHub(source func() (*monitor.AggregateMetrics, []AlertData)) *streamHub {
+	return &streamHub{
+		source:  source,
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		servers: make(map[string][]byte),
+		clients: make(map[chan struct{}]struct{}),
+	}
+}
+
+// run publishes deltas whenever the monitor signals a change
+func (h *streamHub) run(updates <-chan struct{}) {
+	for range updates {
+		h.publish()
+	}
+}
+
+// publish compares the current metrics with the last published state and
+// appends an event for every server or aggregate that changed
+func (h *streamHub) publish() {
+	metrics, alerts := h.source()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(metrics.ServerMetrics))
+	for name := range metrics.ServerMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changed := false
+	for _, name := range names {
+		data, err := json.Marshal(serverMetricData(metrics.ServerMetrics[name]))
+		if err != nil {
+			log.Printf("Error encoding stream event: %v", err)
+			continue
+		}
+		if bytes.Equal(h.servers[name], data) {
+			continue
+		}
+		h.servers[name] = data
+		h.append("server", data)
+		changed = true
+	}
+
+	for name := range h.servers {
+		if _, ok := metrics.ServerMetrics[name]; ok {
+			continue
+		}
+		delete(h.servers, name)
+		data, _ := json.Marshal(map[string]string{"name": name})
+		h.append("remove", data)
+		changed = true
+	}
+
+	update := AggregateUpdateData{
+		TotalRx:        metrics.TotalRx,
+		TotalTx:        metrics.TotalTx,
+		GrandTotalAvg:  metrics.GrandTotalAvg,
+		GrandTotalPeak: metrics.GrandTotalPeak,
+		DominantServer: metrics.DominantServer,
+		Percentiles:    percentileData(metrics.Percentiles),
+		Groups:         groupData(metrics.Groups),
+		Alerts:         alerts,
+		UpdatedAt:      metrics.UpdatedAt,
+	}
+	if !metrics.UpdatedAt.Equal(h.updatedAt) && len(metrics.History) > 0 {
+		entry := historyEntryData(metrics.History[len(metrics.History)-1])
+		update.History = &entry
+	}
+	h.updatedAt = metrics.UpdatedAt
+
+	if data, err := json.Marshal(update); err != nil {
+		log.Printf("Error encoding stream event: %v", err)
+	} else if !bytes.Equal(h.aggregate, data) {
+		h.aggregate = data
+		h.append("aggregate", data)
+		changed = true
+	}
+
+	if !changed {
+		return
+	}
+	for ch := range h.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// append adds an event, dropping the oldest beyond the buffer size. The
+// caller must hold h.mu.
+func (h *streamHub) append(kind string, data []byte) {
+	h.seq++
+	h.events = append(h.events, streamEvent{seq: h.seq, kind: kind, data: data})
+	if len(h.events) > streamBufferSize {
+		h.events = append(h.events[:0:0], h.events[len(h.events)-streamBufferSize:]...)
+	}
+}
+
+// since returns the events after seq. ok is false if some of them are no
+// longer buffered and the client needs a full snapshot instead.
+func (h *streamHub) since(seq uint64) ([]streamEvent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if seq > h.seq {
+		return nil, false
+	}
+	if seq == h.seq {
+		return nil, true
+	}
+	if len(h.events) == 0 || h.events[0].seq > seq+1 {
+		return nil, false
+	}
+
+	start := sort.Search(len(h.events), func(i int) bool {
+		return h.events[i].seq > seq
+	})
+	events := make([]streamEvent, len(h.events)-start)
+	copy(events, h.events[start:])
+	return events, true
+}
+
+// snapshot returns the full current state and the sequence number it
+// corresponds to. Events after that number may already be included, which
+// is harmless because server and aggregate events carry full state.
+func (h *streamHub) snapshot() (uint64, []byte, error) {
+	h.mu.Lock()
+	seq := h.seq
+	h.mu.Unlock()
+
+	metrics, alerts := h.source()
+	data, err := json.Marshal(metricsResponse(metrics, alerts))
+	return seq, data, err
+}
+
+// parseID parses a Last-Event-ID header. ok is false if the ID is missing or
+// was issued by a different process.
+func (h *streamHub) parseID(id string) (uint64, bool) {
+	epoch, seqText, found := strings.Cut(id, "-")
+	if !found || epoch != h.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+func (h *streamHub) formatID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", h.epoch, seq)
+}
+
+func (h *streamHub) addClient() chan struct{} {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.clients[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *streamHub) removeClient(ch chan struct{}) {
+	h.mu.Lock()
+	delete(h.clients, ch)
+	h.mu.Unlock()
+}
+
+// streamHandler handles the /api/stream Server-Sent Events endpoint. A new
+// client gets a "snapshot" event with the full metrics, followed by
+// "server", "remove" and "aggregate" deltas. Reconnecting clients that send
+// Last-Event-ID get the missed deltas replayed, or a new snapshot if they
+// are no longer buffered.
+func (d *Dashboard) streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	h := d.stream
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	wake := h.addClient()
+	defer h.removeClient(wake)
+
+	write := func(format string, args ...interface{}) bool {
+		// The server's WriteTimeout would otherwise end long-lived streams
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	sendSnapshot := func() (uint64, bool) {
+		seq, data, err := h.snapshot()
+		if err != nil {
+			log.Printf("Error encoding stream snapshot: %v", err)
+			return seq, false
+		}
+		return seq, write("id: %s\nevent: snapshot\ndata: %s\n\n", h.formatID(seq), data)
+	}
+
+	if !write("retry: %d\n\n", streamRetry) {
+		return
+	}
+
+	seq, resumed := h.parseID(r.Header.Get("Last-Event-ID"))
+	if !resumed {
+		var ok bool
+		if seq, ok = sendSnapshot(); !ok {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	// Replay missed events right away when resuming
+	pending := resumed
+
+	for {
+		if pending {
+			pending = false
+			events, ok := h.since(seq)
+			if !ok {
+				if seq, ok = sendSnapshot(); !ok {
+					return
+				}
+				continue
+			}
+			for _, ev := range events {
+				if !write("id: %s\nevent: %s\ndata: %s\n\n", h.formatID(ev.seq), ev.kind, ev.data) {
+					return
+				}
+				seq = ev.seq
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+			pending = true
+		case <-heartbeat.C:
+			if !write(": ping\n\n") {
+				return
+			}
+		}
+	}
+}
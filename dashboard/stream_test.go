@@ -0,0 +1,198 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSource returns mutable metrics for the stream hub
+type fakeSource struct {
+	mu      sync.Mutex
+	metrics *monitor.AggregateMetrics
+}
+
+func (f *fakeSource) get() (*monitor.AggregateMetrics, []AlertData) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	copied := *f.metrics
+	copied.ServerMetrics = make(map[string]*monitor.ServerMetrics)
+	for k, v := range f.metrics.ServerMetrics {
+		copied.ServerMetrics[k] = v
+	}
+	return &copied, []AlertData{}
+}
+
+func (f *fakeSource) update(fn func(m *monitor.AggregateMetrics)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f.metrics)
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{metrics: &monitor.AggregateMetrics{
+		ServerMetrics: map[string]*monitor.ServerMetrics{
+			"web": {Name: "web", Online: true, Rx: 100},
+			"db":  {Name: "db", Online: true, Rx: 200},
+		},
+	}}
+}
+
+func TestStreamHubDeltas(t *testing.T) {
+	src := newFakeSource()
+	h := newStreamHub(src.get)
+
+	h.publish()
+	events, ok := h.since(0)
+	if !ok || len(events) != 3 {
+		t.Fatalf("Expected 3 initial events (2 servers + aggregate), got %d", len(events))
+	}
+
+	// Nothing changed
+	h.publish()
+	if events, _ := h.since(3); len(events) != 0 {
+		t.Errorf("Expected no events without changes, got %d", len(events))
+	}
+
+	// One server changes, another is removed
+	src.update(func(m *monitor.AggregateMetrics) {
+		m.ServerMetrics["web"] = &monitor.ServerMetrics{Name: "web", Online: true, Rx: 150}
+		delete(m.ServerMetrics, "db")
+	})
+	h.publish()
+
+	events, ok = h.since(3)
+	if !ok || len(events) != 2 {
+		t.Fatalf("Expected 2 delta events, got %d", len(events))
+	}
+	if events[0].kind != "server" || !strings.Contains(string(events[0].data), `"rx":150`) {
+		t.Errorf("Unexpected server event: %s %s", events[0].kind, events[0].data)
+	}
+	if events[1].kind != "remove" || string(events[1].data) != `{"name":"db"}` {
+		t.Errorf("Unexpected remove event: %s %s", events[1].kind, events[1].data)
+	}
+
+	// An aggregate update carries the newest history entry
+	src.update(func(m *monitor.AggregateMetrics) {
+		m.UpdatedAt = time.Unix(1000, 0)
+		m.History = []monitor.HistoryEntry{{Timestamp: time.Unix(1000, 0), TotalRx: 150}}
+	})
+	h.publish()
+	events, _ = h.since(5)
+	if len(events) != 1 || events[0].kind != "aggregate" || !strings.Contains(string(events[0].data), `"history":{"timestamp":1000`) {
+		t.Errorf("Unexpected aggregate events: %+v", events)
+	}
+
+	// IDs from the future or a different process need a snapshot
+	if _, ok := h.since(100); ok {
+		t.Error("Expected resync for unknown sequence number")
+	}
+	if _, ok := h.parseID("otherepoch-3"); ok {
+		t.Error("Expected IDs of another process to be rejected")
+	}
+	if seq, ok := h.parseID(h.formatID(4)); !ok || seq != 4 {
+		t.Errorf("parseID round trip failed: %d %v", seq, ok)
+	}
+}
+
+func TestStreamHubBufferOverflow(t *testing.T) {
+	src := newFakeSource()
+	h := newStreamHub(src.get)
+
+	for i := 0; i < streamBufferSize+10; i++ {
+		src.update(func(m *monitor.AggregateMetrics) {
+			m.ServerMetrics["web"] = &monitor.ServerMetrics{Name: "web", Rx: uint64(i)}
+		})
+		h.publish()
+	}
+
+	if _, ok := h.since(1); ok {
+		t.Error("Expected resync when events were dropped from the buffer")
+	}
+	if events, ok := h.since(h.seq - 1); !ok || len(events) != 1 {
+		t.Errorf("Expected the newest event to be replayable, got %d, %v", len(events), ok)
+	}
+}
+
+// readEvent reads one SSE message, skipping comments and retry hints
+func readEvent(t *testing.T, r *bufio.Reader) (id, kind, data string) {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if kind != "" {
+				return id, kind, data
+			}
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			kind = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamHandler(t *testing.T) {
+	src := newFakeSource()
+	d := &Dashboard{stream: newStreamHub(src.get)}
+	d.stream.publish()
+
+	srv := httptest.NewServer(http.HandlerFunc(d.streamHandler))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type mismatch. Got %s", ct)
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	_, kind, data := readEvent(t, reader)
+	if kind != "snapshot" || !strings.Contains(data, `"web"`) || !strings.Contains(data, `"db"`) {
+		t.Fatalf("Expected snapshot with both servers, got %s %s", kind, data)
+	}
+
+	src.update(func(m *monitor.AggregateMetrics) {
+		m.ServerMetrics["db"] = &monitor.ServerMetrics{Name: "db", Online: false}
+	})
+	d.stream.publish()
+
+	id, kind, data := readEvent(t, reader)
+	if kind != "server" || !strings.Contains(data, `"online":false`) {
+		t.Fatalf("Expected server delta, got %s %s", kind, data)
+	}
+	resp.Body.Close()
+
+	// Changes while disconnected are replayed after Last-Event-ID
+	src.update(func(m *monitor.AggregateMetrics) {
+		m.ServerMetrics["web"] = &monitor.ServerMetrics{Name: "web", Online: true, Rx: 999}
+	})
+	d.stream.publish()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", id)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	_, kind, data = readEvent(t, bufio.NewReader(resp.Body))
+	if kind != "server" || !strings.Contains(data, `"rx":999`) {
+		t.Errorf("Expected replayed server delta, got %s %s", kind, data)
+	}
+}
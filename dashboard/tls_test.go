@@ -0,0 +1,82 @@
+package dashboard
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelfSignedCertReload(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	generated, err := EnsureSelfSignedCert(certPath, keyPath)
+	if err != nil || !generated {
+		t.Fatalf("Expected certificate to be generated, got %v, %v", generated, err)
+	}
+	if info, err := os.Stat(keyPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Key file should be private, got %v", info.Mode())
+	}
+
+	// Existing files are kept
+	if generated, _ := EnsureSelfSignedCert(certPath, keyPath); generated {
+		t.Errorf("Existing certificate was regenerated")
+	}
+
+	certs := newCertReloader(certPath, keyPath)
+	if err := certs.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	first, _ := certs.GetCertificate(&tls.ClientHelloInfo{})
+
+	// Replace the files and reload
+	os.Remove(certPath)
+	os.Remove(keyPath)
+	if _, err := EnsureSelfSignedCert(certPath, keyPath); err != nil {
+		t.Fatalf("EnsureSelfSignedCert failed: %v", err)
+	}
+	if err := certs.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	second, _ := certs.GetCertificate(&tls.ClientHelloInfo{})
+	if first == second || string(first.Certificate[0]) == string(second.Certificate[0]) {
+		t.Errorf("Reload did not pick up the new certificate")
+	}
+
+	// A broken file keeps the current certificate
+	os.WriteFile(certPath, []byte("garbage"), 0644)
+	if err := certs.Reload(); err == nil {
+		t.Errorf("Expected error for invalid certificate")
+	}
+	if current, _ := certs.GetCertificate(&tls.ClientHelloInfo{}); current != second {
+		t.Errorf("Invalid certificate replaced the working one")
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	cases := []struct {
+		host string
+		port int
+		want string
+	}{
+		{"monitor.example.com:80", 8443, "https://monitor.example.com:8443/api/metrics?x=1"},
+		{"monitor.example.com", 443, "https://monitor.example.com/api/metrics?x=1"},
+		{"[::1]:80", 443, "https://[::1]/api/metrics?x=1"},
+		{"[::1]", 443, "https://[::1]/api/metrics?x=1"},
+		{"[::1]", 8443, "https://[::1]:8443/api/metrics?x=1"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/api/metrics?x=1", nil)
+		r.Host = c.host
+		w := httptest.NewRecorder()
+		httpsRedirect(c.port)(w, r)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != c.want {
+			t.Errorf("%s: got %d %s, want %s", c.host, w.Code, w.Header().Get("Location"), c.want)
+		}
+	}
+}
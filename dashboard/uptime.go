@@ -0,0 +1,100 @@
+package dashboard
+
+import (
+	"bandwidth-monitor/monitor"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ReachabilityData represents the up/down state of a server for API
+type ReachabilityData struct {
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Failures  int       `json:"failures"`
+	Flapping  bool      `json:"flapping"`
+	Changes   int       `json:"changes"`
+	Uptime24h float64   `json:"uptime24h"`
+	Uptime7d  float64   `json:"uptime7d"`
+	Uptime30d float64   `json:"uptime30d"`
+}
+
+// OutageData represents a period a server was down for API
+type OutageData struct {
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end"`      // Null while ongoing
+	Duration float64    `json:"duration"` // Seconds
+}
+
+// UptimeData represents the reachability history of a server for API
+type UptimeData struct {
+	Server       string            `json:"server"`
+	Reachability *ReachabilityData `json:"reachability"`
+	Outages      []OutageData      `json:"outages"`
+}
+
+// reachabilityData converts the reachability of a server for API
+func reachabilityData(r *monitor.Reachability) *ReachabilityData {
+	if r == nil {
+		return nil
+	}
+	return &ReachabilityData{
+		State:     r.State,
+		Since:     r.Since,
+		LastSeen:  r.LastSeen,
+		Failures:  r.Failures,
+		Flapping:  r.Flapping,
+		Changes:   r.Changes,
+		Uptime24h: r.Uptime24h,
+		Uptime7d:  r.Uptime7d,
+		Uptime30d: r.Uptime30d,
+	}
+}
+
+// outageData converts outages for API
+func outageData(outages []monitor.Outage, now time.Time) []OutageData {
+	data := make([]OutageData, len(outages))
+	for i, o := range outages {
+		data[i] = OutageData{Start: o.Start, Duration: o.Duration(now).Seconds()}
+		if !o.End.IsZero() {
+			end := o.End
+			data[i].End = &end
+		}
+	}
+	return data
+}
+
+// uptimeHandler handles the /api/uptime endpoint. The reachability and the
+// outages of the last 30 days are returned for every server, or for the one
+// named by "server".
+func (d *Dashboard) uptimeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		d.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	server := r.URL.Query().Get("server")
+	if server != "" && d.config.GetServer(server) == nil {
+		d.writeJSONError(w, "Server not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	uptime := make([]UptimeData, 0)
+	for name, sm := range d.monitor.GetMetrics().ServerMetrics {
+		if server != "" && name != server {
+			continue
+		}
+		uptime = append(uptime, UptimeData{
+			Server:       name,
+			Reachability: reachabilityData(sm.Reachability),
+			Outages:      outageData(d.monitor.GetOutages(name), now),
+		})
+	}
+	sort.Slice(uptime, func(i, j int) bool {
+		return uptime[i].Server < uptime[j].Server
+	})
+
+	d.writeJSONResponse(w, uptime)
+}
@@ -4,7 +4,6 @@ go 1.24.0
 
 toolchain go1.24.13
 
-require (
-	golang.org/x/crypto v0.47.0 // indirect
-	golang.org/x/sys v0.40.0 // indirect
-)
+require golang.org/x/crypto v0.47.0
+
+require golang.org/x/sys v0.40.0 // indirect
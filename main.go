@@ -4,19 +4,25 @@ import (
 	"bandwidth-monitor/config"
 	"bandwidth-monitor/dashboard"
 	"bandwidth-monitor/monitor"
+	"bandwidth-monitor/notifier"
 	"bandwidth-monitor/sshclient"
 	"bufio"
 	"crypto/rand"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 const (
@@ -79,23 +85,54 @@ func main() {
 
 	switch command {
 	case "add":
-		addServerWizard()
+		if len(flag.Args()) > 1 {
+			runAddCommand(flag.Args()[1:])
+		} else {
+			addServerWizard()
+		}
 	case "update":
+		if hasFlag(flag.Args()[1:], "name") {
+			runUpdateCommand(flag.Args()[1:])
+			return
+		}
 		name := ""
-		if len(flag.Args()) > 1 {
-			name = flag.Args()[1]
+		repin := false
+		for _, arg := range flag.Args()[1:] {
+			if arg == "--repin" || arg == "-repin" {
+				repin = true
+			} else if name == "" {
+				name = arg
+			}
+		}
+		if repin {
+			repinServerHostKey(name)
+		} else {
+			updateServer(name)
 		}
-		updateServer(name)
 	case "list":
 		listServers()
 	case "remove":
+		if hasFlag(flag.Args()[1:], "name") {
+			runRemoveCommand(flag.Args()[1:])
+			return
+		}
 		name := ""
 		if len(flag.Args()) > 1 {
 			name = flag.Args()[1]
 		}
 		removeServer(name)
+	case "import":
+		runImportCommand(flag.Args()[1:])
+	case "export":
+		runExportCommand(flag.Args()[1:])
 	case "web":
 		startWebDashboard()
+	case "test-notify":
+		testNotifications()
+	case "user":
+		runUserCommand(flag.Args()[1:])
+	case "token":
+		runTokenCommand(flag.Args()[1:])
 	case "version", "-v", "--version":
 		fmt.Printf("Bandwidth Monitor v%s\n", version)
 	default:
@@ -113,76 +150,158 @@ func printUsage() {
 	fmt.Println("\nCommands:")
 	fmt.Println("  add              Add a new server (interactive wizard)")
 	fmt.Println("  update <name>    Update an existing server")
+	fmt.Println("  update <name> --repin")
+	fmt.Println("                   Re-pin the SSH host key of a reinstalled server")
 	fmt.Println("  list             List all configured servers")
 	fmt.Println("  remove <name>    Remove a server")
+	fmt.Println("  add --name <name> --ip <ip> [--user root] [--port 22]")
+	fmt.Println("      [--password-file <file>|--key <file>] [--interface eth0] [--no-install]")
+	fmt.Println("      [--collector vnstat|procnetdev|local|http] [--url <url>] [--group <group>] [--tags a,b] [--json]")
+	fmt.Println("                   Add a server without prompts")
+	fmt.Println("  update --name <name> [--new-name <name>] [--ip <ip>] [--user <user>] [--port <port>]")
+	fmt.Println("      [--password-file <file>|--key <file>|--setup] [--interface eth0,eth1]")
+	fmt.Println("      [--collector vnstat|procnetdev|local|http] [--url <url>] [--group <group>] [--tags a,b] [--json]")
+	fmt.Println("                   Update a server without prompts")
+	fmt.Println("  remove --name <name> --yes [--cleanup] [--json]")
+	fmt.Println("                   Remove a server without prompts")
+	fmt.Println("  import <file> [--format csv|yaml|ansible] [--password-file <file>|--key <file>]")
+	fmt.Println("      [--no-install] [--concurrency 5] [--dry-run] [--json]")
+	fmt.Println("                   Set up and add every server in a file")
+	fmt.Println("  export [file] [--format csv|yaml|ansible]")
+	fmt.Println("                   Write the server list to a file or stdout")
 	fmt.Println("  web              Start web dashboard (foreground)")
+	fmt.Println("  test-notify      Send a test alert to all notification channels")
+	fmt.Println("  user <action>    Manage dashboard users (list, add, passwd, role, remove)")
+	fmt.Println("  token <action>   Manage API tokens (list, create, revoke)")
 	fmt.Println("  version          Show version information")
 }
 
-func runServerSetup(ip string, port int, user string, password string) (string, error) {
+// setupOptions controls how runServerSetup prepares a server
+type setupOptions struct {
+	Password   string    // Log in with this password
+	KeyFile    string    // Or log in with this existing private key; the monitor's own key is used when both are empty
+	Interfaces []string  // Interfaces to monitor; detected when empty
+	NoInstall  bool      // Assume vnStat is already installed
+	Collector  string    // With procnetdev, vnStat is neither installed nor configured
+	Log        io.Writer // Progress output
+}
+
+// runServerSetup connects to a server, installs vnStat, authorizes the
+// monitor's SSH key and returns the interfaces to monitor
+func runServerSetup(ip string, port int, user string, opts setupOptions) ([]string, error) {
+	out := opts.Log
+	if out == nil {
+		out = os.Stdout
+	}
+
 	// Generate SSH key if needed
-	fmt.Println("Checking SSH keys...")
+	fmt.Fprintln(out, "Checking SSH keys...")
 	privateKey, publicKey, err := sshclient.GenerateSSHKey()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate SSH key: %v", err)
+		return nil, fmt.Errorf("failed to generate SSH key: %v", err)
 	}
-	fmt.Println("✓ SSH keys ready")
-	fmt.Println()
+	fmt.Fprintln(out, "✓ SSH keys ready")
+	fmt.Fprintln(out)
 
-	fmt.Println("Connecting to server...")
+	fmt.Fprintln(out, "Connecting to server...")
 
-	// Connect to server with password
-	client, err := sshclient.NewClient(ip, port, user, password)
+	// Connect with the password or the given key
+	client, err := dialSetup(ip, port, user, opts, []byte(privateKey))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to server: %v", err)
+		return nil, err
 	}
 	// We handle closing manually to allow key testing
 
-	fmt.Println("✓ Connected successfully")
-	fmt.Println()
+	fmt.Fprintln(out, "✓ Connected successfully")
+	fmt.Fprintln(out)
 
-	// Detect interface
-	fmt.Println("Detecting network interface...")
-	iface, err := client.DetectInterface()
-	if err != nil {
-		client.Close()
-		return "", fmt.Errorf("failed to detect network interface: %v", err)
+	interfaces := opts.Interfaces
+	if len(interfaces) == 0 {
+		// Detect interface
+		fmt.Fprintln(out, "Detecting network interface...")
+		iface, err := client.DetectInterface()
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to detect network interface: %v", err)
+		}
+		fmt.Fprintf(out, "✓ Detected interface: %s\n", iface)
+		fmt.Fprintln(out)
+		interfaces = []string{iface}
 	}
-	fmt.Printf("✓ Detected interface: %s\n", iface)
-	fmt.Println()
 
-	// Install vnStat
-	fmt.Println("Installing vnStat...")
-	if err := client.InstallVnStat(); err != nil {
-		client.Close()
-		return "", fmt.Errorf("failed to install vnStat: %v", err)
+	usesVnStat := opts.Collector != config.CollectorProcNetDev
+	if !usesVnStat {
+		fmt.Fprintln(out, "Using /proc/net/dev, vnStat is not needed")
+	} else if opts.NoInstall {
+		fmt.Fprintln(out, "Skipping vnStat installation")
+	} else {
+		// Install vnStat
+		fmt.Fprintln(out, "Installing vnStat...")
+		if err := client.InstallVnStat(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to install vnStat: %v", err)
+		}
+		fmt.Fprintln(out, "✓ vnStat installed successfully")
+	}
+	fmt.Fprintln(out)
+
+	// Interfaces given explicitly may not be tracked by vnStat yet
+	if len(opts.Interfaces) > 0 && usesVnStat {
+		for _, iface := range interfaces {
+			if err := client.AddVnStatInterface(iface); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+		fmt.Fprintf(out, "✓ vnStat tracks %s\n", strings.Join(interfaces, ", "))
+		fmt.Fprintln(out)
 	}
-	fmt.Println("✓ vnStat installed successfully")
-	fmt.Println()
 
 	// Copy SSH key
-	fmt.Println("Setting up SSH key authentication...")
+	fmt.Fprintln(out, "Setting up SSH key authentication...")
 	if err := client.CopySSHKey(publicKey); err != nil {
 		client.Close()
-		return "", fmt.Errorf("failed to copy SSH key: %v", err)
+		return nil, fmt.Errorf("failed to copy SSH key: %v", err)
 	}
-	fmt.Println("✓ SSH key copied successfully")
-	fmt.Println()
+	fmt.Fprintln(out, "✓ SSH key copied successfully")
+	fmt.Fprintln(out)
 
-	// Close password connection
+	// Close setup connection
 	client.Close()
 
 	// Test key-based connection
-	fmt.Println("Testing SSH key authentication...")
+	fmt.Fprintln(out, "Testing SSH key authentication...")
 	clientWithKey, err := sshclient.NewClientWithKey(ip, port, user, []byte(privateKey))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect with SSH key: %v", err)
+		return nil, fmt.Errorf("failed to connect with SSH key: %v", err)
 	}
 	clientWithKey.Close()
-	fmt.Println("✓ SSH key authentication working")
-	fmt.Println()
+	fmt.Fprintln(out, "✓ SSH key authentication working")
+	fmt.Fprintln(out)
 
-	return iface, nil
+	return interfaces, nil
+}
+
+// dialSetup logs in with the password or key of opts, or else with monitorKey
+func dialSetup(ip string, port int, user string, opts setupOptions, monitorKey []byte) (*sshclient.Client, error) {
+	var client *sshclient.Client
+	var err error
+	switch {
+	case opts.Password != "":
+		client, err = sshclient.NewClient(ip, port, user, opts.Password)
+	case opts.KeyFile != "":
+		key, readErr := os.ReadFile(opts.KeyFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read SSH key: %v", readErr)
+		}
+		client, err = sshclient.NewClientWithKey(ip, port, user, key)
+	default:
+		client, err = sshclient.NewClientWithKey(ip, port, user, monitorKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %v", err)
+	}
+	return client, nil
 }
 
 func selectServer() (string, error) {
@@ -284,7 +403,7 @@ func addServerWizard() {
 
 	fmt.Println()
 
-	iface, err := runServerSetup(ip, port, user, password)
+	interfaces, err := runServerSetup(ip, port, user, setupOptions{Password: password})
 	if err != nil {
 		fmt.Printf("Setup failed: %v\n", err)
 		return
@@ -299,11 +418,11 @@ func addServerWizard() {
 
 	// Add server to config
 	server := config.ServerConfig{
-		Name:      name,
-		IP:        ip,
-		User:      user,
-		Port:      port,
-		Interface: iface,
+		Name:       name,
+		IP:         ip,
+		User:       user,
+		Port:       port,
+		Interfaces: interfaces,
 	}
 
 	if err := cfg.AddServer(server); err != nil {
@@ -326,7 +445,7 @@ func addServerWizard() {
 	fmt.Printf("  IP:        %s\n", ip)
 	fmt.Printf("  User:      %s\n", user)
 	fmt.Printf("  Port:      %d\n", port)
-	fmt.Printf("  Interface: %s\n", iface)
+	fmt.Printf("  Interface: %s\n", strings.Join(interfaces, ","))
 	fmt.Println()
 	fmt.Println("You can now start monitoring with:")
 	fmt.Println("  ./bandwidth-monitor web")
@@ -360,7 +479,11 @@ func updateServer(name string) {
 	fmt.Println("1. Edit Name")
 	fmt.Println("2. Edit IP Address")
 	fmt.Println("3. Re-run SSH Setup")
-	fmt.Println("4. Cancel")
+	fmt.Println("4. Re-pin SSH Host Key")
+	fmt.Println("5. Set Monthly Quota")
+	fmt.Println("6. Edit Interfaces")
+	fmt.Println("7. Set Group and Tags")
+	fmt.Println("8. Cancel")
 	fmt.Println()
 
 	reader := bufio.NewReader(os.Stdin)
@@ -409,19 +532,65 @@ func updateServer(name string) {
 			return
 		}
 
-		iface, err := runServerSetup(server.IP, server.Port, server.User, password)
+		detected, err := runServerSetup(server.IP, server.Port, server.User, setupOptions{Password: password})
 		if err != nil {
 			fmt.Printf("Setup failed: %v\n", err)
 			return
 		}
 
-		server.Interface = iface
+		server.Interfaces = mergeInterfaces(detected[0], server.GetInterfaces())
+		server.Interface = ""
 		if err := cfg.UpdateServer(name, server); err != nil {
 			fmt.Printf("Failed to update server: %v\n", err)
 			return
 		}
 
 	case "4":
+		repinServerHostKey(name)
+		return
+
+	case "5":
+		quota, err := promptQuota(reader, server.Quota)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		server.Quota = quota
+		if err := cfg.UpdateServer(name, server); err != nil {
+			fmt.Printf("Failed to update server: %v\n", err)
+			return
+		}
+
+	case "6":
+		interfaces, err := promptInterfaces(reader, server)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		server.Interfaces = interfaces
+		server.Interface = ""
+		if err := cfg.UpdateServer(name, server); err != nil {
+			fmt.Printf("Failed to update server: %v\n", err)
+			return
+		}
+
+	case "7":
+		group, tags, err := promptLabels(reader, server)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		server.Group = group
+		server.Tags = tags
+		if err := cfg.UpdateServer(name, server); err != nil {
+			fmt.Printf("Failed to update server: %v\n", err)
+			return
+		}
+
+	case "8":
 		fmt.Println("Cancelled.")
 		return
 
@@ -438,6 +607,203 @@ func updateServer(name string) {
 	fmt.Println("✓ Server updated successfully")
 }
 
+// promptInterfaces asks for the list of interfaces to monitor and makes sure
+// vnStat tracks each of them on the server
+func promptInterfaces(reader *bufio.Reader, server config.ServerConfig) ([]string, error) {
+	privateKey, err := sshclient.LoadPrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SSH private key: %w", err)
+	}
+
+	client, err := sshclient.NewClientWithKey(server.IP, server.Port, server.User, []byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer client.Close()
+
+	if available, err := client.ListInterfaces(); err == nil {
+		fmt.Printf("Available interfaces: %s\n", strings.Join(available, ", "))
+	}
+	fmt.Printf("Current interfaces: %s\n", strings.Join(server.GetInterfaces(), ", "))
+
+	fmt.Print("Interfaces to monitor (comma-separated): ")
+	input, _ := reader.ReadString('\n')
+
+	interfaces, err := parseInterfaces(input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range interfaces {
+		if err := client.AddVnStatInterface(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return interfaces, nil
+}
+
+// parseInterfaces parses a comma-separated list of interface names
+func parseInterfaces(input string) ([]string, error) {
+	var interfaces []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(input, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if !config.ValidInterfaceName(name) {
+			return nil, fmt.Errorf("invalid interface name '%s'", name)
+		}
+		seen[name] = true
+		interfaces = append(interfaces, name)
+	}
+	if len(interfaces) == 0 {
+		return nil, fmt.Errorf("at least one interface is required")
+	}
+	return interfaces, nil
+}
+
+// mergeInterfaces makes primary the first interface and keeps the others
+func mergeInterfaces(primary string, existing []string) []string {
+	interfaces := []string{primary}
+	for _, iface := range existing {
+		if iface != primary {
+			interfaces = append(interfaces, iface)
+		}
+	}
+	return interfaces
+}
+
+// promptLabels asks for the group and tags of a server. An empty answer
+// keeps the current value and "-" clears it.
+func promptLabels(reader *bufio.Reader, server config.ServerConfig) (string, []string, error) {
+	group, tags := server.Group, server.Tags
+
+	fmt.Printf("Group [%s] (- to clear): ", group)
+	input, _ := reader.ReadString('\n')
+	switch input = strings.TrimSpace(input); input {
+	case "":
+	case "-":
+		group = ""
+	default:
+		if !config.ValidTag(input) {
+			return "", nil, fmt.Errorf("invalid group '%s' (use letters, digits and - _ . : = /)", input)
+		}
+		group = input
+	}
+
+	fmt.Printf("Tags, comma-separated [%s] (- to clear): ", strings.Join(tags, ","))
+	input, _ = reader.ReadString('\n')
+	switch input = strings.TrimSpace(input); input {
+	case "":
+	case "-":
+		tags = nil
+	default:
+		parsed, err := config.ParseTags(input)
+		if err != nil {
+			return "", nil, err
+		}
+		tags = parsed
+	}
+	return group, tags, nil
+}
+
+// promptQuota asks for monthly quota settings. An empty limit keeps the
+// current quota and "0" removes it.
+func promptQuota(reader *bufio.Reader, current *config.QuotaConfig) (*config.QuotaConfig, error) {
+	quota := config.QuotaConfig{ResetDay: 1, Direction: config.QuotaBoth}
+	if current != nil {
+		quota = *current
+		fmt.Printf("Current quota: %g GB, resets on day %d, counts %s\n", quota.LimitGB, quota.ResetDay, quota.Direction)
+	}
+
+	fmt.Print("Monthly limit in GB (0 to remove): ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "0" {
+		return nil, nil
+	}
+	if input != "" {
+		limit, err := strconv.ParseFloat(input, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit '%s'", input)
+		}
+		quota.LimitGB = limit
+	}
+
+	fmt.Printf("Billing cycle reset day (1-31) [%d]: ", quota.ResetDay)
+	input, _ = reader.ReadString('\n')
+	if input = strings.TrimSpace(input); input != "" {
+		day, err := strconv.Atoi(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reset day '%s'", input)
+		}
+		quota.ResetDay = day
+	}
+
+	fmt.Printf("Count rx, tx or both [%s]: ", quota.Direction)
+	input, _ = reader.ReadString('\n')
+	if input = strings.TrimSpace(input); input != "" {
+		quota.Direction = strings.ToLower(input)
+	}
+
+	if err := quota.Validate(); err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+func repinServerHostKey(name string) {
+	if name == "" {
+		var err error
+		name, err = selectServer()
+		if err != nil {
+			fmt.Printf("Selection failed/cancelled: %v\n", err)
+			return
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		return
+	}
+
+	server := cfg.GetServer(name)
+	if server == nil {
+		fmt.Printf("Server '%s' not found\n", name)
+		return
+	}
+
+	fmt.Printf("Fetching host key from %s (%s:%d)...\n", server.Name, server.IP, server.Port)
+	key, err := sshclient.ScanHostKey(server.IP, server.Port)
+	if err != nil {
+		fmt.Printf("Failed to fetch host key: %v\n", err)
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("Host key fingerprint: %s %s\n", key.Type(), ssh.FingerprintSHA256(key))
+	fmt.Println("Verify this fingerprint on the server (ssh-keygen -lf /etc/ssh/ssh_host_*_key.pub) before trusting it.")
+	fmt.Print("Trust this key? (y/n): ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("Cancelled.")
+		return
+	}
+
+	if err := sshclient.PinHostKey(server.IP, server.Port, key); err != nil {
+		fmt.Printf("Failed to pin host key: %v\n", err)
+		return
+	}
+
+	fmt.Println("✓ Host key pinned")
+}
+
 func listServers() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -457,16 +823,18 @@ func listServers() {
 
 	fmt.Println("=== Configured Servers ===")
 	fmt.Println()
-	fmt.Printf("%-20s %-15s %-10s %-12s %-15s\n", "Name", "IP", "Port", "User", "Interface")
-	fmt.Println("-------------------------------------------------------------------------")
+	fmt.Printf("%-20s %-15s %-10s %-12s %-15s %-12s %s\n", "Name", "IP", "Port", "User", "Interfaces", "Group", "Tags")
+	fmt.Println("--------------------------------------------------------------------------------------------------")
 
 	for _, server := range servers {
-		fmt.Printf("%-20s %-15s %-10d %-12s %-15s\n",
+		fmt.Printf("%-20s %-15s %-10d %-12s %-15s %-12s %s\n",
 			server.Name,
 			server.IP,
 			server.Port,
 			server.User,
-			server.Interface,
+			strings.Join(server.GetInterfaces(), ","),
+			server.Group,
+			strings.Join(server.Tags, ","),
 		)
 	}
 
@@ -564,6 +932,23 @@ func startWebDashboard() {
 		log.Fatalf("Failed to create monitor: %v", err)
 	}
 
+	// Deliver alert notifications. The notifier is replaced on config reload.
+	notify, err := notifier.New(cfg.GetNotifications())
+	if err != nil {
+		log.Fatalf("Invalid notification configuration: %v", err)
+	}
+	var notifyMu sync.Mutex
+	defer func() {
+		notifyMu.Lock()
+		notify.Close()
+		notifyMu.Unlock()
+	}()
+	mon.OnAlert(func(ev monitor.AlertEvent) {
+		notifyMu.Lock()
+		defer notifyMu.Unlock()
+		notify.Notify(ev)
+	})
+
 	// Start monitoring
 	mon.Start()
 	defer mon.Stop()
@@ -574,31 +959,55 @@ func startWebDashboard() {
 	if !settings.AuthEnabled {
 		fmt.Println("WARNING: HTTP Basic Auth disabled! The dashboard is accessible to everyone.")
 	} else {
-		if settings.AuthPass == "" {
-			// Generate random password
+		if len(cfg.GetUsers()) == 0 {
+			// Create an admin account with a random password
 			randomPass, err := generateRandomPassword(8)
 			if err != nil {
 				log.Fatalf("Failed to generate random password: %v", err)
 			}
 
-			settings.AuthPass = randomPass
-			// Save the generated password to config
-			cfg.UpdateSettings(settings)
+			adminUser := settings.AuthUser
+			if adminUser == "" {
+				adminUser = "admin"
+			}
+			if err := cfg.AddUser(adminUser, randomPass, config.RoleAdmin); err != nil {
+				log.Fatalf("Failed to create admin user: %v", err)
+			}
 			if err := cfg.Save(); err != nil {
 				log.Printf("Failed to save config with generated password: %v", err)
 			}
 
 			fmt.Printf("✓ HTTP Basic Auth enabled\n")
 			fmt.Println("========================================")
-			fmt.Printf("[SECURITY] Dashboard Password: %s\n", settings.AuthPass)
+			fmt.Printf("[SECURITY] Dashboard User: %s\n", adminUser)
+			fmt.Printf("[SECURITY] Dashboard Password: %s\n", randomPass)
 			fmt.Println("========================================")
 		} else {
-			fmt.Println("✓ HTTP Basic Auth enabled")
+			fmt.Printf("✓ HTTP Basic Auth enabled (%d users)\n", len(cfg.GetUsers()))
+		}
+	}
+
+	// Keep login sessions valid across restarts
+	if settings.AuthEnabled && settings.SessionSecret == "" {
+		secret, err := generateRandomPassword(48)
+		if err != nil {
+			log.Fatalf("Failed to generate session secret: %v", err)
+		}
+		settings.SessionSecret = secret
+		cfg.UpdateSettings(settings)
+		if err := cfg.Save(); err != nil {
+			log.Printf("Failed to save session secret: %v", err)
+		}
+	}
+
+	if settings.TLSEnabled {
+		if err := settings.ValidateTLS(); err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
 		}
 	}
 
 	// Create dashboard
-	dash := dashboard.NewDashboard(mon, settings.ListenPort, settings.AuthUser, settings.AuthPass, settings.AuthEnabled)
+	dash := dashboard.NewDashboard(mon, cfg)
 
 	// Start dashboard in a goroutine
 	go func() {
@@ -607,21 +1016,123 @@ func startWebDashboard() {
 		}
 	}()
 
-	fmt.Printf("✓ Dashboard started on http://localhost:%d\n", settings.ListenPort)
+	scheme := "http"
+	if settings.TLSEnabled {
+		scheme = "https"
+	}
+	fmt.Printf("✓ Dashboard started on %s://localhost:%d\n", scheme, settings.ListenPort)
 	fmt.Println()
 	fmt.Println("Press Ctrl+C to stop...")
 	fmt.Println()
 
+	// Reload the config on SIGHUP or when config.json changes
+	reload := func() {
+		if err := cfg.Reload(); err != nil {
+			log.Printf("Failed to reload config: %v", err)
+			return
+		}
+
+		mon.RefreshServers()
+
+		// Pick up renewed certificates
+		if err := dash.ReloadCertificates(); err != nil {
+			log.Printf("Keeping previous TLS certificate: %v", err)
+		}
+
+		if n, err := notifier.New(cfg.GetNotifications()); err != nil {
+			log.Printf("Keeping previous notification channels: %v", err)
+		} else {
+			notifyMu.Lock()
+			old := notify
+			notify = n
+			notifyMu.Unlock()
+			old.Close()
+		}
+
+		newSettings := cfg.GetSettings()
+		if newSettings.ListenPort != settings.ListenPort || newSettings.AuthEnabled != settings.AuthEnabled ||
+			newSettings.MetricsToken != settings.MetricsToken || newSettings.DashboardEnabled != settings.DashboardEnabled ||
+			newSettings.SessionSecret != settings.SessionSecret || newSettings.TLSEnabled != settings.TLSEnabled ||
+			newSettings.TLSCert != settings.TLSCert || newSettings.TLSKey != settings.TLSKey ||
+			newSettings.HTTPRedirectPort != settings.HTTPRedirectPort {
+			log.Println("Dashboard settings changed; restart the service to apply them")
+		}
+
+		log.Println("Configuration reloaded")
+	}
+
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	go config.Watch(config.DefaultWatchInterval, stopWatch, reload)
+
 	// Wait for interrupt signal
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range sigChan {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		log.Println("Received SIGHUP, reloading configuration")
+		reload()
+	}
 
 	fmt.Println("\nShutting down...")
 	mon.Stop()
 	fmt.Println("✓ Stopped")
 }
 
+// testNotifications sends a sample alert to every configured channel
+func testNotifications() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	channels := cfg.GetNotifications()
+	if len(channels) == 0 {
+		fmt.Println("No notification channels configured.")
+		return
+	}
+
+	notify, err := notifier.New(channels)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer notify.Close()
+
+	now := time.Now()
+	results := notify.Test(monitor.AlertEvent{
+		Alert: monitor.Alert{
+			Rule:      "test-notification",
+			Server:    "example",
+			Field:     "rx",
+			Operator:  ">",
+			Threshold: 0,
+			Value:     1,
+			Severity:  "info",
+			State:     monitor.AlertFiring,
+			Since:     now,
+			FiredAt:   now,
+		},
+		Time: now,
+	})
+
+	failed := false
+	for _, c := range channels {
+		if err := results[c.Name]; err != nil {
+			fmt.Printf("✗ %s (%s): %v\n", c.Name, c.Type, err)
+			failed = true
+		} else {
+			fmt.Printf("✓ %s (%s)\n", c.Name, c.Type)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
 func trimString(s string) string {
 	return s[:len(s)-1]
 }
@@ -44,7 +44,7 @@ func showMainMenu() {
 		}
 		fmt.Printf(" 5. Dashboard Status: [%s]\n", status)
 		fmt.Printf(" 6. Change Web Port (Current: %d)\n", settings.ListenPort)
-		fmt.Println(" 7. Security Settings (Change User/Pass)")
+		fmt.Println(" 7. Security Settings (Users, Tokens & Auth)")
 		fmt.Println()
 		fmt.Println("[ System Service Control ]")
 		fmt.Println(" 8. Install/Update Background Service (Systemd)")
@@ -144,9 +144,15 @@ func changeSecuritySettings(cfg *config.Config) {
 	reader := bufio.NewReader(os.Stdin)
 
 	fmt.Println()
-	fmt.Println("1. Change Username")
-	fmt.Println("2. Change Password")
-	fmt.Printf("3. Toggle Auth (Current: %v)\n", settings.AuthEnabled)
+	fmt.Printf("1. Manage Users (%d configured)\n", len(cfg.GetUsers()))
+	fmt.Printf("2. Toggle Auth (Current: %v)\n", settings.AuthEnabled)
+	metricsTokenStatus := "not set, uses dashboard auth"
+	if settings.MetricsToken != "" {
+		metricsTokenStatus = "set"
+	}
+	fmt.Printf("3. Set Prometheus /metrics Token (Current: %s)\n", metricsTokenStatus)
+	fmt.Printf("4. Toggle HTTPS (Current: %v)\n", settings.TLSEnabled)
+	fmt.Printf("5. Manage API Tokens (%d configured)\n", len(cfg.GetTokens()))
 	fmt.Print("Select option: ")
 
 	input, _ := reader.ReadString('\n')
@@ -154,16 +160,41 @@ func changeSecuritySettings(cfg *config.Config) {
 
 	switch input {
 	case "1":
-		fmt.Print("Enter new username: ")
-		user, _ := reader.ReadString('\n')
-		settings.AuthUser = strings.TrimSpace(user)
+		manageUsersMenu(cfg)
+		return
+	case "5":
+		manageTokensMenu(cfg)
+		return
 	case "2":
-		fmt.Print("Enter new password: ")
-		pass, _ := reader.ReadString('\n')
-		settings.AuthPass = strings.TrimSpace(pass)
-	case "3":
 		settings.AuthEnabled = !settings.AuthEnabled
 		fmt.Printf("Auth set to: %v\n", settings.AuthEnabled)
+	case "3":
+		fmt.Print("Enter token (empty = generate, '-' = remove): ")
+		token, _ := reader.ReadString('\n')
+		token = strings.TrimSpace(token)
+		switch token {
+		case "-":
+			settings.MetricsToken = ""
+			fmt.Println("Metrics token removed. /metrics now uses the dashboard auth.")
+		case "":
+			generated, err := generateRandomPassword(32)
+			if err != nil {
+				fmt.Printf("Error generating token: %v\n", err)
+				pressEnterToContinue()
+				return
+			}
+			settings.MetricsToken = generated
+			fmt.Printf("Metrics token: %s\n", generated)
+		default:
+			settings.MetricsToken = token
+		}
+	case "4":
+		settings.TLSEnabled = !settings.TLSEnabled
+		fmt.Printf("HTTPS set to: %v\n", settings.TLSEnabled)
+		if settings.TLSEnabled && settings.TLSCert == "" {
+			fmt.Printf("A self-signed certificate will be generated in %s on next start.\n", config.ConfigDir)
+		}
+		fmt.Println("Restart the service to apply.")
 	default:
 		fmt.Println("Invalid option")
 		pressEnterToContinue()
@@ -0,0 +1,426 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertState is the state of an alert instance
+type AlertState string
+
+const (
+	AlertInactive AlertState = "inactive"
+	AlertPending  AlertState = "pending"
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+// AllServers matches every configured server in an alert rule
+const AllServers = "*"
+
+// Alert is an alert instance for one rule and target (server or aggregate)
+type Alert struct {
+	Rule      string
+	Server    string // Empty for aggregate rules
+	Field     string
+	Operator  string
+	Threshold float64
+	Value     float64
+	Severity  string
+	State     AlertState
+	Since     time.Time // When the condition first became true
+	FiredAt   time.Time
+	AckedBy   string // User who acknowledged the alert, empty if unacknowledged
+	AckedAt   time.Time
+}
+
+// AlertEvent is emitted when an alert starts firing or resolves
+type AlertEvent struct {
+	Alert
+	Time time.Time
+}
+
+// alertRule is a validated config.AlertRule
+type alertRule struct {
+	config.AlertRule
+	field string
+	hold  time.Duration
+}
+
+// alertInstance tracks the state machine of one rule/target pair
+type alertInstance struct {
+	state   AlertState
+	since   time.Time
+	firedAt time.Time
+	value   float64
+	ackedBy string
+	ackedAt time.Time
+}
+
+// AlertEngine evaluates threshold rules against aggregate metrics and keeps
+// a pending/firing/resolved state machine per rule and target
+type AlertEngine struct {
+	mu        sync.Mutex
+	rules     []alertRule
+	instances map[string]*alertInstance
+}
+
+// serverFields maps normalized field names to per-server values
+var serverFields = map[string]func(sm *ServerMetrics) float64{
+	"rx":       func(sm *ServerMetrics) float64 { return float64(sm.Rx) },
+	"tx":       func(sm *ServerMetrics) float64 { return float64(sm.Tx) },
+	"totalrx":  func(sm *ServerMetrics) float64 { return float64(sm.TotalRx) },
+	"totaltx":  func(sm *ServerMetrics) float64 { return float64(sm.TotalTx) },
+	"avgrx12h": func(sm *ServerMetrics) float64 { return float64(sm.AvgRx12h) },
+	"avgtx12h": func(sm *ServerMetrics) float64 { return float64(sm.AvgTx12h) },
+	"avgrx24h": func(sm *ServerMetrics) float64 { return float64(sm.AvgRx24h) },
+	"avgtx24h": func(sm *ServerMetrics) float64 { return float64(sm.AvgTx24h) },
+	"peakrx":   func(sm *ServerMetrics) float64 { return float64(sm.PeakRx) },
+	"peaktx":   func(sm *ServerMetrics) float64 { return float64(sm.PeakTx) },
+	"online":   func(sm *ServerMetrics) float64 { return boolValue(sm.Online) },
+	"anomaly": func(sm *ServerMetrics) float64 {
+		for _, a := range sm.Anomalies {
+			if a.Active {
+				return 1
+			}
+		}
+		return 0
+	},
+	"failures": func(sm *ServerMetrics) float64 {
+		if sm.Reachability == nil {
+			return 0
+		}
+		return float64(sm.Reachability.Failures)
+	},
+	"flapping": func(sm *ServerMetrics) float64 {
+		return boolValue(sm.Reachability != nil && sm.Reachability.Flapping)
+	},
+	"uptime24h": func(sm *ServerMetrics) float64 {
+		return uptimeValue(sm, func(r *Reachability) float64 { return r.Uptime24h })
+	},
+	"uptime7d": func(sm *ServerMetrics) float64 {
+		return uptimeValue(sm, func(r *Reachability) float64 { return r.Uptime7d })
+	},
+	"uptime30d": func(sm *ServerMetrics) float64 {
+		return uptimeValue(sm, func(r *Reachability) float64 { return r.Uptime30d })
+	},
+	"quotapercent": func(sm *ServerMetrics) float64 {
+		if sm.Quota == nil {
+			return 0
+		}
+		return sm.Quota.Percent
+	},
+	"quotaprojectedpercent": func(sm *ServerMetrics) float64 {
+		if sm.Quota == nil {
+			return 0
+		}
+		return sm.Quota.ProjectedPercent
+	},
+}
+
+// uptimeValue returns an uptime percentage of a server, 100 until it was
+// polled
+func uptimeValue(sm *ServerMetrics, uptime func(r *Reachability) float64) float64 {
+	if sm.Reachability == nil {
+		return 100
+	}
+	return uptime(sm.Reachability)
+}
+
+// aggregateFields maps normalized field names to aggregate values. The
+// aggregate has no daily byte totals, so total_rx and total_tx are server
+// fields only.
+var aggregateFields = map[string]func(am *AggregateMetrics) float64{
+	"rx":             func(am *AggregateMetrics) float64 { return float64(am.TotalRx) },
+	"tx":             func(am *AggregateMetrics) float64 { return float64(am.TotalTx) },
+	"grandtotalavg":  func(am *AggregateMetrics) float64 { return float64(am.GrandTotalAvg) },
+	"grandtotalpeak": func(am *AggregateMetrics) float64 { return float64(am.GrandTotalPeak) },
+	"online": func(am *AggregateMetrics) float64 {
+		online := 0
+		for _, sm := range am.ServerMetrics {
+			if sm.Online {
+				online++
+			}
+		}
+		return float64(online)
+	},
+}
+
+// normalizeField lowercases a field name and strips underscores so that
+// "PeakRx", "peak_rx" and "peakrx" are equivalent
+func normalizeField(field string) string {
+	return strings.ToLower(strings.ReplaceAll(field, "_", ""))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// NewAlertEngine creates an alert engine for the given rules
+func NewAlertEngine(rules []config.AlertRule) (*AlertEngine, error) {
+	e := &AlertEngine{
+		instances: make(map[string]*alertInstance),
+	}
+	if err := e.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SetRules validates and replaces the rule set. State of rules that still
+// exist with the same name is kept.
+func (e *AlertEngine) SetRules(rules []config.AlertRule) error {
+	parsed := make([]alertRule, 0, len(rules))
+	names := make(map[string]bool)
+
+	for i, r := range rules {
+		if r.Name == "" {
+			return fmt.Errorf("alert rule #%d: name is required", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("alert rule '%s': duplicate name", r.Name)
+		}
+		names[r.Name] = true
+
+		field := normalizeField(r.Field)
+		if r.Server == "" {
+			if _, ok := aggregateFields[field]; !ok {
+				if _, ok := serverFields[field]; ok {
+					return fmt.Errorf("alert rule '%s': '%s' is a server field; the aggregate has rx and tx (summed current rates), grand_total_avg, grand_total_peak and online", r.Name, r.Field)
+				}
+				return fmt.Errorf("alert rule '%s': unknown aggregate field '%s'", r.Name, r.Field)
+			}
+		} else if _, ok := serverFields[field]; !ok {
+			return fmt.Errorf("alert rule '%s': unknown server field '%s'", r.Name, r.Field)
+		}
+
+		switch r.Operator {
+		case ">", ">=", "<", "<=", "==", "!=":
+		default:
+			return fmt.Errorf("alert rule '%s': unknown operator '%s'", r.Name, r.Operator)
+		}
+
+		var hold time.Duration
+		if r.For != "" {
+			d, err := time.ParseDuration(r.For)
+			if err != nil || d < 0 {
+				return fmt.Errorf("alert rule '%s': invalid duration '%s'", r.Name, r.For)
+			}
+			hold = d
+		}
+
+		if r.Hysteresis < 0 {
+			return fmt.Errorf("alert rule '%s': hysteresis must not be negative", r.Name)
+		}
+
+		parsed = append(parsed, alertRule{AlertRule: r, field: field, hold: hold})
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = parsed
+	for key := range e.instances {
+		if !names[strings.SplitN(key, "\x00", 2)[0]] {
+			delete(e.instances, key)
+		}
+	}
+
+	return nil
+}
+
+func instanceKey(rule, server string) string {
+	return rule + "\x00" + server
+}
+
+// conditionMet reports whether value violates the rule threshold. While the
+// alert is firing, the threshold is shifted by the hysteresis so that the
+// value has to clearly recover before the alert resolves.
+func conditionMet(r alertRule, value float64, firing bool) bool {
+	threshold := r.Threshold
+	if firing {
+		switch r.Operator {
+		case ">", ">=":
+			threshold -= r.Hysteresis
+		case "<", "<=":
+			threshold += r.Hysteresis
+		}
+	}
+
+	switch r.Operator {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// Evaluate runs all rules against the metrics and returns the alerts that
+// started firing or resolved
+func (e *AlertEngine) Evaluate(metrics *AggregateMetrics, now time.Time) []AlertEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []AlertEvent
+	seen := make(map[string]bool)
+
+	for _, r := range e.rules {
+		if r.Server == "" {
+			value := aggregateFields[r.field](metrics)
+			key := instanceKey(r.Name, "")
+			seen[key] = true
+			if ev := e.step(r, "", key, value, now); ev != nil {
+				events = append(events, *ev)
+			}
+			continue
+		}
+
+		names := make([]string, 0, len(metrics.ServerMetrics))
+		for name := range metrics.ServerMetrics {
+			if r.Server == AllServers || r.Server == name {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value := serverFields[r.field](metrics.ServerMetrics[name])
+			key := instanceKey(r.Name, name)
+			seen[key] = true
+			if ev := e.step(r, name, key, value, now); ev != nil {
+				events = append(events, *ev)
+			}
+		}
+	}
+
+	// Forget targets that disappeared (e.g. removed servers)
+	for key := range e.instances {
+		if !seen[key] {
+			delete(e.instances, key)
+		}
+	}
+
+	return events
+}
+
+// step advances the state machine of one rule/target pair
+func (e *AlertEngine) step(r alertRule, server, key string, value float64, now time.Time) *AlertEvent {
+	inst, ok := e.instances[key]
+	if !ok {
+		inst = &alertInstance{state: AlertInactive}
+		e.instances[key] = inst
+	}
+	inst.value = value
+
+	met := conditionMet(r, value, inst.state == AlertFiring)
+
+	switch inst.state {
+	case AlertInactive, AlertResolved:
+		if !met {
+			inst.state = AlertInactive
+			return nil
+		}
+		inst.state = AlertPending
+		inst.since = now
+		fallthrough
+
+	case AlertPending:
+		if !met {
+			inst.state = AlertInactive
+			return nil
+		}
+		if now.Sub(inst.since) < r.hold {
+			return nil
+		}
+		inst.state = AlertFiring
+		inst.firedAt = now
+		inst.ackedBy = ""
+		inst.ackedAt = time.Time{}
+		return &AlertEvent{Alert: e.alert(r, server, inst), Time: now}
+
+	case AlertFiring:
+		if met {
+			return nil
+		}
+		inst.state = AlertResolved
+		return &AlertEvent{Alert: e.alert(r, server, inst), Time: now}
+	}
+
+	return nil
+}
+
+func (e *AlertEngine) alert(r alertRule, server string, inst *alertInstance) Alert {
+	return Alert{
+		Rule:      r.Name,
+		Server:    server,
+		Field:     r.Field,
+		Operator:  r.Operator,
+		Threshold: r.Threshold,
+		Value:     inst.value,
+		Severity:  r.Severity,
+		State:     inst.state,
+		Since:     inst.since,
+		FiredAt:   inst.firedAt,
+		AckedBy:   inst.ackedBy,
+		AckedAt:   inst.ackedAt,
+	}
+}
+
+// Acknowledge marks the firing alert of a rule and target as acknowledged.
+// The acknowledgement is cleared when the alert fires again.
+func (e *AlertEngine) Acknowledge(rule, server, user string, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	inst, ok := e.instances[instanceKey(rule, server)]
+	if !ok || inst.state != AlertFiring {
+		return fmt.Errorf("alert '%s' is not firing", rule)
+	}
+	inst.ackedBy = user
+	inst.ackedAt = now
+	return nil
+}
+
+// Active returns all pending and firing alerts
+func (e *AlertEngine) Active() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []Alert
+	for _, r := range e.rules {
+		for key, inst := range e.instances {
+			parts := strings.SplitN(key, "\x00", 2)
+			if parts[0] != r.Name {
+				continue
+			}
+			if inst.state == AlertPending || inst.state == AlertFiring {
+				alerts = append(alerts, e.alert(r, parts[1], inst))
+			}
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule != alerts[j].Rule {
+			return alerts[i].Rule < alerts[j].Rule
+		}
+		return alerts[i].Server < alerts[j].Server
+	})
+
+	return alerts
+}
@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"testing"
+	"time"
+)
+
+func TestAlertEngineStateMachine(t *testing.T) {
+	engine, err := NewAlertEngine([]config.AlertRule{
+		{Name: "high-rx", Server: "*", Field: "Rx", Operator: ">", Threshold: 100, For: "5m", Hysteresis: 20},
+	})
+	if err != nil {
+		t.Fatalf("NewAlertEngine failed: %v", err)
+	}
+
+	base := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	metricsWith := func(rx uint64) *AggregateMetrics {
+		return &AggregateMetrics{
+			ServerMetrics: map[string]*ServerMetrics{
+				"server1": {Name: "server1", Online: true, Rx: rx},
+			},
+		}
+	}
+
+	steps := []struct {
+		offset time.Duration
+		rx     uint64
+		event  AlertState // Expected event, empty for none
+		active AlertState // Expected active state, empty for none
+	}{
+		{0, 50, "", ""},
+		{time.Minute, 150, "", AlertPending},             // Condition starts
+		{3 * time.Minute, 150, "", AlertPending},         // Not held for 5m yet
+		{6 * time.Minute, 150, AlertFiring, AlertFiring}, // Held for 5m
+		{7 * time.Minute, 90, "", AlertFiring},           // Below threshold but within hysteresis
+		{8 * time.Minute, 70, AlertResolved, ""},         // Clearly recovered
+		{9 * time.Minute, 150, "", AlertPending},         // Starts again
+		{10 * time.Minute, 50, "", ""},                   // Drops before firing
+	}
+
+	for i, s := range steps {
+		events := engine.Evaluate(metricsWith(s.rx), base.Add(s.offset))
+
+		if s.event == "" && len(events) != 0 {
+			t.Errorf("Step %d: expected no events, got %+v", i, events)
+		}
+		if s.event != "" && (len(events) != 1 || events[0].State != s.event || events[0].Server != "server1") {
+			t.Errorf("Step %d: expected %s event, got %+v", i, s.event, events)
+		}
+
+		active := engine.Active()
+		if s.active == "" && len(active) != 0 {
+			t.Errorf("Step %d: expected no active alerts, got %+v", i, active)
+		}
+		if s.active != "" && (len(active) != 1 || active[0].State != s.active) {
+			t.Errorf("Step %d: expected active %s alert, got %+v", i, s.active, active)
+		}
+	}
+}
+
+func TestAlertEngineAggregateAndOnline(t *testing.T) {
+	engine, err := NewAlertEngine([]config.AlertRule{
+		{Name: "server-down", Server: "server2", Field: "online", Operator: "==", Threshold: 0},
+		{Name: "fleet-tx", Field: "tx", Operator: ">=", Threshold: 1000},
+	})
+	if err != nil {
+		t.Fatalf("NewAlertEngine failed: %v", err)
+	}
+
+	metrics := &AggregateMetrics{
+		TotalTx: 1000,
+		ServerMetrics: map[string]*ServerMetrics{
+			"server1": {Name: "server1", Online: false},
+			"server2": {Name: "server2", Online: false},
+		},
+	}
+
+	events := engine.Evaluate(metrics, time.Now())
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 firing events, got %+v", events)
+	}
+	if events[0].Rule != "server-down" || events[0].Server != "server2" {
+		t.Errorf("Unexpected server event: %+v", events[0])
+	}
+	if events[1].Rule != "fleet-tx" || events[1].Server != "" {
+		t.Errorf("Unexpected aggregate event: %+v", events[1])
+	}
+}
+
+func TestAlertRuleValidation(t *testing.T) {
+	invalid := [][]config.AlertRule{
+		{{Field: "rx", Operator: ">"}},
+		{{Name: "a", Server: "*", Field: "bogus", Operator: ">"}},
+		{{Name: "a", Field: "peak_rx", Operator: ">"}},  // Not an aggregate field
+		{{Name: "a", Field: "total_rx", Operator: ">"}}, // Bytes today only exist per server
+		{{Name: "a", Server: "*", Field: "rx", Operator: "=>"}},
+		{{Name: "a", Server: "*", Field: "rx", Operator: ">", For: "soon"}},
+		{{Name: "a", Server: "*", Field: "rx", Operator: ">"}, {Name: "a", Server: "*", Field: "tx", Operator: ">"}},
+	}
+
+	for i, rules := range invalid {
+		if _, err := NewAlertEngine(rules); err == nil {
+			t.Errorf("Case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestAlertAcknowledge(t *testing.T) {
+	engine, err := NewAlertEngine([]config.AlertRule{
+		{Name: "high-rx", Server: "*", Field: "rx", Operator: ">", Threshold: 100},
+	})
+	if err != nil {
+		t.Fatalf("NewAlertEngine failed: %v", err)
+	}
+
+	now := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	metricsWith := func(rx uint64) *AggregateMetrics {
+		return &AggregateMetrics{ServerMetrics: map[string]*ServerMetrics{
+			"server1": {Name: "server1", Rx: rx},
+		}}
+	}
+
+	if err := engine.Acknowledge("high-rx", "server1", "alice", now); err == nil {
+		t.Errorf("Expected error acknowledging an inactive alert")
+	}
+
+	engine.Evaluate(metricsWith(150), now)
+	if err := engine.Acknowledge("high-rx", "server1", "alice", now); err != nil {
+		t.Fatalf("Acknowledge failed: %v", err)
+	}
+	if a := engine.Active(); len(a) != 1 || a[0].AckedBy != "alice" || !a[0].AckedAt.Equal(now) {
+		t.Errorf("Expected acknowledged alert, got %+v", a)
+	}
+
+	// Firing again clears the acknowledgement
+	engine.Evaluate(metricsWith(50), now.Add(time.Minute))
+	engine.Evaluate(metricsWith(150), now.Add(2*time.Minute))
+	if a := engine.Active(); len(a) != 1 || a[0].AckedBy != "" {
+		t.Errorf("Expected acknowledgement to be cleared, got %+v", a)
+	}
+}
@@ -0,0 +1,356 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/store"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// baselineRetention is how much hourly traffic the baseline learns from
+	baselineRetention = 8 * 7 * 24 * time.Hour
+
+	// baselineMinSamples is how many past hours a baseline needs before
+	// rates are compared against it
+	baselineMinSamples = 3
+
+	// baselineMinDeviation is the smallest standard deviation assumed, as a
+	// fraction of the mean, so that a few very steady weeks don't turn
+	// ordinary fluctuations into anomalies
+	baselineMinDeviation = 0.1
+
+	// anomalyRetention and maxAnomalies bound the anomalies kept per server
+	anomalyRetention = 7 * 24 * time.Hour
+	maxAnomalies     = 20
+)
+
+// Baseline scopes
+const (
+	BaselineHourOfWeek = "hour_of_week"
+	BaselineHourOfDay  = "hour_of_day" // Used until enough weeks are known
+)
+
+// Anomaly kinds
+const (
+	AnomalySpike = "spike"
+	AnomalyDrop  = "drop"
+)
+
+// Baseline is the traffic expected in an hour, learned from the same hour
+// in past weeks, in bytes per second
+type Baseline struct {
+	Scope    string
+	Samples  int // Past hours the baseline was computed from
+	Rx       uint64
+	Tx       uint64
+	StdDevRx uint64
+	StdDevTx uint64
+}
+
+// AnomalyEvent is a period in which a rate deviated from the baseline
+type AnomalyEvent struct {
+	Time     time.Time // When the deviation was first seen
+	LastSeen time.Time
+	Field    string  // rx or tx
+	Kind     string  // AnomalySpike or AnomalyDrop
+	Rate     uint64  // Most deviating rate seen, bytes per second
+	Expected uint64  // Baseline at that time, bytes per second
+	ZScore   float64 // Standard deviations from the baseline, negative for drops
+	Active   bool    // Still deviating at the latest poll
+}
+
+// anomalyDetector learns the hourly traffic of one server and tracks the
+// anomalies of its current rates
+type anomalyDetector struct {
+	mu     sync.Mutex
+	hours  map[int64]Rate           // Average rates of complete hours by start time
+	active map[string]*AnomalyEvent // Ongoing anomaly by field
+	events []*AnomalyEvent          // Newest first
+}
+
+func newAnomalyDetector() *anomalyDetector {
+	return &anomalyDetector{
+		hours:  make(map[int64]Rate),
+		active: make(map[string]*AnomalyEvent),
+	}
+}
+
+// hourOfWeek returns the slot of t in a week starting on Sunday
+func hourOfWeek(t time.Time) int {
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// learn adds the complete hours of vnStat hourly buckets and forgets hours
+// older than the retention
+func (d *anomalyDetector) learn(hour []TrafficBucket, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, b := range hour {
+		if time.Unix(b.Timestamp, 0).Add(time.Hour).After(now) {
+			continue // Still filling
+		}
+		d.hours[b.Timestamp] = Rate{Rx: b.Rx / 3600, Tx: b.Tx / 3600}
+	}
+
+	cutoff := now.Add(-baselineRetention).Unix()
+	for ts := range d.hours {
+		if ts < cutoff {
+			delete(d.hours, ts)
+		}
+	}
+}
+
+// seed adds hourly averages from the history store for hours vnStat no
+// longer reports
+func (d *anomalyDetector) seed(samples []store.Sample, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, s := range samples {
+		if time.Unix(s.Timestamp, 0).Add(time.Hour).After(now) {
+			continue
+		}
+		if _, ok := d.hours[s.Timestamp]; !ok {
+			d.hours[s.Timestamp] = Rate{Rx: s.Rx, Tx: s.Tx}
+		}
+	}
+}
+
+// baseline returns the expected traffic at the given time, or nil while
+// too few past hours are known. The same hour of the week is used once
+// enough weeks were seen, the same hour of the day until then.
+func (d *anomalyDetector) baseline(at time.Time) *Baseline {
+	local := at.Local()
+	week := hourOfWeek(local)
+
+	var weekly, daily []Rate
+	for ts, r := range d.hours {
+		t := time.Unix(ts, 0).Local()
+		if t.Hour() != local.Hour() {
+			continue
+		}
+		daily = append(daily, r)
+		if hourOfWeek(t) == week {
+			weekly = append(weekly, r)
+		}
+	}
+
+	switch {
+	case len(weekly) >= baselineMinSamples:
+		return newBaseline(BaselineHourOfWeek, weekly)
+	case len(daily) >= baselineMinSamples:
+		return newBaseline(BaselineHourOfDay, daily)
+	}
+	return nil
+}
+
+// newBaseline computes the mean and sample standard deviation of rates
+func newBaseline(scope string, rates []Rate) *Baseline {
+	var sumRx, sumTx float64
+	for _, r := range rates {
+		sumRx += float64(r.Rx)
+		sumTx += float64(r.Tx)
+	}
+	n := float64(len(rates))
+	meanRx, meanTx := sumRx/n, sumTx/n
+
+	var varRx, varTx float64
+	for _, r := range rates {
+		varRx += (float64(r.Rx) - meanRx) * (float64(r.Rx) - meanRx)
+		varTx += (float64(r.Tx) - meanTx) * (float64(r.Tx) - meanTx)
+	}
+	if n > 1 {
+		varRx /= n - 1
+		varTx /= n - 1
+	}
+
+	return &Baseline{
+		Scope:    scope,
+		Samples:  len(rates),
+		Rx:       uint64(math.Round(meanRx)),
+		Tx:       uint64(math.Round(meanTx)),
+		StdDevRx: uint64(math.Round(math.Sqrt(varRx))),
+		StdDevTx: uint64(math.Round(math.Sqrt(varTx))),
+	}
+}
+
+// deviation returns the kind and z-score of a rate compared with a
+// baseline mean and whether it crosses the thresholds
+func deviation(rate, mean, stddev uint64, t config.AnomalyThresholds) (string, float64, bool) {
+	diff := float64(rate) - float64(mean)
+	kind := AnomalySpike
+	if diff < 0 {
+		kind = AnomalyDrop
+	}
+	sd := max(float64(stddev), float64(mean)*baselineMinDeviation, 1)
+	z := diff / sd
+
+	if math.Abs(diff) < float64(t.MinDelta) {
+		return kind, z, false
+	}
+	if math.Abs(z) >= t.ZScore {
+		return kind, z, true
+	}
+	if t.Ratio > 0 && (float64(rate) >= float64(mean)*t.Ratio || float64(rate) <= float64(mean)/t.Ratio) {
+		return kind, z, true
+	}
+	return kind, z, false
+}
+
+// check compares current rates with the baseline of now and updates the
+// anomalies. It returns the baseline, nil while it is still being learned.
+func (d *anomalyDetector) check(rx, tx uint64, now time.Time, t config.AnomalyThresholds) *Baseline {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b := d.baseline(now)
+	if b == nil {
+		d.end("rx")
+		d.end("tx")
+	} else {
+		kind, z, flagged := deviation(rx, b.Rx, b.StdDevRx, t)
+		d.record("rx", kind, rx, b.Rx, z, flagged, now)
+		kind, z, flagged = deviation(tx, b.Tx, b.StdDevTx, t)
+		d.record("tx", kind, tx, b.Tx, z, flagged, now)
+	}
+
+	d.prune(now)
+	return b
+}
+
+// record starts, extends or ends the anomaly of a field. A deviation that
+// changes from a spike to a drop starts a new anomaly.
+func (d *anomalyDetector) record(field, kind string, rate, expected uint64, z float64, flagged bool, now time.Time) {
+	ev, ok := d.active[field]
+	if ok && (!flagged || ev.Kind != kind) {
+		d.end(field)
+		ok = false
+	}
+	if !flagged {
+		return
+	}
+
+	if !ok {
+		ev = &AnomalyEvent{Time: now, Field: field, Kind: kind, Active: true}
+		d.active[field] = ev
+		d.events = append([]*AnomalyEvent{ev}, d.events...)
+	}
+	ev.LastSeen = now
+	if math.Abs(z) >= math.Abs(ev.ZScore) {
+		ev.Rate, ev.Expected, ev.ZScore = rate, expected, z
+	}
+}
+
+// end marks the ongoing anomaly of a field as over
+func (d *anomalyDetector) end(field string) {
+	if ev, ok := d.active[field]; ok {
+		ev.Active = false
+		delete(d.active, field)
+	}
+}
+
+// prune drops anomalies that ended before the retention and the oldest ones
+// beyond maxAnomalies
+func (d *anomalyDetector) prune(now time.Time) {
+	cutoff := now.Add(-anomalyRetention)
+	kept := d.events[:0]
+	for _, ev := range d.events {
+		if ev.Active || !ev.LastSeen.Before(cutoff) {
+			kept = append(kept, ev)
+		}
+	}
+	for len(kept) > maxAnomalies {
+		if oldest := kept[len(kept)-1]; oldest.Active {
+			d.end(oldest.Field)
+		}
+		kept = kept[:len(kept)-1]
+	}
+	d.events = kept
+}
+
+// recent returns copies of the kept anomalies, newest first
+func (d *anomalyDetector) recent() []AnomalyEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	events := make([]AnomalyEvent, len(d.events))
+	for i, ev := range d.events {
+		events[i] = *ev
+	}
+	return events
+}
+
+// anomalyDetectorFor returns the anomaly detector of a server. A new one is
+// seeded with hourly averages from the history store, so that the baseline
+// survives restarts even though vnStat keeps only a few days of hours. It
+// returns nil for a server that was removed.
+func (m *Monitor) anomalyDetectorFor(name string) *anomalyDetector {
+	m.mu.Lock()
+	if _, ok := m.pollers[name]; !ok {
+		m.mu.Unlock()
+		return nil
+	}
+	if m.anomalies == nil {
+		m.anomalies = make(map[string]*anomalyDetector)
+	}
+	d, ok := m.anomalies[name]
+	if !ok {
+		d = newAnomalyDetector()
+		m.anomalies[name] = d
+	}
+	m.mu.Unlock()
+
+	if !ok && m.store != nil {
+		now := time.Now()
+		samples, err := m.store.Query(name, now.Add(-baselineRetention), now, time.Hour)
+		if err != nil {
+			log.Printf("Failed to load traffic baseline for %s: %v", name, err)
+			return d
+		}
+		d.seed(samples, now)
+	}
+	return d
+}
+
+// anomalyThresholds returns the configured anomaly thresholds
+func (m *Monitor) anomalyThresholds() config.AnomalyThresholds {
+	if m.config == nil {
+		return config.SettingsConfig{}.GetAnomalyThresholds()
+	}
+	return m.config.GetSettings().GetAnomalyThresholds()
+}
+
+// checkAnomalies learns the hourly traffic of a server and compares its
+// current rates with the baseline of this hour
+func (m *Monitor) checkAnomalies(name string, metrics *ServerMetrics) {
+	d := m.anomalyDetectorFor(name)
+	if d == nil {
+		return
+	}
+	if metrics.traffic != nil {
+		d.learn(metrics.traffic.Hour, metrics.UpdatedAt)
+	}
+	metrics.Baseline = d.check(metrics.Rx, metrics.Tx, metrics.UpdatedAt, m.anomalyThresholds())
+}
+
+// GetAnomalies returns the recent anomalies of every server, newest first
+func (m *Monitor) GetAnomalies() map[string][]AnomalyEvent {
+	m.mu.RLock()
+	detectors := make(map[string]*anomalyDetector, len(m.anomalies))
+	for name, d := range m.anomalies {
+		detectors[name] = d
+	}
+	m.mu.RUnlock()
+
+	anomalies := make(map[string][]AnomalyEvent, len(detectors))
+	for name, d := range detectors {
+		if events := d.recent(); len(events) > 0 {
+			anomalies[name] = events
+		}
+	}
+	return anomalies
+}
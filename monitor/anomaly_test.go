@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/store"
+	"testing"
+	"time"
+)
+
+// hourBucket returns a vnStat hourly bucket with the given average rates
+func hourBucket(start time.Time, rx, tx uint64) TrafficBucket {
+	return TrafficBucket{Timestamp: start.Unix(), Rx: rx * 3600, Tx: tx * 3600}
+}
+
+func TestBaseline(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	hour := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+
+	// Two earlier days at the same hour of the day
+	d := newAnomalyDetector()
+	d.learn([]TrafficBucket{
+		hourBucket(hour.AddDate(0, 0, -1), 1000, 100),
+		hourBucket(hour.AddDate(0, 0, -2), 1000, 100),
+		hourBucket(hour.Add(-time.Hour), 9999, 9999), // Another hour of the day
+		hourBucket(hour, 9999, 9999),                 // Still filling
+	}, now)
+	if b := d.baseline(now); b != nil {
+		t.Errorf("Expected no baseline from 2 samples, got %+v", b)
+	}
+
+	// A third day gives an hour-of-day baseline
+	d.learn([]TrafficBucket{hourBucket(hour.AddDate(0, 0, -3), 1000, 100)}, now)
+	if b := d.baseline(now); b == nil || b.Scope != BaselineHourOfDay || b.Rx != 1000 || b.Samples != 3 {
+		t.Errorf("Expected hour-of-day baseline of 1000, got %+v", b)
+	}
+
+	// Three weeks of the same hour of the week take precedence
+	d.learn([]TrafficBucket{
+		hourBucket(hour.AddDate(0, 0, -7), 10_000_000, 100),
+		hourBucket(hour.AddDate(0, 0, -14), 12_000_000, 100),
+		hourBucket(hour.AddDate(0, 0, -21), 8_000_000, 100),
+	}, now)
+	b := d.baseline(now)
+	if b == nil || b.Scope != BaselineHourOfWeek || b.Samples != 3 {
+		t.Fatalf("Expected hour-of-week baseline from 3 weeks, got %+v", b)
+	}
+	if b.Rx != 10_000_000 || b.StdDevRx != 2_000_000 || b.Tx != 100 || b.StdDevTx != 0 {
+		t.Errorf("Baseline mismatch: %+v", b)
+	}
+
+	// Hours older than the retention are forgotten
+	d.learn(nil, now.Add(baselineRetention))
+	if len(d.hours) != 0 {
+		t.Errorf("Expected old hours to be forgotten, %d left", len(d.hours))
+	}
+}
+
+func TestAnomalyDetection(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	hour := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	d := newAnomalyDetector()
+	d.learn([]TrafficBucket{
+		hourBucket(hour.AddDate(0, 0, -7), 10_000_000, 500_000),
+		hourBucket(hour.AddDate(0, 0, -14), 12_000_000, 500_000),
+		hourBucket(hour.AddDate(0, 0, -21), 8_000_000, 500_000),
+	}, now)
+	thresholds := config.SettingsConfig{}.GetAnomalyThresholds()
+
+	steps := []struct {
+		rx     uint64
+		events int
+		active bool
+	}{
+		{11_000_000, 0, false}, // Within normal variation
+		{20_000_000, 1, true},  // 5 standard deviations above
+		{25_000_000, 1, true},  // Same spike, higher
+		{10_000_000, 1, false}, // Back to normal
+		{0, 2, true},           // Traffic collapsed
+	}
+	for i, s := range steps {
+		d.check(s.rx, 500_000, now.Add(time.Duration(i)*time.Minute), thresholds)
+		events := d.recent()
+		if len(events) != s.events {
+			t.Fatalf("Step %d: got %d anomalies, want %d", i, len(events), s.events)
+		}
+		if len(events) > 0 && events[0].Active != s.active {
+			t.Errorf("Step %d: active mismatch. Got %v, want %v", i, events[0].Active, s.active)
+		}
+	}
+
+	events := d.recent()
+	drop, spike := events[0], events[1]
+	if drop.Kind != AnomalyDrop || drop.Field != "rx" || drop.ZScore >= 0 {
+		t.Errorf("Unexpected drop: %+v", drop)
+	}
+	if spike.Kind != AnomalySpike || spike.Rate != 25_000_000 || spike.Expected != 10_000_000 || spike.ZScore != 7.5 {
+		t.Errorf("Unexpected spike: %+v", spike)
+	}
+	if !spike.Time.Equal(now.Add(time.Minute)) || !spike.LastSeen.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("Spike period mismatch: %s - %s", spike.Time, spike.LastSeen)
+	}
+
+	// Ended anomalies are dropped after the retention
+	d.check(10_000_000, 500_000, now.Add(anomalyRetention+time.Hour), thresholds)
+	if events := d.recent(); len(events) != 0 {
+		t.Errorf("Expected old anomalies to be dropped, got %d", len(events))
+	}
+}
+
+func TestAnomalyThresholds(t *testing.T) {
+	thresholds := config.AnomalyThresholds{ZScore: 100, Ratio: 1.5, MinDelta: 125000}
+
+	// The ratio flags what the z-score doesn't
+	if kind, _, flagged := deviation(16_000_000, 10_000_000, 2_000_000, thresholds); !flagged || kind != AnomalySpike {
+		t.Errorf("Expected rate 1.6 times the baseline to be a spike, got %s %v", kind, flagged)
+	}
+	if kind, _, flagged := deviation(6_000_000, 10_000_000, 2_000_000, thresholds); !flagged || kind != AnomalyDrop {
+		t.Errorf("Expected rate 0.6 times the baseline to be a drop, got %s %v", kind, flagged)
+	}
+	if _, _, flagged := deviation(14_000_000, 10_000_000, 2_000_000, thresholds); flagged {
+		t.Error("Rate 1.4 times the baseline should not be flagged")
+	}
+
+	// Small absolute deviations of idle servers are ignored
+	if _, _, flagged := deviation(50_000, 1000, 0, thresholds); flagged {
+		t.Error("Deviation below the minimum should not be flagged")
+	}
+
+	// A steady baseline still allows 10% of variation per standard deviation
+	if _, z, _ := deviation(12_000_000, 10_000_000, 0, thresholds); z != 2 {
+		t.Errorf("Z-score against a steady baseline mismatch. Got %g, want 2", z)
+	}
+}
+
+func TestAnomalyBaselineFromStore(t *testing.T) {
+	historyStore, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	// The same hour in the last three weeks, older than vnStat keeps hours
+	now := time.Now()
+	hour := now.Truncate(time.Hour)
+	for week := 3; week >= 1; week-- {
+		at := hour.AddDate(0, 0, -7*week).Add(10 * time.Minute)
+		if err := historyStore.Append("web", store.Sample{Timestamp: at.Unix(), Rx: 1_000_000, Tx: 200_000}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	m := &Monitor{
+		store:     historyStore,
+		pollers:   map[string]*poller{"web": {}},
+		metrics:   &AggregateMetrics{ServerMetrics: make(map[string]*ServerMetrics)},
+		pollStats: make(map[string]*PollStats),
+	}
+
+	metrics := &ServerMetrics{Name: "web", Online: true, Rx: 50_000_000, Tx: 200_000, UpdatedAt: now}
+	m.checkAnomalies("web", metrics)
+	m.setServerMetrics("web", metrics)
+
+	if b := metrics.Baseline; b == nil || b.Rx != 1_000_000 || b.Samples != 3 {
+		t.Fatalf("Expected baseline loaded from the store, got %+v", b)
+	}
+	sm := m.GetServerMetrics("web")
+	if len(sm.Anomalies) != 1 || !sm.Anomalies[0].Active || sm.Anomalies[0].Field != "rx" {
+		t.Errorf("Expected an active rx anomaly in server metrics, got %+v", sm.Anomalies)
+	}
+	if got := m.GetAnomalies()["web"]; len(got) != 1 {
+		t.Errorf("GetAnomalies returned %d anomalies, want 1", len(got))
+	}
+	if v := serverFields["anomaly"](sm); v != 1 {
+		t.Errorf("Alert field anomaly mismatch. Got %g, want 1", v)
+	}
+}
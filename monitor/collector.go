@@ -0,0 +1,268 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// httpCollectorTimeout bounds a single request of the http collector
+	httpCollectorTimeout = 15 * time.Second
+
+	// maxHTTPResponseSize caps the vnStat JSON read from an endpoint
+	maxHTTPResponseSize = 64 << 20
+)
+
+// Collector reads the traffic of a server. Implementations are registered
+// by name with RegisterCollector and picked per server by its collector
+// setting.
+type Collector interface {
+	Collect(server config.ServerConfig) (*Traffic, error)
+}
+
+// Traffic is what a collector read from a server
+type Traffic struct {
+	Data     *VnStatData    // Buckets in vnStat's format
+	Counters *CounterSample // Interface counters for current rates, nil to use the latest five-minute bucket
+}
+
+// Rate is the current speed of an interface in bytes per second
+type Rate struct {
+	Rx uint64
+	Tx uint64
+}
+
+// CollectorFactory creates a collector for a monitor. Collectors that log
+// in over SSH share the monitor's connection pool.
+type CollectorFactory func(pool *sshclient.Pool) Collector
+
+// serverForgetter is implemented by collectors that keep state per server,
+// which is dropped when the server is removed or its host changes
+type serverForgetter interface {
+	Forget(server string)
+}
+
+var (
+	collectorsMu       sync.RWMutex
+	collectorFactories = make(map[string]CollectorFactory)
+)
+
+// RegisterCollector makes a collector available under a name. Registering
+// a name again replaces the earlier factory for monitors created afterwards.
+func RegisterCollector(name string, factory CollectorFactory) {
+	collectorsMu.Lock()
+	defer collectorsMu.Unlock()
+	collectorFactories[name] = factory
+}
+
+// Collectors returns the names of the registered collectors
+func Collectors() []string {
+	collectorsMu.RLock()
+	defer collectorsMu.RUnlock()
+
+	names := make([]string, 0, len(collectorFactories))
+	for name := range collectorFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterCollector(config.CollectorVnStat, func(pool *sshclient.Pool) Collector {
+		return &vnStatCollector{pool: pool}
+	})
+	RegisterCollector(config.CollectorProcNetDev, func(pool *sshclient.Pool) Collector {
+		return newNetDevCollector(sshNetDevReader(pool))
+	})
+	RegisterCollector(config.CollectorLocal, func(*sshclient.Pool) Collector {
+		return newNetDevCollector(readLocalNetDev)
+	})
+	RegisterCollector(config.CollectorHTTP, func(*sshclient.Pool) Collector {
+		return &httpCollector{client: &http.Client{Timeout: httpCollectorTimeout}}
+	})
+}
+
+// newCollectors creates one instance of every registered collector
+func newCollectors(pool *sshclient.Pool) map[string]Collector {
+	collectorsMu.RLock()
+	defer collectorsMu.RUnlock()
+
+	collectors := make(map[string]Collector, len(collectorFactories))
+	for name, factory := range collectorFactories {
+		collectors[name] = factory(pool)
+	}
+	return collectors
+}
+
+// SetCollector replaces the collector used by servers with the given
+// collector name, e.g. to poll a fake in tests
+func (m *Monitor) SetCollector(name string, c Collector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.collectors == nil {
+		m.collectors = make(map[string]Collector)
+	}
+	m.collectors[name] = c
+}
+
+// collectorFor returns the collector of a server
+func (m *Monitor) collectorFor(server config.ServerConfig) (Collector, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.collectors[server.GetCollector()]
+	if !ok {
+		return nil, fmt.Errorf("unknown collector '%s'", server.GetCollector())
+	}
+	return c, nil
+}
+
+// forgetServer drops the state every collector keeps for a server that was
+// removed or moved to another host or collector. The caller must hold m.mu.
+func (m *Monitor) forgetServer(name string) {
+	for _, c := range m.collectors {
+		if f, ok := c.(serverForgetter); ok {
+			f.Forget(name)
+		}
+	}
+}
+
+// connectionError marks a collector error caused by not reaching the server
+type connectionError struct {
+	err error
+}
+
+func (e *connectionError) Error() string { return e.err.Error() }
+func (e *connectionError) Unwrap() error { return e.err }
+
+// Unreachable wraps an error of a collector that could not reach the
+// server, so that the poll counts as a connection failure
+func Unreachable(err error) error {
+	return &connectionError{err: err}
+}
+
+// isUnreachable reports whether err was wrapped by Unreachable
+func isUnreachable(err error) bool {
+	var ce *connectionError
+	return errors.As(err, &ce)
+}
+
+// processTraffic turns collected traffic into server metrics. Rates from
+// the deltas of consecutive counter readings replace the five-minute rate
+// for every interface that has one.
+func (m *Monitor) processTraffic(server config.ServerConfig, traffic *Traffic) *ServerMetrics {
+	metrics := m.processVnStatData(server, traffic.Data)
+	if traffic.Counters == nil || len(metrics.Interfaces) == 0 {
+		return metrics
+	}
+
+	tracker := m.rateTrackerFor(server.Name)
+	if tracker == nil {
+		return metrics
+	}
+	rates := tracker.update(traffic.Counters)
+	metrics.Rx, metrics.Tx = 0, 0
+	for i := range metrics.Interfaces {
+		im := &metrics.Interfaces[i]
+		if rate, ok := rates[im.Name]; ok {
+			im.Rx, im.Tx = rate.Rx, rate.Tx
+		}
+		metrics.Rx += im.Rx
+		metrics.Tx += im.Tx
+	}
+	return metrics
+}
+
+// vnStatCollector runs vnStat on the server over a pooled SSH connection
+type vnStatCollector struct {
+	pool *sshclient.Pool
+}
+
+func (c *vnStatCollector) Collect(server config.ServerConfig) (*Traffic, error) {
+	// Reuse the pooled connection (dials on first use or after a failure)
+	client, err := c.pool.Get(server.IP, server.Port, server.User)
+	if err != nil {
+		return nil, Unreachable(err)
+	}
+
+	jsonData, err := client.GetVnStatData(server.GetInterfaces()...)
+	if err != nil {
+		// The connection may be broken; drop it so the next tick reconnects
+		c.pool.Invalidate(server.IP, server.Port, server.User)
+		return nil, Unreachable(err)
+	}
+
+	var data VnStatData
+	if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+		return nil, fmt.Errorf("failed to parse vnStat data: %w", err)
+	}
+
+	// vnStat saves its database only every few minutes, so current rates
+	// come from the kernel's counters. Without them the five-minute rate
+	// is used for this poll.
+	traffic := &Traffic{Data: &data}
+	if output, err := client.ReadNetDev(); err == nil {
+		if sample, err := parseNetDev(output); err == nil {
+			traffic.Counters = sample.counterSample(time.Now())
+		}
+	}
+	return traffic, nil
+}
+
+// httpCollector fetches vnStat JSON from the server's URL, e.g. the output
+// of "vnstat --json" served by a web server or an agent
+type httpCollector struct {
+	client *http.Client
+}
+
+func (c *httpCollector) Collect(server config.ServerConfig) (*Traffic, error) {
+	if err := server.ValidateSource(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, Unreachable(fmt.Errorf("failed to fetch vnStat data: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch vnStat data: %s", resp.Status)
+	}
+
+	var data VnStatData
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseSize)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse vnStat data: %w", err)
+	}
+	return &Traffic{Data: &data, Counters: data.totalCounters()}, nil
+}
+
+// totalCounters returns vnStat's all-time totals as counters read when the
+// database was last updated, so that rates average over vnStat's save
+// interval instead of a partly filled bucket
+func (v *VnStatData) totalCounters() *CounterSample {
+	if len(v.Interfaces) == 0 {
+		return nil
+	}
+	sample := &CounterSample{At: v.GetUpdatedTime(), Counters: make(map[string]ByteCounters, len(v.Interfaces))}
+	for _, iface := range v.Interfaces {
+		sample.Counters[iface.Name] = ByteCounters{Rx: iface.Traffic.Total.Rx, Tx: iface.Traffic.Total.Tx}
+	}
+	return sample
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCollector returns canned traffic without logging in anywhere
+type fakeCollector struct {
+	traffic *Traffic
+	err     error
+	calls   int
+}
+
+func (f *fakeCollector) Collect(config.ServerConfig) (*Traffic, error) {
+	f.calls++
+	return f.traffic, f.err
+}
+
+// fakeTraffic returns one interface with the given counters
+func fakeTraffic(t *testing.T, name string, at time.Time, rx, tx uint64) *Traffic {
+	var data VnStatData
+	if err := json.Unmarshal([]byte(`{"interfaces":[{"name":"`+name+`"}]}`), &data); err != nil {
+		t.Fatalf("Failed to build vnStat data: %v", err)
+	}
+	counters := &CounterSample{At: at, Counters: map[string]ByteCounters{name: {Rx: rx, Tx: tx}}}
+	return &Traffic{Data: &data, Counters: counters}
+}
+
+func TestCollectorSelection(t *testing.T) {
+	m := &Monitor{
+		pollers:   map[string]*poller{"web": {}},
+		metrics:   &AggregateMetrics{ServerMetrics: make(map[string]*ServerMetrics)},
+		pollStats: make(map[string]*PollStats),
+	}
+	start := time.Now()
+	fake := &fakeCollector{traffic: fakeTraffic(t, "eth0", start, 5000, 5000)}
+	m.SetCollector("fake", fake)
+	server := config.ServerConfig{Name: "web", Collector: "fake"}
+
+	m.collectMetrics(server)
+	fake.traffic = fakeTraffic(t, "eth0", start.Add(10*time.Second), 6000, 7000)
+	m.collectMetrics(server)
+	metrics := m.GetServerMetrics("web")
+	if fake.calls != 2 {
+		t.Fatalf("Fake collector called %d times, want 2", fake.calls)
+	}
+	if !metrics.Online || metrics.Rx != 100 || metrics.Tx != 200 {
+		t.Errorf("Unexpected metrics: online=%v rx=%d tx=%d", metrics.Online, metrics.Rx, metrics.Tx)
+	}
+
+	// Only unreachable servers count as connection failures
+	fake.err = Unreachable(errors.New("connection refused"))
+	m.collectMetrics(server)
+	fake.err = errors.New("bad data")
+	m.collectMetrics(server)
+	stats := m.GetPollStats()["web"]
+	if stats.Polls != 4 || stats.SSHErrors != 1 {
+		t.Errorf("Poll stats mismatch. Got %d polls and %d connection errors, want 4 and 1", stats.Polls, stats.SSHErrors)
+	}
+	// Below the failure threshold the last metrics are kept with the error
+	if metrics := m.GetServerMetrics("web"); !metrics.Online || metrics.Error != "bad data" || metrics.Rx != 100 {
+		t.Errorf("Expected failing server with last metrics, got online=%v error=%q rx=%d", metrics.Online, metrics.Error, metrics.Rx)
+	}
+
+	// A server naming a collector that is not registered. Neither error
+	// counts toward reachability, only the connection failure does.
+	server.Collector = "snmp"
+	m.collectMetrics(server)
+	m.collectMetrics(server)
+	metrics = m.GetServerMetrics("web")
+	if !metrics.Online || !strings.Contains(metrics.Error, "unknown collector") || metrics.Rx != 100 {
+		t.Errorf("Expected failing server with unknown collector error, got online=%v error=%q rx=%d", metrics.Online, metrics.Error, metrics.Rx)
+	}
+	if r := metrics.Reachability; r == nil || r.State != ReachabilityUp || r.Failures != 1 {
+		t.Errorf("Expected 1 failure with the server up, got %+v", r)
+	}
+}
+
+func TestBuiltinCollectorsRegistered(t *testing.T) {
+	collectors := newCollectors(nil)
+	for _, name := range []string{config.CollectorVnStat, config.CollectorProcNetDev, config.CollectorLocal, config.CollectorHTTP} {
+		if _, ok := collectors[name]; !ok {
+			t.Errorf("Collector %s not registered", name)
+		}
+	}
+}
+
+func TestHTTPCollector(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"vnstatversion":"2.12","jsonversion":"2","interfaces":[{"name":"eth0","updated":{"timestamp":1770387600},"traffic":{"total":{"rx":10,"tx":20}}}]}`))
+	}))
+	c := &httpCollector{client: srv.Client()}
+	server := config.ServerConfig{Name: "web", Collector: config.CollectorHTTP, URL: srv.URL + "/vnstat.json"}
+
+	traffic, err := c.Collect(server)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(traffic.Data.Interfaces) != 1 || traffic.Data.Interfaces[0].Traffic.Total.Rx != 10 {
+		t.Errorf("Unexpected vnStat data: %+v", traffic.Data)
+	}
+	if c := traffic.Counters; c == nil || c.Counters["eth0"].Tx != 20 || c.At.Unix() != 1770387600 {
+		t.Errorf("Expected vnStat totals as counters at the update time, got %+v", c)
+	}
+
+	status = http.StatusInternalServerError
+	if _, err := c.Collect(server); err == nil || isUnreachable(err) {
+		t.Errorf("Expected a non-connection error for status 500, got %v", err)
+	}
+
+	srv.Close()
+	if _, err := c.Collect(server); !isUnreachable(err) {
+		t.Errorf("Expected unreachable error after the server closed, got %v", err)
+	}
+
+	server.URL = ""
+	if _, err := c.Collect(server); err == nil {
+		t.Error("Expected error without a URL")
+	}
+}
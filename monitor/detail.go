@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Limits of the per-server detail views
+const (
+	detailHours  = 24
+	detailDays   = 30
+	detailMonths = 12
+	detailTop    = 10
+)
+
+// ServerDetail is everything known about a single server, for its detail page
+type ServerDetail struct {
+	Metrics       *ServerMetrics
+	VnStatVersion string
+	Interfaces    []InterfaceInfo
+	Hours         []TrafficPoint // Last 24 hours
+	Days          []TrafficPoint // Last 30 days
+	Months        []TrafficPoint // Last 12 months
+	Top           []TrafficPoint // Busiest days, highest first
+	LastError     string
+	LastErrorAt   time.Time
+	LastSuccessAt time.Time
+}
+
+// GetServerDetail returns the cached vnStat history and status of a server.
+// No SSH calls are made; before the first successful poll only the metrics
+// and error fields are filled in.
+func (m *Monitor) GetServerDetail(name string) (*ServerDetail, error) {
+	return m.serverDetail(name, time.Now())
+}
+
+func (m *Monitor) serverDetail(name string, now time.Time) (*ServerDetail, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.pollers[name]; !ok {
+		return nil, fmt.Errorf("server '%s' not found", name)
+	}
+
+	detail := &ServerDetail{
+		Hours:  []TrafficPoint{},
+		Days:   []TrafficPoint{},
+		Months: []TrafficPoint{},
+		Top:    []TrafficPoint{},
+	}
+	if sm, ok := m.metrics.ServerMetrics[name]; ok {
+		smCopy := *sm
+		detail.Metrics = &smCopy
+	}
+	if stats, ok := m.pollStats[name]; ok {
+		detail.LastError = stats.LastError
+		detail.LastErrorAt = stats.LastErrorAt
+		detail.LastSuccessAt = stats.LastSuccessAt
+	}
+
+	traffic, ok := m.traffic[name]
+	if !ok {
+		return detail, nil
+	}
+
+	detail.VnStatVersion = traffic.VnStatVersion
+	detail.Interfaces = append([]InterfaceInfo(nil), traffic.Interfaces...)
+
+	detail.Hours = recentPoints(traffic.Hour, hourResolution, now.Add(-detailHours*time.Hour))
+	detail.Days = recentPoints(traffic.Day, dayResolution, now.AddDate(0, 0, -detailDays))
+	detail.Months = recentPoints(traffic.Month, monthResolution, now.AddDate(0, -detailMonths, 0))
+
+	top := make([]TrafficBucket, len(traffic.Top))
+	copy(top, traffic.Top)
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Rx+top[i].Tx > top[j].Rx+top[j].Tx
+	})
+	if len(top) > detailTop {
+		top = top[:detailTop]
+	}
+	for _, b := range top {
+		detail.Top = append(detail.Top, TrafficPoint{
+			Time:     time.Unix(b.Timestamp, 0),
+			Duration: dayResolution,
+			Rx:       b.Rx,
+			Tx:       b.Tx,
+		})
+	}
+
+	return detail, nil
+}
+
+// recentPoints converts the buckets that end after since into points, oldest first
+func recentPoints(buckets []TrafficBucket, resolution time.Duration, since time.Time) []TrafficPoint {
+	points := []TrafficPoint{}
+	for _, b := range sortedBuckets(buckets) {
+		d := bucketDuration(b, resolution)
+		t := time.Unix(b.Timestamp, 0)
+		if !t.Add(d).After(since) {
+			continue
+		}
+		points = append(points, TrafficPoint{Time: t, Duration: d, Rx: b.Rx, Tx: b.Tx})
+	}
+	return points
+}
@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerDetail(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	top := bucketsEvery(now.AddDate(0, 0, -20), 24*time.Hour, 12, 100, 100)
+	top[3].Rx = 9000
+	top[7].Tx = 5000
+
+	m := &Monitor{
+		pollers: map[string]*poller{"web": {}, "new": {}},
+		metrics: &AggregateMetrics{ServerMetrics: map[string]*ServerMetrics{
+			"web": {Name: "web", Online: true},
+		}},
+		pollStats: map[string]*PollStats{
+			"web": {LastError: "ssh: timeout", LastErrorAt: now.Add(-time.Hour), LastSuccessAt: now},
+		},
+		traffic: map[string]*serverTraffic{"web": {
+			Hour:          bucketsEvery(now.Add(-48*time.Hour), time.Hour, 48, 10, 20),
+			Day:           bucketsEvery(now.AddDate(0, 0, -60), 24*time.Hour, 60, 10, 20),
+			Month:         bucketsEvery(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0, 1, 10, 20),
+			Top:           top,
+			VnStatVersion: "2.12",
+			Interfaces:    []InterfaceInfo{{Name: "eth0", TotalRx: 42}},
+		}},
+	}
+
+	d, err := m.serverDetail("web", now)
+	if err != nil {
+		t.Fatalf("serverDetail failed: %v", err)
+	}
+	if len(d.Hours) != 24 {
+		t.Errorf("Wrong hour count. Got %d, want %d", len(d.Hours), 24)
+	}
+	if len(d.Days) != 30 {
+		t.Errorf("Wrong day count. Got %d, want %d", len(d.Days), 30)
+	}
+	if len(d.Months) != 0 {
+		t.Errorf("Months older than a year should be skipped, got %d", len(d.Months))
+	}
+	if len(d.Top) != detailTop || d.Top[0].Rx != 9000 || d.Top[1].Tx != 5000 {
+		t.Errorf("Top days not sorted by volume: %+v", d.Top)
+	}
+	if d.VnStatVersion != "2.12" || len(d.Interfaces) != 1 {
+		t.Errorf("Missing vnStat info: %q %+v", d.VnStatVersion, d.Interfaces)
+	}
+	if d.LastError != "ssh: timeout" || !d.LastSuccessAt.Equal(now) {
+		t.Errorf("Missing poll status: %q at %s", d.LastError, d.LastSuccessAt)
+	}
+
+	// Known server that has not been polled yet
+	d, err = m.serverDetail("new", now)
+	if err != nil || d.Metrics != nil || len(d.Hours) != 0 {
+		t.Errorf("Expected empty detail for unpolled server, got %+v, %v", d, err)
+	}
+
+	if _, err := m.serverDetail("missing", now); err == nil {
+		t.Error("Expected error for unknown server")
+	}
+}
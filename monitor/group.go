@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"slices"
+	"sort"
+)
+
+// GroupMetrics are the totals of a set of servers, computed like the
+// global aggregate
+type GroupMetrics struct {
+	Name           string
+	Servers        []string // Member names, sorted
+	Online         int      // Members that are online
+	TotalRx        uint64   // Sum of current rates of online members
+	TotalTx        uint64
+	GrandTotalAvg  uint64 // Sum of members' Avg24h
+	GrandTotalPeak uint64 // Sum of members' MaxPeak
+	DominantServer string // Member with the highest daily average
+}
+
+// AggregateServers sums the metrics of servers. Offline servers are counted
+// as members but add nothing to the totals.
+func AggregateServers(name string, servers []*ServerMetrics) *GroupMetrics {
+	sorted := make([]*ServerMetrics, len(servers))
+	copy(sorted, servers)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	g := &GroupMetrics{Name: name, Servers: make([]string, 0, len(sorted))}
+	var maxUsage uint64
+	for _, sm := range sorted {
+		g.Servers = append(g.Servers, sm.Name)
+		if !sm.Online {
+			continue
+		}
+		g.Online++
+		g.TotalRx += sm.Rx
+		g.TotalTx += sm.Tx
+
+		// Grand Total Avg = Sum of all servers' (AvgRx24h + AvgTx24h)
+		serverAvg := sm.AvgRx24h + sm.AvgTx24h
+		g.GrandTotalAvg += serverAvg
+
+		// Grand Total Peak = Sum of all servers' MaxPeak; the max of Rx/Tx
+		// is taken for capacity planning
+		g.GrandTotalPeak += max(sm.PeakRx, sm.PeakTx)
+
+		// Dominant Server (by daily average usage)
+		if serverAvg > maxUsage {
+			maxUsage = serverAvg
+			g.DominantServer = sm.Name
+		}
+	}
+	return g
+}
+
+// GroupServers aggregates servers by the keys returned for each of them. A
+// server with several keys counts towards each group; one without keys is
+// left out.
+func GroupServers(servers map[string]*ServerMetrics, keys func(sm *ServerMetrics) []string) map[string]*GroupMetrics {
+	members := make(map[string][]*ServerMetrics)
+	for _, sm := range servers {
+		for _, key := range keys(sm) {
+			members[key] = append(members[key], sm)
+		}
+	}
+
+	groups := make(map[string]*GroupMetrics, len(members))
+	for key, list := range members {
+		groups[key] = AggregateServers(key, list)
+	}
+	return groups
+}
+
+// ByGroup returns the configured group of a server, for GroupServers
+func ByGroup(sm *ServerMetrics) []string {
+	if sm.Group == "" {
+		return nil
+	}
+	return []string{sm.Group}
+}
+
+// ByTag returns the tags of a server, for GroupServers
+func ByTag(sm *ServerMetrics) []string {
+	return sm.Tags
+}
+
+// HasTags reports whether the server carries every given tag
+func (sm *ServerMetrics) HasTags(tags ...string) bool {
+	for _, tag := range tags {
+		if !slices.Contains(sm.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupServers(t *testing.T) {
+	servers := map[string]*ServerMetrics{
+		"web1": {Name: "web1", Group: "acme", Tags: []string{"eu", "web"}, Online: true, Rx: 100, Tx: 50, AvgRx24h: 40, AvgTx24h: 20, PeakRx: 300, PeakTx: 100},
+		"web2": {Name: "web2", Group: "acme", Tags: []string{"us", "web"}, Online: true, Rx: 200, Tx: 10, AvgRx24h: 80, AvgTx24h: 10, PeakRx: 50, PeakTx: 400},
+		"db1":  {Name: "db1", Group: "globex", Tags: []string{"eu"}, Online: false, Rx: 999},
+		"mon":  {Name: "mon", Online: true, Rx: 1},
+	}
+
+	groups := GroupServers(servers, ByGroup)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	acme := groups["acme"]
+	if acme.TotalRx != 300 || acme.TotalTx != 60 || acme.GrandTotalAvg != 150 || acme.GrandTotalPeak != 700 {
+		t.Errorf("Unexpected acme totals: %+v", acme)
+	}
+	if acme.DominantServer != "web2" || acme.Online != 2 || strings.Join(acme.Servers, ",") != "web1,web2" {
+		t.Errorf("Unexpected acme members: %+v", acme)
+	}
+
+	// Offline members count but add nothing
+	if globex := groups["globex"]; globex.Online != 0 || globex.TotalRx != 0 || len(globex.Servers) != 1 {
+		t.Errorf("Unexpected globex totals: %+v", globex)
+	}
+
+	// A server with several tags counts towards each of them
+	byTag := GroupServers(servers, ByTag)
+	if len(byTag) != 3 || byTag["eu"].TotalRx != 100 || len(byTag["eu"].Servers) != 2 || byTag["web"].TotalRx != 300 {
+		t.Errorf("Unexpected tag groups: eu=%+v web=%+v", byTag["eu"], byTag["web"])
+	}
+
+	if !servers["web1"].HasTags("web", "eu") || servers["web1"].HasTags("web", "us") || !servers["mon"].HasTags() {
+		t.Error("HasTags mismatch")
+	}
+}
@@ -0,0 +1,221 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// vnStat bucket resolutions. Months vary in length; monthResolution is only
+// used to rank the tiers.
+const (
+	fiveMinuteResolution = 5 * time.Minute
+	hourResolution       = time.Hour
+	dayResolution        = 24 * time.Hour
+	monthResolution      = 30 * 24 * time.Hour
+
+	// maxHistoryPoints limits the number of points returned when no step is given
+	maxHistoryPoints = 2000
+)
+
+// serverTraffic holds the vnStat buckets of a server's monitored
+// interfaces, summed across interfaces
+type serverTraffic struct {
+	FiveMinute []TrafficBucket
+	Hour       []TrafficBucket
+	Day        []TrafficBucket
+	Month      []TrafficBucket
+	Top        []TrafficBucket // Top days, summed across interfaces
+	UpdatedAt  time.Time
+
+	VnStatVersion string
+	Interfaces    []InterfaceInfo
+}
+
+// InterfaceInfo describes a monitored interface as reported by vnStat
+type InterfaceInfo struct {
+	Name    string
+	Alias   string
+	Created time.Time // When vnStat started tracking the interface
+	Updated time.Time // Last vnStat database update
+	TotalRx uint64    // All-time bytes received
+	TotalTx uint64    // All-time bytes transmitted
+}
+
+// trafficTier is one vnStat bucket array with its resolution
+type trafficTier struct {
+	resolution time.Duration
+	buckets    func(t *serverTraffic) []TrafficBucket
+}
+
+var trafficTiers = []trafficTier{
+	{fiveMinuteResolution, func(t *serverTraffic) []TrafficBucket { return t.FiveMinute }},
+	{hourResolution, func(t *serverTraffic) []TrafficBucket { return t.Hour }},
+	{dayResolution, func(t *serverTraffic) []TrafficBucket { return t.Day }},
+	{monthResolution, func(t *serverTraffic) []TrafficBucket { return t.Month }},
+}
+
+// TrafficPoint is the volume transferred during one history interval
+type TrafficPoint struct {
+	Time     time.Time
+	Duration time.Duration
+	Rx       uint64 // Bytes
+	Tx       uint64 // Bytes
+}
+
+// TrafficHistory is the result of a history query
+type TrafficHistory struct {
+	Resolution time.Duration // Resolution of the vnStat buckets used
+	Step       time.Duration // Width of the returned points; zero when buckets are returned as-is
+	Points     []TrafficPoint
+}
+
+// cacheTraffic stores the latest vnStat buckets of a server for history queries
+func (m *Monitor) cacheTraffic(name string, traffic *serverTraffic) {
+	if traffic == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pollers[name]; !ok {
+		return
+	}
+	if m.traffic == nil {
+		m.traffic = make(map[string]*serverTraffic)
+	}
+	m.traffic[name] = traffic
+}
+
+// QueryTraffic returns the traffic of a server, or of all servers when name
+// is empty, between from and to from the cached vnStat buckets. The finest
+// vnStat tier that covers the range at the requested step is used, and its
+// buckets are summed into step-sized intervals. No SSH calls are made.
+func (m *Monitor) QueryTraffic(name string, from, to time.Time, step time.Duration) (*TrafficHistory, error) {
+	m.mu.RLock()
+	var sources []*serverTraffic
+	if name == "" {
+		for _, t := range m.traffic {
+			sources = append(sources, t)
+		}
+	} else if t, ok := m.traffic[name]; ok {
+		sources = append(sources, t)
+	} else if _, ok := m.pollers[name]; !ok {
+		m.mu.RUnlock()
+		return nil, fmt.Errorf("server '%s' not found", name)
+	}
+	m.mu.RUnlock()
+
+	// Sum each tier across the selected servers
+	tiers := make([][]TrafficBucket, len(trafficTiers))
+	for i, tier := range trafficTiers {
+		lists := make([][]TrafficBucket, 0, len(sources))
+		for _, s := range sources {
+			lists = append(lists, tier.buckets(s))
+		}
+		if len(lists) > 0 {
+			tiers[i] = sortedBuckets(mergeBuckets(lists...))
+		}
+	}
+
+	i := selectTrafficTier(tiers, from, to, step)
+	history := &TrafficHistory{
+		Resolution: trafficTiers[i].resolution,
+		Points:     []TrafficPoint{},
+	}
+
+	for _, b := range tiers[i] {
+		t := time.Unix(b.Timestamp, 0)
+		if t.Before(from) || t.After(to) {
+			continue
+		}
+		history.Points = append(history.Points, TrafficPoint{
+			Time:     t,
+			Duration: bucketDuration(b, history.Resolution),
+			Rx:       b.Rx,
+			Tx:       b.Tx,
+		})
+	}
+
+	if step > history.Resolution {
+		history.Step = step
+		history.Points = groupPoints(history.Points, step)
+	}
+
+	return history, nil
+}
+
+// selectTrafficTier picks the finest tier whose resolution fits the step
+// and whose data reaches back to from. Without a step, the finest covering
+// tier that yields at most maxHistoryPoints is used.
+func selectTrafficTier(tiers [][]TrafficBucket, from, to time.Time, step time.Duration) int {
+	fits := func(i int) bool {
+		res := trafficTiers[i].resolution
+		if step > 0 {
+			return res <= step
+		}
+		return to.Sub(from)/res <= maxHistoryPoints
+	}
+	covers := func(i int) bool {
+		b := tiers[i]
+		return len(b) > 0 && !time.Unix(b[0].Timestamp, 0).After(from.Add(trafficTiers[i].resolution))
+	}
+
+	for i := range tiers {
+		if fits(i) && covers(i) {
+			return i
+		}
+	}
+	for i := range tiers {
+		if covers(i) {
+			return i
+		}
+	}
+
+	// No tier reaches back far enough; use the one with the oldest data
+	best := len(tiers) - 1
+	for i := range tiers {
+		if len(tiers[i]) == 0 {
+			continue
+		}
+		if len(tiers[best]) == 0 || tiers[i][0].Timestamp < tiers[best][0].Timestamp {
+			best = i
+		}
+	}
+	return best
+}
+
+// bucketDuration returns the length of a vnStat bucket
+func bucketDuration(b TrafficBucket, resolution time.Duration) time.Duration {
+	if resolution == monthResolution && b.Date.Month >= 1 && b.Date.Month <= 12 {
+		return time.Duration(daysIn(b.Date.Year, time.Month(b.Date.Month))) * 24 * time.Hour
+	}
+	return resolution
+}
+
+// sortedBuckets returns the buckets ordered by timestamp
+func sortedBuckets(buckets []TrafficBucket) []TrafficBucket {
+	sorted := make([]TrafficBucket, len(buckets))
+	copy(sorted, buckets)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp < sorted[j].Timestamp
+	})
+	return sorted
+}
+
+// groupPoints sums points into step-sized intervals aligned to the Unix epoch
+func groupPoints(points []TrafficPoint, step time.Duration) []TrafficPoint {
+	seconds := int64(step / time.Second)
+	grouped := []TrafficPoint{}
+	for _, p := range points {
+		start := time.Unix(p.Time.Unix()/seconds*seconds, 0)
+		if n := len(grouped); n > 0 && grouped[n-1].Time.Equal(start) {
+			grouped[n-1].Rx += p.Rx
+			grouped[n-1].Tx += p.Tx
+			continue
+		}
+		grouped = append(grouped, TrafficPoint{Time: start, Duration: step, Rx: p.Rx, Tx: p.Tx})
+	}
+	return grouped
+}
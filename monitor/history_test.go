@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func bucketsEvery(start time.Time, step time.Duration, n int, rx, tx uint64) []TrafficBucket {
+	buckets := make([]TrafficBucket, n)
+	for i := range buckets {
+		t := start.Add(time.Duration(i) * step)
+		buckets[i].Timestamp = t.Unix()
+		buckets[i].Date.Year = t.Year()
+		buckets[i].Date.Month = int(t.Month())
+		buckets[i].Date.Day = t.Day()
+		buckets[i].Rx = rx
+		buckets[i].Tx = tx
+	}
+	return buckets
+}
+
+func TestQueryTraffic(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	web := &serverTraffic{
+		FiveMinute: bucketsEvery(now.Add(-2*time.Hour), 5*time.Minute, 24, 300, 600),
+		Hour:       bucketsEvery(now.Add(-72*time.Hour), time.Hour, 72, 3600, 7200),
+		Day:        bucketsEvery(now.AddDate(0, 0, -30).Truncate(24*time.Hour), 24*time.Hour, 31, 1000, 2000),
+		Month:      bucketsEvery(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), 0, 1, 5000, 5000),
+	}
+	db := &serverTraffic{
+		FiveMinute: bucketsEvery(now.Add(-2*time.Hour), 5*time.Minute, 24, 100, 100),
+	}
+
+	m := &Monitor{
+		pollers: map[string]*poller{"web": {}, "db": {}, "new": {}},
+		traffic: map[string]*serverTraffic{"web": web, "db": db},
+	}
+
+	// Last hour of five-minute data
+	h, err := m.QueryTraffic("web", now.Add(-time.Hour), now, 0)
+	if err != nil {
+		t.Fatalf("QueryTraffic failed: %v", err)
+	}
+	if h.Resolution != 5*time.Minute || len(h.Points) != 12 {
+		t.Errorf("Expected 12 five-minute points, got %d at %s", len(h.Points), h.Resolution)
+	}
+
+	// Grouped into 30-minute steps
+	h, _ = m.QueryTraffic("web", now.Add(-time.Hour), now, 30*time.Minute)
+	if h.Step != 30*time.Minute || len(h.Points) != 2 || h.Points[0].Rx != 6*300 {
+		t.Errorf("Unexpected 30m grouping: step %s, points %+v", h.Step, h.Points)
+	}
+
+	// Two days back is only covered by the hourly tier
+	h, _ = m.QueryTraffic("web", now.Add(-48*time.Hour), now, 0)
+	if h.Resolution != time.Hour {
+		t.Errorf("Expected hourly resolution, got %s", h.Resolution)
+	}
+
+	// A daily step uses day buckets
+	h, _ = m.QueryTraffic("web", now.AddDate(0, 0, -20), now, 24*time.Hour)
+	if h.Resolution != 24*time.Hour || len(h.Points) != 20 {
+		t.Errorf("Expected 20 daily points, got %d at %s", len(h.Points), h.Resolution)
+	}
+
+	// Month buckets report their real length
+	h, _ = m.QueryTraffic("web", time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), now, 30*24*time.Hour)
+	if h.Resolution != monthResolution {
+		t.Errorf("Expected month resolution, got %s", h.Resolution)
+	}
+	if len(h.Points) != 1 || h.Points[0].Duration != 31*24*time.Hour {
+		t.Errorf("Unexpected month points: %+v", h.Points)
+	}
+
+	// Aggregate sums all servers
+	h, _ = m.QueryTraffic("", now.Add(-time.Hour), now, 0)
+	if len(h.Points) != 12 || h.Points[0].Rx != 400 || h.Points[0].Tx != 700 {
+		t.Errorf("Unexpected aggregate points: %+v", h.Points)
+	}
+
+	// Known server without data yet
+	h, err = m.QueryTraffic("new", now.Add(-time.Hour), now, 0)
+	if err != nil || len(h.Points) != 0 {
+		t.Errorf("Expected empty history for new server, got %v, %v", h, err)
+	}
+
+	if _, err := m.QueryTraffic("missing", now.Add(-time.Hour), now, 0); err == nil {
+		t.Error("Expected error for unknown server")
+	}
+}
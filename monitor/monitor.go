@@ -3,10 +3,14 @@ package monitor
 import (
 	"bandwidth-monitor/config"
 	"bandwidth-monitor/sshclient"
-	"encoding/json"
+	"bandwidth-monitor/store"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -74,12 +78,20 @@ type PeakEvent struct {
 type ServerMetrics struct {
 	Name      string
 	IP        string
+	Interface string
+	Group     string
+	Tags      []string
 	Online    bool
 	Rx        uint64 // Bytes per second (Current)
 	Tx        uint64 // Bytes per second (Current)
 	TotalRx   uint64 // Total bytes today
 	TotalTx   uint64 // Total bytes today
 
+	// Bytes per second over the latest complete five-minute bucket. Rx and
+	// Tx fall back to these until counter readings give a current rate.
+	FiveMinuteRx uint64
+	FiveMinuteTx uint64
+
 	// New Analytics Fields (Bytes per second)
 	AvgRx12h   uint64
 	AvgTx12h   uint64
@@ -89,17 +101,46 @@ type ServerMetrics struct {
 	PeakTx     uint64 // Max observed speed in last 24h
 	PeakEvents []PeakEvent // Top 3 peak hours
 
+	Interfaces  []InterfaceMetrics // Per-interface breakdown
+	Quota       *QuotaUsage        // Nil when no quota is configured
+	Percentiles *PercentileUsage   // Billing percentiles, nil until computed
+	Baseline    *Baseline          // Expected rates this hour, nil while still being learned
+	Anomalies   []AnomalyEvent     // Recent deviations from the baseline, newest first
+
+	Reachability *Reachability // Up/down state, uptime and flapping; nil until polled
+
+	traffic *serverTraffic // vnStat buckets behind these metrics, cached for history queries
+
 	UpdatedAt time.Time
 	Error     string
 }
 
+// InterfaceMetrics represents metrics for a single network interface
+type InterfaceMetrics struct {
+	Name         string
+	Rx           uint64 // Bytes per second (Current)
+	Tx           uint64 // Bytes per second (Current)
+	TotalRx      uint64 // Total bytes today
+	TotalTx      uint64 // Total bytes today
+	FiveMinuteRx uint64 // Bytes per second over the latest complete five-minute bucket
+	FiveMinuteTx uint64
+	AvgRx12h     uint64
+	AvgTx12h     uint64
+	AvgRx24h     uint64
+	AvgTx24h     uint64
+	PeakRx       uint64
+	PeakTx       uint64
+}
+
 // AggregateMetrics represents aggregated metrics from all servers
 type AggregateMetrics struct {
 	TotalRx        uint64
 	TotalTx        uint64
-	GrandTotalAvg  uint64 // Sum of all servers' Avg24h
-	GrandTotalPeak uint64 // Sum of all servers' MaxPeak
-	DominantServer string // Name of server with highest usage
+	GrandTotalAvg  uint64                   // Sum of all servers' Avg24h
+	GrandTotalPeak uint64                   // Sum of all servers' MaxPeak
+	DominantServer string                   // Name of server with highest usage
+	Percentiles    *PercentileUsage         // Billing percentiles of the aggregate, nil until computed
+	Groups         map[string]*GroupMetrics // Totals per configured group
 	ServerMetrics  map[string]*ServerMetrics
 	History        []HistoryEntry
 	UpdatedAt      time.Time
@@ -112,17 +153,63 @@ type HistoryEntry struct {
 	TotalTx   uint64
 }
 
+// PollStats holds cumulative polling statistics for a server
+type PollStats struct {
+	Polls         uint64        // Number of completed polls
+	SSHErrors     uint64        // Polls that failed to connect or run vnStat
+	LastDuration  time.Duration // Duration of the most recent poll
+	LastError     string        // Most recent poll error, kept after recovery
+	LastErrorAt   time.Time
+	LastSuccessAt time.Time
+}
+
 // Monitor manages monitoring of all servers
 type Monitor struct {
 	config       *config.Config
-	privateKey   []byte
+	pool         *sshclient.Pool
+	store        *store.Store
 	metrics      *AggregateMetrics
+	pollStats    map[string]*PollStats
+	alerts       *AlertEngine
+	alertHooks   []func(AlertEvent)
+	pollers      map[string]*poller
+	traffic      map[string]*serverTraffic       // Latest vnStat buckets per server
+	collectors   map[string]Collector            // Registered collectors by name
+	rates        map[string]*rateTracker         // Smoothed counter rates per server
+	percentiles  map[string]*PercentileUsage     // Latest billing percentiles per server
+	anomalies    map[string]*anomalyDetector     // Traffic baselines and anomalies per server
+	reachability map[string]*reachabilityTracker // Up/down state machine per server
+	savedUptime  map[string]reachabilityRecord   // Persisted history of servers without a tracker yet
+	uptimeOnce   sync.Once                       // Loads savedUptime
+	uptimeMu     sync.Mutex                      // Serializes writes of the uptime file
+	reconcileMu  sync.Mutex // Serializes RefreshServers
+	subscribers  map[chan struct{}]struct{}
+	subMu        sync.Mutex
 	mu           sync.RWMutex
 	stopChan     chan struct{}
+	stopOnce     sync.Once
 	pollInterval time.Duration
 	historyLimit int
 }
 
+// poller is the polling goroutine of one server
+type poller struct {
+	server config.ServerConfig
+	stop   chan struct{}
+	poll   chan struct{} // Requests an immediate poll
+}
+
+// RemoveData deletes the history and uptime files the monitor keeps in a
+// data directory, leaving any other files in place
+func RemoveData(dataDir string) error {
+	for _, name := range []string{uptimeFile, uptimeFile + ".tmp"} {
+		if err := os.Remove(filepath.Join(dataDir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove uptime history: %w", err)
+		}
+	}
+	return store.Purge(dataDir)
+}
+
 // NewMonitor creates a new monitor instance
 func NewMonitor(cfg *config.Config, pollInterval time.Duration) (*Monitor, error) {
 	// Load SSH private key
@@ -131,245 +218,537 @@ func NewMonitor(cfg *config.Config, pollInterval time.Duration) (*Monitor, error
 		return nil, fmt.Errorf("failed to load SSH private key: %w", err)
 	}
 
-	// Calculate history limit to keep approximately 5 minutes of data
-	historyLimit := int((5 * time.Minute) / pollInterval)
-	if historyLimit < 1 {
-		historyLimit = 1
+	// Open persistent history store
+	dataDir := cfg.GetSettings().GetDataDir()
+	historyStore, err := store.Open(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history store in %s: %w", dataDir, err)
 	}
 
+	// Validate alert rules
+	alerts, err := NewAlertEngine(cfg.GetAlerts())
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert configuration: %w", err)
+	}
+
+	pool := sshclient.NewPool([]byte(privateKeyStr))
 	return &Monitor{
 		config:     cfg,
-		privateKey: []byte(privateKeyStr),
+		pool:       pool,
+		store:      historyStore,
+		collectors: newCollectors(pool),
 		metrics: &AggregateMetrics{
 			ServerMetrics: make(map[string]*ServerMetrics),
 			History:       make([]HistoryEntry, 0),
 		},
+		pollStats:    make(map[string]*PollStats),
+		alerts:       alerts,
+		pollers:      make(map[string]*poller),
 		stopChan:     make(chan struct{}),
 		pollInterval: pollInterval,
-		historyLimit: historyLimit,
+		historyLimit: historyLimitFor(pollInterval),
 	}, nil
 }
 
+// historyLimitFor returns the number of history entries that cover
+// approximately 5 minutes at the given poll interval
+func historyLimitFor(pollInterval time.Duration) int {
+	historyLimit := int((5 * time.Minute) / pollInterval)
+	if historyLimit < 1 {
+		historyLimit = 1
+	}
+	return historyLimit
+}
+
 // Start begins monitoring all servers
 func (m *Monitor) Start() {
-	servers := m.config.GetServers()
-
-	var wg sync.WaitGroup
-	for _, server := range servers {
-		wg.Add(1)
-		go func(s config.ServerConfig) {
-			defer wg.Done()
-			m.monitorServer(s)
-		}(server)
-	}
+	m.reconcileMu.Lock()
+	m.reconcileServers(false)
+	m.reconcileMu.Unlock()
 
 	// Start history cleaner
 	go m.cleanHistory()
 
+	// Start store compaction
+	go m.compactStore()
+
 	// Start aggregation updater
 	go m.updateAggregate()
+
+	// Start billing percentile updater
+	go m.updatePercentiles()
+
+	// Start uptime history saver
+	go m.persistReachability()
 }
 
-// Stop stops monitoring
+// Stop stops monitoring, closes all pooled SSH connections and saves the
+// reachability history
 func (m *Monitor) Stop() {
-	close(m.stopChan)
+	m.stopOnce.Do(func() {
+		close(m.stopChan)
+		m.pool.Close()
+		m.saveReachability()
+	})
 }
 
-// monitorServer monitors a single server
-func (m *Monitor) monitorServer(server config.ServerConfig) {
-	ticker := time.NewTicker(m.pollInterval)
+// monitorServer monitors a single server until the monitor or the poller
+// is stopped
+func (m *Monitor) monitorServer(p *poller) {
+	ticker := time.NewTicker(m.getPollInterval())
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-m.stopChan:
 			return
+		case <-p.stop:
+			return
 		case <-ticker.C:
-			m.collectMetrics(server)
+			m.collectMetrics(p.server)
+		case <-p.poll:
+			m.collectMetrics(p.server)
 		}
 	}
 }
 
+// PollNow asks the poller of a server to poll immediately instead of
+// waiting for the next tick. Requests made while a poll is pending are merged.
+func (m *Monitor) PollNow(name string) error {
+	m.mu.RLock()
+	p, ok := m.pollers[name]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("server '%s' not found", name)
+	}
+
+	select {
+	case p.poll <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// getPollInterval returns the current poll interval
+func (m *Monitor) getPollInterval() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pollInterval
+}
+
 // collectMetrics collects metrics from a single server
 func (m *Monitor) collectMetrics(server config.ServerConfig) {
+	start := time.Now()
+	sshFailed := false
+	defer func() {
+		m.recordPoll(server.Name, time.Since(start), sshFailed)
+	}()
+
 	metrics := &ServerMetrics{
 		Name:      server.Name,
 		IP:        server.IP,
+		Interface: strings.Join(server.GetInterfaces(), ","),
+		Group:     server.Group,
+		Tags:      server.Tags,
 		Online:    false,
 		UpdatedAt: time.Now(),
 	}
 
-	// Connect to server
-	client, err := sshclient.NewClientWithKey(server.IP, server.Port, server.User, m.privateKey)
+	collector, err := m.collectorFor(server)
 	if err != nil {
 		metrics.Error = err.Error()
-		m.setServerMetrics(server.Name, metrics)
+		m.setServerMetrics(server.Name, m.trackReachability(server.Name, metrics, false))
 		return
 	}
-	defer client.Close()
 
-	// Get vnStat data
-	jsonData, err := client.GetVnStatData(server.Interface)
+	traffic, err := collector.Collect(server)
 	if err != nil {
+		sshFailed = isUnreachable(err)
 		metrics.Error = err.Error()
-		m.setServerMetrics(server.Name, metrics)
+		m.setServerMetrics(server.Name, m.trackReachability(server.Name, metrics, sshFailed))
 		return
 	}
 
-	// Parse vnStat data
-	var vnstat VnStatData
-	if err := json.Unmarshal([]byte(jsonData), &vnstat); err != nil {
-		metrics.Error = fmt.Sprintf("failed to parse vnStat data: %v", err)
-		m.setServerMetrics(server.Name, metrics)
+	processedMetrics := m.processTraffic(server, traffic)
+	m.checkAnomalies(server.Name, processedMetrics)
+	m.setServerMetrics(server.Name, m.trackReachability(server.Name, processedMetrics, false))
+	m.cacheTraffic(server.Name, processedMetrics.traffic)
+	m.recordSample(server.Name, processedMetrics.UpdatedAt, processedMetrics.Rx, processedMetrics.Tx)
+}
+
+// recordSample appends a rate sample to the persistent history store
+func (m *Monitor) recordSample(series string, t time.Time, rx, tx uint64) {
+	if m.store == nil {
 		return
 	}
 
-	// Process metrics using extracted logic
-	processedMetrics := m.processVnStatData(server, &vnstat)
-	m.setServerMetrics(server.Name, processedMetrics)
+	sample := store.Sample{
+		Timestamp: t.Unix(),
+		Rx:        rx,
+		Tx:        tx,
+	}
+	if err := m.store.Append(series, sample); err != nil {
+		log.Printf("Failed to record history for %s: %v", series, err)
+	}
 }
 
 // processVnStatData processes the parsed vnStat data and returns ServerMetrics.
+// Server-level fields are computed from the combined traffic of all
+// monitored interfaces; Interfaces holds the per-interface breakdown.
 func (m *Monitor) processVnStatData(server config.ServerConfig, vnstat *VnStatData) *ServerMetrics {
+	wanted := server.GetInterfaces()
 	metrics := &ServerMetrics{
 		Name:      server.Name,
 		IP:        server.IP,
+		Interface: strings.Join(wanted, ","),
+		Group:     server.Group,
+		Tags:      server.Tags,
 		Online:    true,
 		UpdatedAt: time.Now(),
 	}
 
-	if len(vnstat.Interfaces) > 0 {
-		iface := vnstat.Interfaces[0]
-		now := time.Now().UTC()
+	now := time.Now().UTC()
 
-		// Get today's total
-		// Find the day entry that matches today
-		// (Assuming the last entry in Day array is usually today, but we can check timestamp if needed.
-		// For simplicity and standard vnStat behavior, the list usually ends with current data,
-		// but let's look for the one with highest timestamp just to be safe or just take the last one)
-		if len(iface.Traffic.Day) > 0 {
-			// Sort days by timestamp just in case
-			sort.Slice(iface.Traffic.Day, func(i, j int) bool {
-				return iface.Traffic.Day[i].Timestamp < iface.Traffic.Day[j].Timestamp
-			})
-			today := iface.Traffic.Day[len(iface.Traffic.Day)-1]
-			metrics.TotalRx = today.Rx
-			metrics.TotalTx = today.Tx
+	// Pick the configured interfaces in order; without a configuration,
+	// every interface in the data is used
+	indexes := make([]int, 0, len(vnstat.Interfaces))
+	var missing []string
+	if len(wanted) == 0 {
+		for i := range vnstat.Interfaces {
+			indexes = append(indexes, i)
 		}
+	} else {
+		for _, name := range wanted {
+			found := false
+			for i, iface := range vnstat.Interfaces {
+				if iface.Name == name {
+					indexes = append(indexes, i)
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, name)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		metrics.Error = fmt.Sprintf("interface(s) not found: %s", strings.Join(missing, ", "))
+	}
 
-		// Calculate real-time speed from FiveMinute data
-		if len(iface.Traffic.FiveMinute) > 0 {
-			// Sort by timestamp DESCENDING (newest first)
-			// Copy slice to avoid modifying original if we needed it elsewhere, but here it's fine.
-			// Actually let's just sort the slice in place.
-			buckets := iface.Traffic.FiveMinute
-			sort.Slice(buckets, func(i, j int) bool {
-				return buckets[i].Timestamp > buckets[j].Timestamp
-			})
+	if len(indexes) == 0 {
+		return metrics
+	}
 
-			// Take the latest bucket
-			latest := buckets[0]
+	var fiveMinute, hour, day, month, top [][]TrafficBucket
+	var infos []InterfaceInfo
+	for _, i := range indexes {
+		iface := vnstat.Interfaces[i]
+		im, _ := trafficMetrics(iface.Traffic.FiveMinute, iface.Traffic.Hour, iface.Traffic.Day, now)
+		im.Name = iface.Name
+		metrics.Interfaces = append(metrics.Interfaces, im)
+
+		fiveMinute = append(fiveMinute, iface.Traffic.FiveMinute)
+		hour = append(hour, iface.Traffic.Hour)
+		day = append(day, iface.Traffic.Day)
+		month = append(month, iface.Traffic.Month)
+		top = append(top, iface.Traffic.Top)
+
+		infos = append(infos, InterfaceInfo{
+			Name:    iface.Name,
+			Alias:   iface.Alias,
+			Created: time.Unix(iface.Created.Timestamp, 0),
+			Updated: time.Unix(iface.Updated.Timestamp, 0),
+			TotalRx: iface.Traffic.Total.Rx,
+			TotalTx: iface.Traffic.Total.Tx,
+		})
+	}
 
-			// Calculate speed: Volume / 300 seconds
-			metrics.Rx = latest.Rx / 300
-			metrics.Tx = latest.Tx / 300
+	if len(wanted) == 0 {
+		names := make([]string, len(metrics.Interfaces))
+		for i, im := range metrics.Interfaces {
+			names[i] = im.Name
 		}
+		metrics.Interface = strings.Join(names, ",")
+	}
 
-		// Calculate Averages and Peaks (12h/24h)
-		var sumRx12, sumTx12, sumRx24, sumTx24 uint64
-		var count12, count24 uint64
-		var peakRx, peakTx uint64
-
-		type hourTraffic struct {
-			t     time.Time
-			rx    uint64
-			tx    uint64
-			total uint64
-		}
-		var hours []hourTraffic
+	metrics.traffic = &serverTraffic{
+		FiveMinute: mergeBuckets(fiveMinute...),
+		Hour:       mergeBuckets(hour...),
+		Day:        mergeBuckets(day...),
+		Month:      mergeBuckets(month...),
+		Top:        mergeBuckets(top...),
+		UpdatedAt:  metrics.UpdatedAt,
 
-		for _, h := range iface.Traffic.Hour {
-			ts := time.Unix(h.Timestamp, 0).UTC()
-			age := now.Sub(ts)
+		VnStatVersion: vnstat.VnStatVersion,
+		Interfaces:    infos,
+	}
 
-			// Calculate 24h average usage
-			// Using 24h window
-			if age <= 24*time.Hour && age >= 0 {
-				sumRx24 += h.Rx
-				sumTx24 += h.Tx
-				count24++
+	total, peaks := trafficMetrics(metrics.traffic.FiveMinute, metrics.traffic.Hour, metrics.traffic.Day, now)
+	metrics.Rx = total.Rx
+	metrics.Tx = total.Tx
+	metrics.FiveMinuteRx = total.FiveMinuteRx
+	metrics.FiveMinuteTx = total.FiveMinuteTx
+	metrics.TotalRx = total.TotalRx
+	metrics.TotalTx = total.TotalTx
+	metrics.AvgRx12h = total.AvgRx12h
+	metrics.AvgTx12h = total.AvgTx12h
+	metrics.AvgRx24h = total.AvgRx24h
+	metrics.AvgTx24h = total.AvgTx24h
+	metrics.PeakRx = total.PeakRx
+	metrics.PeakTx = total.PeakTx
+	metrics.PeakEvents = peaks
+
+	// Billing-cycle usage for servers with a monthly quota
+	if server.Quota != nil {
+		metrics.Quota = computeQuota(*server.Quota, metrics.traffic.Day, metrics.traffic.Month, now)
+	}
 
-				rateRx := h.Rx / 3600
-				rateTx := h.Tx / 3600
+	return metrics
+}
 
-				if rateRx > peakRx {
-					peakRx = rateRx
-				}
-				if rateTx > peakTx {
-					peakTx = rateTx
-				}
+// mergeBuckets sums buckets with the same timestamp across interfaces
+func mergeBuckets(lists ...[]TrafficBucket) []TrafficBucket {
+	if len(lists) == 1 {
+		return lists[0]
+	}
 
-				hours = append(hours, hourTraffic{
-					t:     ts,
-					rx:    rateRx,
-					tx:    rateTx,
-					total: rateRx + rateTx,
-				})
-
-				// Filter for last 12h
-				if age <= 12*time.Hour {
-					sumRx12 += h.Rx
-					sumTx12 += h.Tx
-					count12++
-				}
+	byTime := make(map[int64]int)
+	var merged []TrafficBucket
+	for _, list := range lists {
+		for _, b := range list {
+			if i, ok := byTime[b.Timestamp]; ok {
+				merged[i].Rx += b.Rx
+				merged[i].Tx += b.Tx
+				continue
 			}
+			byTime[b.Timestamp] = len(merged)
+			merged = append(merged, b)
 		}
+	}
+	return merged
+}
 
-		if count12 > 0 {
-			metrics.AvgRx12h = sumRx12 / (count12 * 3600)
-			metrics.AvgTx12h = sumTx12 / (count12 * 3600)
-		}
-		if count24 > 0 {
-			metrics.AvgRx24h = sumRx24 / (count24 * 3600)
-			metrics.AvgTx24h = sumTx24 / (count24 * 3600)
-		}
-
-		metrics.PeakRx = peakRx
-		metrics.PeakTx = peakTx
+// trafficMetrics computes rates, today's totals, averages and peaks from
+// vnStat buckets. It also returns the top 3 peak hours.
+func trafficMetrics(fiveMinute, hour, day []TrafficBucket, now time.Time) (InterfaceMetrics, []PeakEvent) {
+	var metrics InterfaceMetrics
+
+	// Get today's total
+	// Find the day entry that matches today
+	// (Assuming the last entry in Day array is usually today, but we can check timestamp if needed.
+	// For simplicity and standard vnStat behavior, the list usually ends with current data,
+	// but let's look for the one with highest timestamp just to be safe or just take the last one)
+	if len(day) > 0 {
+		// Sort days by timestamp just in case
+		sort.Slice(day, func(i, j int) bool {
+			return day[i].Timestamp < day[j].Timestamp
+		})
+		today := day[len(day)-1]
+		metrics.TotalRx = today.Rx
+		metrics.TotalTx = today.Tx
+	}
 
-		// Find top 3 peak hours
-		sort.Slice(hours, func(i, j int) bool {
-			return hours[i].total > hours[j].total
+	// Calculate the five-minute rate from FiveMinute data
+	if len(fiveMinute) > 0 {
+		// Sort by timestamp DESCENDING (newest first)
+		sort.Slice(fiveMinute, func(i, j int) bool {
+			return fiveMinute[i].Timestamp > fiveMinute[j].Timestamp
 		})
 
-		limit := 3
-		if len(hours) < 3 {
-			limit = len(hours)
+		// Take the latest complete bucket; the current one is still filling
+		latest := fiveMinute[0]
+		for _, b := range fiveMinute {
+			if time.Unix(b.Timestamp, 0).Add(fiveMinuteResolution).Before(now) {
+				latest = b
+				break
+			}
 		}
 
-		metrics.PeakEvents = make([]PeakEvent, 0, limit)
-		for i := 0; i < limit; i++ {
-			metrics.PeakEvents = append(metrics.PeakEvents, PeakEvent{
-				Time: hours[i].t,
-				Rx:   hours[i].rx,
-				Tx:   hours[i].tx,
+		// Calculate speed: Volume / 300 seconds
+		metrics.FiveMinuteRx = latest.Rx / 300
+		metrics.FiveMinuteTx = latest.Tx / 300
+		metrics.Rx = metrics.FiveMinuteRx
+		metrics.Tx = metrics.FiveMinuteTx
+	}
+
+	// Calculate Averages and Peaks (12h/24h)
+	var sumRx12, sumTx12, sumRx24, sumTx24 uint64
+	var count12, count24 uint64
+	var peakRx, peakTx uint64
+
+	type hourTraffic struct {
+		t     time.Time
+		rx    uint64
+		tx    uint64
+		total uint64
+	}
+	var hours []hourTraffic
+
+	for _, h := range hour {
+		ts := time.Unix(h.Timestamp, 0).UTC()
+		age := now.Sub(ts)
+
+		// Calculate 24h average usage
+		// Using 24h window
+		if age <= 24*time.Hour && age >= 0 {
+			sumRx24 += h.Rx
+			sumTx24 += h.Tx
+			count24++
+
+			rateRx := h.Rx / 3600
+			rateTx := h.Tx / 3600
+
+			if rateRx > peakRx {
+				peakRx = rateRx
+			}
+			if rateTx > peakTx {
+				peakTx = rateTx
+			}
+
+			hours = append(hours, hourTraffic{
+				t:     ts,
+				rx:    rateRx,
+				tx:    rateTx,
+				total: rateRx + rateTx,
 			})
+
+			// Filter for last 12h
+			if age <= 12*time.Hour {
+				sumRx12 += h.Rx
+				sumTx12 += h.Tx
+				count12++
+			}
 		}
 	}
 
-	return metrics
+	if count12 > 0 {
+		metrics.AvgRx12h = sumRx12 / (count12 * 3600)
+		metrics.AvgTx12h = sumTx12 / (count12 * 3600)
+	}
+	if count24 > 0 {
+		metrics.AvgRx24h = sumRx24 / (count24 * 3600)
+		metrics.AvgTx24h = sumTx24 / (count24 * 3600)
+	}
+
+	metrics.PeakRx = peakRx
+	metrics.PeakTx = peakTx
+
+	// Find top 3 peak hours
+	sort.Slice(hours, func(i, j int) bool {
+		return hours[i].total > hours[j].total
+	})
+
+	limit := 3
+	if len(hours) < 3 {
+		limit = len(hours)
+	}
+
+	peaks := make([]PeakEvent, 0, limit)
+	for i := 0; i < limit; i++ {
+		peaks = append(peaks, PeakEvent{
+			Time: hours[i].t,
+			Rx:   hours[i].rx,
+			Tx:   hours[i].tx,
+		})
+	}
+
+	return metrics, peaks
 }
 
 // setServerMetrics updates metrics for a server
 func (m *Monitor) setServerMetrics(name string, metrics *ServerMetrics) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+
+	// Drop results of a poll that finished after the server was removed
+	if _, ok := m.pollers[name]; !ok {
+		return
+	}
+	metrics.Percentiles = m.percentiles[name]
+	if d, ok := m.anomalies[name]; ok {
+		metrics.Anomalies = d.recent()
+	}
 	m.metrics.ServerMetrics[name] = metrics
+
+	stats := m.statsFor(name)
+	if metrics.Error != "" {
+		stats.LastError = metrics.Error
+		stats.LastErrorAt = metrics.UpdatedAt
+	}
+	if metrics.Online {
+		stats.LastSuccessAt = metrics.UpdatedAt
+	}
+
+	m.notifyChanged()
+}
+
+// statsFor returns the poll statistics of a server, creating them if
+// needed. The caller must hold m.mu.
+func (m *Monitor) statsFor(name string) *PollStats {
+	stats, ok := m.pollStats[name]
+	if !ok {
+		stats = &PollStats{}
+		m.pollStats[name] = stats
+	}
+	return stats
+}
+
+// Subscribe returns a channel that is signalled whenever server or
+// aggregate metrics change. Signals are coalesced, so a slow reader only
+// sees the latest state through GetMetrics. The returned function
+// unsubscribes.
+func (m *Monitor) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	m.subMu.Lock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[chan struct{}]struct{})
+	}
+	m.subscribers[ch] = struct{}{}
+	m.subMu.Unlock()
+
+	return ch, func() {
+		m.subMu.Lock()
+		delete(m.subscribers, ch)
+		m.subMu.Unlock()
+	}
+}
+
+// notifyChanged signals all subscribers without blocking
+func (m *Monitor) notifyChanged() {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	for ch := range m.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// recordPoll updates the polling statistics for a server
+func (m *Monitor) recordPoll(name string, duration time.Duration, sshFailed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pollers[name]; !ok {
+		return
+	}
+
+	stats := m.statsFor(name)
+	stats.Polls++
+	stats.LastDuration = duration
+	if sshFailed {
+		stats.SSHErrors++
+	}
 }
 
 // updateAggregate updates aggregate metrics periodically
 func (m *Monitor) updateAggregate() {
-	ticker := time.NewTicker(m.pollInterval)
+	interval := m.getPollInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -377,42 +756,27 @@ func (m *Monitor) updateAggregate() {
 		case <-m.stopChan:
 			return
 		case <-ticker.C:
-			m.mu.Lock()
+			// Follow poll interval changes from config reloads
+			if current := m.getPollInterval(); current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
 
-			var totalRx, totalTx, grandTotalAvg, grandTotalPeak uint64
-			var dominantServer string
-			var maxUsage uint64
+			m.mu.Lock()
 
+			servers := make([]*ServerMetrics, 0, len(m.metrics.ServerMetrics))
 			for _, metrics := range m.metrics.ServerMetrics {
-				if metrics.Online {
-					totalRx += metrics.Rx
-					totalTx += metrics.Tx
-
-					// Grand Total Avg = Sum of all servers' (AvgRx24h + AvgTx24h)
-					serverAvg := metrics.AvgRx24h + metrics.AvgTx24h
-					grandTotalAvg += serverAvg
-
-					// Grand Total Peak = Sum of all servers' MaxPeak (PeakRx or PeakTx)
-					// We take the max of Rx/Tx for peak capacity planning
-					serverPeak := metrics.PeakRx
-					if metrics.PeakTx > serverPeak {
-						serverPeak = metrics.PeakTx
-					}
-					grandTotalPeak += serverPeak
-
-					// Dominant Server (by daily average usage)
-					if serverAvg > maxUsage {
-						maxUsage = serverAvg
-						dominantServer = metrics.Name
-					}
-				}
+				servers = append(servers, metrics)
 			}
+			total := AggregateServers("", servers)
+			totalRx, totalTx := total.TotalRx, total.TotalTx
 
 			m.metrics.TotalRx = totalRx
 			m.metrics.TotalTx = totalTx
-			m.metrics.GrandTotalAvg = grandTotalAvg
-			m.metrics.GrandTotalPeak = grandTotalPeak
-			m.metrics.DominantServer = dominantServer
+			m.metrics.GrandTotalAvg = total.GrandTotalAvg
+			m.metrics.GrandTotalPeak = total.GrandTotalPeak
+			m.metrics.DominantServer = total.DominantServer
+			m.metrics.Groups = GroupServers(m.metrics.ServerMetrics, ByGroup)
 			m.metrics.UpdatedAt = time.Now()
 
 			// Add to history
@@ -424,10 +788,71 @@ func (m *Monitor) updateAggregate() {
 			m.metrics.History = append(m.metrics.History, entry)
 
 			m.mu.Unlock()
+
+			m.recordSample(store.AggregateSeries, entry.Timestamp, totalRx, totalTx)
+			m.evaluateAlerts()
+			m.notifyChanged()
 		}
 	}
 }
 
+// evaluateAlerts runs the alert rules against the current metrics
+func (m *Monitor) evaluateAlerts() {
+	if m.alerts == nil {
+		return
+	}
+
+	events := m.alerts.Evaluate(m.GetMetrics(), time.Now())
+	if len(events) == 0 {
+		return
+	}
+
+	m.mu.RLock()
+	hooks := m.alertHooks
+	m.mu.RUnlock()
+
+	for _, ev := range events {
+		target := ev.Server
+		if target == "" {
+			target = "aggregate"
+		}
+		log.Printf("Alert %s [%s] %s: %s %s %g (value %g)",
+			strings.ToUpper(string(ev.State)), ev.Rule, target, ev.Field, ev.Operator, ev.Threshold, ev.Value)
+
+		for _, hook := range hooks {
+			hook(ev)
+		}
+	}
+}
+
+// OnAlert registers a function called for every alert that starts firing or
+// resolves. Hooks run on the polling goroutine and must not block.
+func (m *Monitor) OnAlert(hook func(AlertEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertHooks = append(m.alertHooks, hook)
+}
+
+// GetAlerts returns all pending and firing alerts
+func (m *Monitor) GetAlerts() []Alert {
+	if m.alerts == nil {
+		return nil
+	}
+	return m.alerts.Active()
+}
+
+// AcknowledgeAlert marks a firing alert as acknowledged by user
+func (m *Monitor) AcknowledgeAlert(rule, server, user string) error {
+	if m.alerts == nil {
+		return fmt.Errorf("alert '%s' is not firing", rule)
+	}
+	if err := m.alerts.Acknowledge(rule, server, user, time.Now()); err != nil {
+		return err
+	}
+	m.notifyChanged()
+	return nil
+}
+
 // cleanHistory removes old history entries
 func (m *Monitor) cleanHistory() {
 	ticker := time.NewTicker(time.Minute)
@@ -447,6 +872,36 @@ func (m *Monitor) cleanHistory() {
 	}
 }
 
+// compactStore periodically drops history older than each tier's retention
+func (m *Monitor) compactStore() {
+	if m.store == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+			if err := m.store.Compact(); err != nil {
+				log.Printf("Failed to compact history store: %v", err)
+			}
+		}
+	}
+}
+
+// QueryHistory returns persisted rate samples of a server (or
+// store.AggregateSeries) between from and to, averaged into step-sized buckets
+func (m *Monitor) QueryHistory(series string, from, to time.Time, step time.Duration) ([]store.Sample, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("history store is not available")
+	}
+	return m.store.Query(series, from, to, step)
+}
+
 // GetMetrics returns current metrics
 func (m *Monitor) GetMetrics() *AggregateMetrics {
 	m.mu.RLock()
@@ -459,6 +914,8 @@ func (m *Monitor) GetMetrics() *AggregateMetrics {
 		GrandTotalAvg:  m.metrics.GrandTotalAvg,
 		GrandTotalPeak: m.metrics.GrandTotalPeak,
 		DominantServer: m.metrics.DominantServer,
+		Percentiles:    m.metrics.Percentiles,
+		Groups:         make(map[string]*GroupMetrics, len(m.metrics.Groups)),
 		ServerMetrics:  make(map[string]*ServerMetrics),
 		History:        make([]HistoryEntry, len(m.metrics.History)),
 		UpdatedAt:      m.metrics.UpdatedAt,
@@ -467,11 +924,26 @@ func (m *Monitor) GetMetrics() *AggregateMetrics {
 	for k, v := range m.metrics.ServerMetrics {
 		metricsCopy.ServerMetrics[k] = v
 	}
+	for k, v := range m.metrics.Groups {
+		metricsCopy.Groups[k] = v
+	}
 	copy(metricsCopy.History, m.metrics.History)
 
 	return metricsCopy
 }
 
+// GetPollStats returns a copy of the polling statistics of all servers
+func (m *Monitor) GetPollStats() map[string]PollStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make(map[string]PollStats, len(m.pollStats))
+	for k, v := range m.pollStats {
+		stats[k] = *v
+	}
+	return stats
+}
+
 // GetServerMetrics returns metrics for a specific server
 func (m *Monitor) GetServerMetrics(name string) *ServerMetrics {
 	m.mu.RLock()
@@ -483,8 +955,107 @@ func (m *Monitor) GetServerMetrics(name string) *ServerMetrics {
 	return nil
 }
 
-// RefreshServers updates the monitored servers list
+// RefreshServers applies the current configuration to a running monitor:
+// alert rules and the poll interval are updated, new servers start being
+// polled, changed servers are restarted, and removed servers are stopped and
+// their metrics evicted
 func (m *Monitor) RefreshServers() {
-	// The monitor reads from config.GetServers() which is always up to date
-	log.Println("Server list refreshed")
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	select {
+	case <-m.stopChan:
+		return
+	default:
+	}
+
+	if m.alerts != nil {
+		if err := m.alerts.SetRules(m.config.GetAlerts()); err != nil {
+			log.Printf("Keeping previous alert rules: %v", err)
+		}
+	}
+
+	restartAll := false
+	if seconds := m.config.GetSettings().PollInterval; seconds > 0 {
+		interval := time.Duration(seconds) * time.Second
+		m.mu.Lock()
+		if interval != m.pollInterval {
+			log.Printf("Poll interval changed from %s to %s", m.pollInterval, interval)
+			m.pollInterval = interval
+			m.historyLimit = historyLimitFor(interval)
+			restartAll = true
+		}
+		m.mu.Unlock()
+	}
+
+	m.reconcileServers(restartAll)
+}
+
+// reconcileServers starts, restarts and stops pollers so that exactly the
+// configured servers are polled. The caller must hold reconcileMu.
+func (m *Monitor) reconcileServers(restartAll bool) {
+	servers := m.config.GetServers()
+	configured := make(map[string]config.ServerConfig, len(servers))
+	for _, s := range servers {
+		configured[s.Name] = s
+	}
+
+	// Pooled connections are dropped after m.mu is released, as removing
+	// one waits for a dial in progress
+	var stale []config.ServerConfig
+	defer func() {
+		for _, s := range stale {
+			m.pool.Remove(s.IP, s.Port, s.User)
+		}
+	}()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, p := range m.pollers {
+		s, ok := configured[name]
+		if ok && !restartAll && reflect.DeepEqual(s, p.server) {
+			continue
+		}
+
+		close(p.stop)
+		delete(m.pollers, name)
+
+		// Drop the pooled connection if the server was removed or now
+		// lives at a different address
+		moved := !ok || s.IP != p.server.IP || s.Port != p.server.Port || s.User != p.server.User
+		if moved {
+			stale = append(stale, p.server)
+		}
+
+		// Counters read from another host or by another collector don't
+		// continue the old ones, so rates start over
+		if moved || s.GetCollector() != p.server.GetCollector() {
+			delete(m.rates, name)
+			m.forgetServer(name)
+		}
+
+		if !ok {
+			delete(m.metrics.ServerMetrics, name)
+			delete(m.pollStats, name)
+			delete(m.traffic, name)
+			delete(m.percentiles, name)
+			delete(m.anomalies, name)
+			delete(m.reachability, name)
+			delete(m.savedUptime, name)
+			go m.saveReachability()
+			m.notifyChanged()
+			log.Printf("Stopped polling removed server %s", name)
+		}
+	}
+
+	for _, s := range servers {
+		if _, ok := m.pollers[s.Name]; ok {
+			continue
+		}
+
+		p := &poller{server: s, stop: make(chan struct{}), poll: make(chan struct{}, 1)}
+		m.pollers[s.Name] = p
+		go m.monitorServer(p)
+	}
 }
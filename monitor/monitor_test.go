@@ -2,7 +2,10 @@ package monitor
 
 import (
 	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -231,3 +234,153 @@ func TestMetricCalculation(t *testing.T) {
 		t.Errorf("Top peak event Rx mismatch. Got %d, want 10", top.Rx)
 	}
 }
+
+// TestMultipleInterfaces verifies per-interface metrics and server totals
+func TestMultipleInterfaces(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	hourTs := now.Add(-time.Hour).Unix()
+	fiveTs := now.Add(-5 * time.Minute).Unix()
+
+	jsonData := fmt.Sprintf(`{
+	  "vnstatversion": "2.12",
+	  "interfaces": [
+	    { "name": "eth0", "traffic": {
+	        "fiveminute": [{ "timestamp": %[1]d, "rx": 30000, "tx": 60000 }],
+	        "hour": [{ "timestamp": %[2]d, "rx": 36000, "tx": 72000 }],
+	        "day": [{ "timestamp": %[2]d, "rx": 1000, "tx": 2000 }] } },
+	    { "name": "lo", "traffic": {
+	        "fiveminute": [{ "timestamp": %[1]d, "rx": 999999, "tx": 999999 }] } },
+	    { "name": "wg0", "traffic": {
+	        "fiveminute": [{ "timestamp": %[1]d, "rx": 3000, "tx": 6000 }],
+	        "hour": [{ "timestamp": %[2]d, "rx": 3600, "tx": 7200 }],
+	        "day": [{ "timestamp": %[2]d, "rx": 100, "tx": 200 }] } }
+	  ]
+	}`, fiveTs, hourTs)
+
+	var data VnStatData
+	if err := json.Unmarshal([]byte(jsonData), &data); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	m := &Monitor{}
+	server := config.ServerConfig{Name: "web1", Interfaces: []string{"wg0", "eth0", "tun9"}}
+	metrics := m.processVnStatData(server, &data)
+
+	if len(metrics.Interfaces) != 2 {
+		t.Fatalf("Expected 2 interfaces, got %d", len(metrics.Interfaces))
+	}
+	if metrics.Interfaces[0].Name != "wg0" || metrics.Interfaces[1].Name != "eth0" {
+		t.Errorf("Interface order mismatch. Got %s, %s", metrics.Interfaces[0].Name, metrics.Interfaces[1].Name)
+	}
+	if metrics.Interfaces[1].Rx != 100 || metrics.Interfaces[0].Rx != 10 {
+		t.Errorf("Per-interface Rx mismatch. Got eth0=%d wg0=%d", metrics.Interfaces[1].Rx, metrics.Interfaces[0].Rx)
+	}
+
+	// Server totals are the sum of the selected interfaces (lo is ignored)
+	if metrics.Rx != 110 || metrics.Tx != 220 {
+		t.Errorf("Server rate mismatch. Got %d/%d, want 110/220", metrics.Rx, metrics.Tx)
+	}
+	if metrics.TotalRx != 1100 || metrics.TotalTx != 2200 {
+		t.Errorf("Server today mismatch. Got %d/%d, want 1100/2200", metrics.TotalRx, metrics.TotalTx)
+	}
+	if metrics.PeakRx != 11 {
+		t.Errorf("Server peak mismatch. Got %d, want 11", metrics.PeakRx)
+	}
+	if metrics.Interface != "wg0,eth0,tun9" {
+		t.Errorf("Interface label mismatch. Got %s", metrics.Interface)
+	}
+	if !strings.Contains(metrics.Error, "tun9") {
+		t.Errorf("Expected missing interface error, got %q", metrics.Error)
+	}
+}
+
+// TestRefreshServers verifies that pollers follow config changes
+func TestRefreshServers(t *testing.T) {
+	cfg := &config.Config{
+		Settings: config.SettingsConfig{PollInterval: 3600},
+		Servers: []config.ServerConfig{
+			{Name: "a", IP: "10.0.0.1", Port: 22, User: "root"},
+			{Name: "b", IP: "10.0.0.2", Port: 22, User: "root"},
+		},
+	}
+
+	m := &Monitor{
+		config: cfg,
+		pool:   sshclient.NewPool(nil),
+		metrics: &AggregateMetrics{
+			ServerMetrics: make(map[string]*ServerMetrics),
+		},
+		pollStats:    make(map[string]*PollStats),
+		pollers:      make(map[string]*poller),
+		stopChan:     make(chan struct{}),
+		pollInterval: time.Hour,
+		historyLimit: 1,
+	}
+	defer m.Stop()
+
+	m.reconcileServers(false)
+	if len(m.pollers) != 2 {
+		t.Fatalf("Expected 2 pollers, got %d", len(m.pollers))
+	}
+	m.setServerMetrics("b", &ServerMetrics{Name: "b", Online: true})
+	m.recordPoll("b", time.Second, false)
+	pollerA := m.pollers["a"]
+
+	// Remove b, add c
+	cfg.Servers = []config.ServerConfig{
+		{Name: "a", IP: "10.0.0.1", Port: 22, User: "root"},
+		{Name: "c", IP: "10.0.0.3", Port: 22, User: "root"},
+	}
+	m.RefreshServers()
+
+	if _, ok := m.pollers["b"]; ok {
+		t.Error("Poller for removed server b still running")
+	}
+	if _, ok := m.pollers["c"]; !ok {
+		t.Error("Poller for new server c not started")
+	}
+	if m.pollers["a"] != pollerA {
+		t.Error("Unchanged server a should keep its poller")
+	}
+	if _, ok := m.GetMetrics().ServerMetrics["b"]; ok {
+		t.Error("Metrics of removed server b not evicted")
+	}
+	if _, ok := m.GetPollStats()["b"]; ok {
+		t.Error("Poll stats of removed server b not evicted")
+	}
+
+	// A poll finishing after removal must not resurrect the server
+	m.setServerMetrics("b", &ServerMetrics{Name: "b"})
+	if _, ok := m.GetMetrics().ServerMetrics["b"]; ok {
+		t.Error("Late poll result resurrected removed server b")
+	}
+	if m.rateTrackerFor("b") != nil || m.anomalyDetectorFor("b") != nil || m.reachabilityFor("b") != nil {
+		t.Error("Late poll recreated state of removed server b")
+	}
+
+	// Changing the poll interval restarts every poller
+	cfg.Settings.PollInterval = 1800
+	m.RefreshServers()
+	if m.getPollInterval() != 30*time.Minute {
+		t.Errorf("Poll interval mismatch. Got %s, want 30m", m.getPollInterval())
+	}
+	if m.pollers["a"] == pollerA {
+		t.Error("Poller for a should restart after an interval change")
+	}
+	select {
+	case <-pollerA.stop:
+	default:
+		t.Error("Old poller for a was not stopped")
+	}
+
+	// Moving a server to another host forgets the counters of the old one
+	m.rateTrackerFor("c")
+	cfg.Servers[1].IP = "10.0.0.4"
+	m.RefreshServers()
+	m.mu.RLock()
+	_, ok := m.rates["c"]
+	m.mu.RUnlock()
+	if ok {
+		t.Error("Rate tracker of moved server c not reset")
+	}
+}
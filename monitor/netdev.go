@@ -0,0 +1,353 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long buckets built from /proc/net/dev samples are kept. They only
+// live in memory and start empty when the service starts.
+const (
+	netDevFiveMinuteRetention = 48 * time.Hour
+	netDevHourRetention       = 72 * time.Hour
+	netDevDayRetention        = 62 * 24 * time.Hour
+	netDevMonthRetention      = 2 * 365 * 24 * time.Hour
+
+	// netDevMaxSpread caps how far back traffic counted after a reboot or a
+	// long outage is spread
+	netDevMaxSpread = 31 * 24 * time.Hour
+)
+
+// netDevSample is one reading of /proc/uptime and /proc/net/dev
+type netDevSample struct {
+	Uptime   float64 // Seconds since boot
+	Names    []string
+	Counters map[string]ByteCounters
+}
+
+// parseNetDev parses the output of "cat /proc/uptime /proc/net/dev"
+func parseNetDev(output string) (*netDevSample, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty /proc/net/dev output")
+	}
+
+	uptimeFields := strings.Fields(lines[0])
+	if len(uptimeFields) == 0 {
+		return nil, fmt.Errorf("missing /proc/uptime")
+	}
+	uptime, err := strconv.ParseFloat(uptimeFields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid /proc/uptime: %s", lines[0])
+	}
+
+	sample := &netDevSample{Uptime: uptime, Counters: make(map[string]ByteCounters)}
+	for _, line := range lines[1:] {
+		name, stats, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(name, "|") {
+			continue // Header lines
+		}
+		name = strings.TrimSpace(name)
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("invalid /proc/net/dev line for %s", name)
+		}
+		rx, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rx counter for %s: %w", name, err)
+		}
+		tx, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tx counter for %s: %w", name, err)
+		}
+		sample.Names = append(sample.Names, name)
+		sample.Counters[name] = ByteCounters{Rx: rx, Tx: tx}
+	}
+	if len(sample.Names) == 0 {
+		return nil, fmt.Errorf("no interfaces in /proc/net/dev")
+	}
+	return sample, nil
+}
+
+// counterSample returns the counters of a sample read at the given time
+func (s *netDevSample) counterSample(at time.Time) *CounterSample {
+	return &CounterSample{At: at, Uptime: s.Uptime, Counters: s.Counters}
+}
+
+// netDevTracker turns consecutive /proc/net/dev samples of a server into
+// vnStat-style traffic buckets
+type netDevTracker struct {
+	mu     sync.Mutex
+	prev   *netDevSample
+	prevAt time.Time
+	ifaces map[string]*netDevInterface
+}
+
+// netDevInterface holds the buckets of one interface
+type netDevInterface struct {
+	created time.Time
+	buckets [4]map[int64]*TrafficBucket
+}
+
+func newNetDevTracker() *netDevTracker {
+	return &netDevTracker{ifaces: make(map[string]*netDevInterface)}
+}
+
+// add records a sample taken at now. The interval between samples is taken
+// from the host's uptime so that SSH latency does not skew the buckets; an
+// uptime going backwards means the host rebooted and its counters restarted
+// from zero.
+func (t *netDevTracker) add(sample *netDevSample, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev := t.prev
+	t.prev, t.prevAt = sample, now
+	for _, name := range sample.Names {
+		if _, ok := t.ifaces[name]; !ok {
+			t.ifaces[name] = &netDevInterface{created: now}
+		}
+	}
+	if prev == nil {
+		return
+	}
+
+	rebooted := sample.Uptime < prev.Uptime
+	elapsed := sample.Uptime - prev.Uptime
+	if rebooted {
+		elapsed = sample.Uptime
+	}
+	if elapsed <= 0 {
+		return
+	}
+	span := time.Duration(elapsed * float64(time.Second))
+	if span > netDevMaxSpread {
+		span = netDevMaxSpread
+	}
+
+	for _, name := range sample.Names {
+		cur := sample.Counters[name]
+		last, seen := prev.Counters[name]
+		if !seen && !rebooted {
+			continue // New interface: this sample is its baseline
+		}
+
+		rx := counterDelta(last.Rx, cur.Rx)
+		tx := counterDelta(last.Tx, cur.Tx)
+		if rebooted {
+			rx, tx = cur.Rx, cur.Tx
+		}
+
+		iface := t.ifaces[name]
+		iface.addTraffic(now.Add(-span), now, rx, tx)
+		iface.trim(now)
+	}
+}
+
+// addTraffic spreads bytes transferred between start and end over the
+// five-minute intervals they cover and adds them to every bucket tier
+func (i *netDevInterface) addTraffic(start, end time.Time, rx, tx uint64) {
+	total := end.Sub(start)
+	var doneRx, doneTx uint64
+	for t := start; t.Before(end); {
+		next := t.Truncate(fiveMinuteResolution).Add(fiveMinuteResolution)
+		if !next.Before(end) {
+			next = end
+		}
+
+		pieceRx, pieceTx := rx-doneRx, tx-doneTx // The last piece takes the remainder
+		if next.Before(end) {
+			frac := float64(next.Sub(t)) / float64(total)
+			pieceRx = uint64(float64(rx) * frac)
+			pieceTx = uint64(float64(tx) * frac)
+		}
+		doneRx += pieceRx
+		doneTx += pieceTx
+
+		local := t.Local()
+		starts := [4]time.Time{
+			t.Truncate(fiveMinuteResolution),
+			time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), 0, 0, 0, local.Location()),
+			time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location()),
+			time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location()),
+		}
+		for tier, s := range starts {
+			if i.buckets[tier] == nil {
+				i.buckets[tier] = make(map[int64]*TrafficBucket)
+			}
+			b, ok := i.buckets[tier][s.Unix()]
+			if !ok {
+				b = newTrafficBucket(s)
+				i.buckets[tier][s.Unix()] = b
+			}
+			b.Rx += pieceRx
+			b.Tx += pieceTx
+		}
+		t = next
+	}
+}
+
+// trim drops buckets older than their tier's retention
+func (i *netDevInterface) trim(now time.Time) {
+	retention := [4]time.Duration{netDevFiveMinuteRetention, netDevHourRetention, netDevDayRetention, netDevMonthRetention}
+	for tier, buckets := range i.buckets {
+		cutoff := now.Add(-retention[tier]).Unix()
+		for ts := range buckets {
+			if ts < cutoff {
+				delete(buckets, ts)
+			}
+		}
+	}
+}
+
+// newTrafficBucket returns an empty bucket starting at start, with the
+// calendar fields vnStat would report in the monitor's time zone
+func newTrafficBucket(start time.Time) *TrafficBucket {
+	local := start.Local()
+	b := &TrafficBucket{Timestamp: start.Unix()}
+	b.Date.Year = local.Year()
+	b.Date.Month = int(local.Month())
+	b.Date.Day = local.Day()
+	b.Time.Hour = local.Hour()
+	b.Time.Minute = local.Minute()
+	return b
+}
+
+// tier returns the buckets of a tier oldest first
+func (i *netDevInterface) tier(n int) []TrafficBucket {
+	list := make([]TrafficBucket, 0, len(i.buckets[n]))
+	for _, b := range i.buckets[n] {
+		list = append(list, *b)
+	}
+	return sortedBuckets(list)
+}
+
+// vnStatData returns the tracked traffic in the form vnStat reports it.
+// Without configured interfaces every interface except loopback is included.
+func (t *netDevTracker) vnStatData(wanted []string) *VnStatData {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := wanted
+	if len(names) == 0 && t.prev != nil {
+		for _, name := range t.prev.Names {
+			if name != "lo" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	data := &VnStatData{}
+	for _, name := range names {
+		iface, ok := t.ifaces[name]
+		if !ok || t.prev == nil {
+			continue
+		}
+		if _, ok := t.prev.Counters[name]; !ok {
+			continue // Interface disappeared
+		}
+
+		n := len(data.Interfaces)
+		data.Interfaces = slices.Grow(data.Interfaces, 1)[:n+1]
+		entry := &data.Interfaces[n]
+		entry.Name = name
+		entry.Created.Timestamp = iface.created.Unix()
+		entry.Updated.Timestamp = t.prevAt.Unix()
+		entry.Traffic.Total.Rx = t.prev.Counters[name].Rx
+		entry.Traffic.Total.Tx = t.prev.Counters[name].Tx
+		entry.Traffic.FiveMinute = iface.tier(0)
+		entry.Traffic.Hour = iface.tier(1)
+		entry.Traffic.Day = iface.tier(2)
+		entry.Traffic.Month = iface.tier(3)
+	}
+	return data
+}
+
+// netDevReader returns the output of "cat /proc/uptime /proc/net/dev" on a server
+type netDevReader func(server config.ServerConfig) (string, error)
+
+// netDevCollector samples /proc/net/dev and keeps a tracker per server
+type netDevCollector struct {
+	read netDevReader
+	now  func() time.Time
+
+	mu       sync.Mutex
+	trackers map[string]*netDevTracker
+}
+
+func newNetDevCollector(read netDevReader) *netDevCollector {
+	return &netDevCollector{read: read, now: time.Now, trackers: make(map[string]*netDevTracker)}
+}
+
+func (c *netDevCollector) Collect(server config.ServerConfig) (*Traffic, error) {
+	output, err := c.read(server)
+	if err != nil {
+		return nil, err
+	}
+
+	sample, err := parseNetDev(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse /proc/net/dev: %w", err)
+	}
+
+	now := c.now()
+	tracker := c.tracker(server.Name)
+	tracker.add(sample, now)
+	return &Traffic{Data: tracker.vnStatData(server.GetInterfaces()), Counters: sample.counterSample(now)}, nil
+}
+
+// tracker returns the tracker of a server, creating it if needed
+func (c *netDevCollector) tracker(name string) *netDevTracker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.trackers[name]
+	if !ok {
+		t = newNetDevTracker()
+		c.trackers[name] = t
+	}
+	return t
+}
+
+// Forget drops the tracker of a removed or moved server
+func (c *netDevCollector) Forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.trackers, name)
+}
+
+// sshNetDevReader reads /proc/net/dev over a pooled SSH connection
+func sshNetDevReader(pool *sshclient.Pool) netDevReader {
+	return func(server config.ServerConfig) (string, error) {
+		client, err := pool.Get(server.IP, server.Port, server.User)
+		if err != nil {
+			return "", Unreachable(err)
+		}
+		output, err := client.ReadNetDev()
+		if err != nil {
+			pool.Invalidate(server.IP, server.Port, server.User)
+			return "", Unreachable(err)
+		}
+		return output, nil
+	}
+}
+
+// readLocalNetDev reads /proc/net/dev of the host running the monitor
+func readLocalNetDev(config.ServerConfig) (string, error) {
+	var output []byte
+	for _, path := range []string{"/proc/uptime", "/proc/net/dev"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		output = append(output, data...)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// netDevOutput builds the output of "cat /proc/uptime /proc/net/dev"
+func netDevOutput(uptime float64, eth0Rx, eth0Tx uint64) string {
+	return fmt.Sprintf(`%.2f 1234.56
+Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0: %d  1000    0    0    0     0          0         0 %d   900    0    0    0     0       0          0
+`, uptime, eth0Rx, eth0Tx)
+}
+
+func TestParseNetDev(t *testing.T) {
+	sample, err := parseNetDev(netDevOutput(350.5, 123456, 654321))
+	if err != nil {
+		t.Fatalf("parseNetDev failed: %v", err)
+	}
+	if sample.Uptime != 350.5 {
+		t.Errorf("Uptime mismatch. Got %g, want 350.5", sample.Uptime)
+	}
+	if len(sample.Names) != 2 || sample.Names[1] != "eth0" {
+		t.Errorf("Unexpected interfaces: %v", sample.Names)
+	}
+	if c := sample.Counters["eth0"]; c.Rx != 123456 || c.Tx != 654321 {
+		t.Errorf("eth0 counters mismatch. Got rx=%d tx=%d", c.Rx, c.Tx)
+	}
+
+	if _, err := parseNetDev("not a number\n"); err == nil {
+		t.Errorf("Expected error for invalid uptime")
+	}
+}
+
+func TestNetDevTracker(t *testing.T) {
+	m := &Monitor{pollers: map[string]*poller{"alpine": {}}}
+	c := newNetDevCollector(nil)
+	server := config.ServerConfig{Name: "alpine", Collector: config.CollectorProcNetDev}
+	start := time.Now().Truncate(time.Hour)
+
+	add := func(offset time.Duration, uptime float64, rx, tx uint64) *ServerMetrics {
+		c.read = func(config.ServerConfig) (string, error) {
+			return netDevOutput(uptime, rx, tx), nil
+		}
+		c.now = func() time.Time { return start.Add(offset) }
+		traffic, err := c.Collect(server)
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		return m.processTraffic(server, traffic)
+	}
+
+	// The first sample is only a baseline
+	metrics := add(0, 1000, 10000, 20000)
+	if !metrics.Online || metrics.Rx != 0 || metrics.TotalRx != 0 {
+		t.Errorf("Unexpected metrics after first sample: online=%v rx=%d total=%d", metrics.Online, metrics.Rx, metrics.TotalRx)
+	}
+	if metrics.Interface != "eth0" {
+		t.Errorf("Loopback should be skipped. Got %s", metrics.Interface)
+	}
+
+	// 10 seconds later by the host's clock, even though the poll took longer
+	metrics = add(12*time.Second, 1010, 15000, 30000)
+	if metrics.Rx != 500 || metrics.Tx != 1000 {
+		t.Errorf("Rate mismatch. Got rx=%d tx=%d, want 500 and 1000", metrics.Rx, metrics.Tx)
+	}
+	if metrics.TotalRx != 5000 || metrics.TotalTx != 10000 {
+		t.Errorf("Daily total mismatch. Got rx=%d tx=%d", metrics.TotalRx, metrics.TotalTx)
+	}
+
+	// Reboot: counters restart, traffic since boot is counted and the
+	// smoothed rate moves toward 100/200 bytes per second
+	metrics = add(60*time.Second, 20, 2000, 4000)
+	if metrics.Rx <= 100 || metrics.Rx >= 500 || metrics.Tx <= 200 || metrics.Tx >= 1000 {
+		t.Errorf("Rate after reboot mismatch. Got rx=%d tx=%d", metrics.Rx, metrics.Tx)
+	}
+	if metrics.TotalRx != 7000 || metrics.TotalTx != 14000 {
+		t.Errorf("Daily total after reboot mismatch. Got rx=%d tx=%d", metrics.TotalRx, metrics.TotalTx)
+	}
+
+	// Traffic is split across five-minute buckets, keeping the sum
+	metrics = add(11*time.Minute, 620, 62000, 4000)
+	var fiveMinuteRx uint64
+	for _, b := range metrics.traffic.FiveMinute {
+		fiveMinuteRx += b.Rx
+	}
+	if fiveMinuteRx != 67000 || len(metrics.traffic.FiveMinute) != 3 {
+		t.Errorf("Five-minute buckets mismatch. Got %d buckets with %d bytes", len(metrics.traffic.FiveMinute), fiveMinuteRx)
+	}
+
+	// A configured interface that does not exist is reported
+	server.Interfaces = []string{"eth0", "wg0"}
+	metrics = add(12*time.Minute, 680, 62000, 4000)
+	if metrics.Error == "" || len(metrics.Interfaces) != 1 {
+		t.Errorf("Expected missing interface error, got %q with %d interfaces", metrics.Error, len(metrics.Interfaces))
+	}
+
+	// Removing the server drops its counters
+	c.Forget(server.Name)
+	if len(c.trackers) != 0 {
+		t.Errorf("Tracker of removed server not dropped")
+	}
+}
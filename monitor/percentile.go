@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/store"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// percentileResolution is the sampling interval burstable billing uses
+	percentileResolution = 5 * time.Minute
+
+	// percentileRefreshInterval is how often the configured window is recomputed
+	percentileRefreshInterval = 5 * time.Minute
+)
+
+// PercentileUsage holds burstable-billing percentiles of the five-minute
+// average rates in a billing window, in bytes per second
+type PercentileUsage struct {
+	Window     string // e.g. "month" or "30d"
+	From       time.Time
+	To         time.Time
+	Samples    int // Five-minute samples in the window
+	Rx95       uint64
+	Tx95       uint64
+	Rx99       uint64
+	Tx99       uint64
+	Billable95 uint64 // The greater of Rx95 and Tx95, as transit is usually billed
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted values:
+// the top (100-p)% of samples are discarded and the highest remaining one
+// is returned
+func percentile(sorted []uint64, p float64) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// billingRange returns the start of the window that ends at now. Billing
+// months start at local midnight of the reset day, clamped to the end of
+// short months.
+func billingRange(w config.BillingWindow, now time.Time) time.Time {
+	if w.Days > 0 {
+		return now.AddDate(0, 0, -w.Days)
+	}
+	local := now.Local()
+	start, _ := billingCycle(newCivilDate(local.Year(), local.Month(), local.Day()), w.ResetDay)
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// computePercentiles summarizes five-minute samples
+func computePercentiles(samples []store.Sample, window string, from, to time.Time) *PercentileUsage {
+	rx := make([]uint64, len(samples))
+	tx := make([]uint64, len(samples))
+	for i, s := range samples {
+		rx[i], tx[i] = s.Rx, s.Tx
+	}
+	slices.Sort(rx)
+	slices.Sort(tx)
+
+	usage := &PercentileUsage{
+		Window:  window,
+		From:    from,
+		To:      to,
+		Samples: len(samples),
+		Rx95:    percentile(rx, 95),
+		Tx95:    percentile(tx, 95),
+		Rx99:    percentile(rx, 99),
+		Tx99:    percentile(tx, 99),
+	}
+	usage.Billable95 = max(usage.Rx95, usage.Tx95)
+	return usage
+}
+
+// Percentiles computes the 95th and 99th percentiles of a server's (or
+// store.AggregateSeries') five-minute average rates over the window ending now
+func (m *Monitor) Percentiles(series string, w config.BillingWindow, now time.Time) (*PercentileUsage, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("history store is not available")
+	}
+
+	from := billingRange(w, now)
+	samples, err := m.store.Query(series, from, now, percentileResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	return computePercentiles(samples, w.String(), from, now), nil
+}
+
+// refreshPercentiles recomputes the configured window for every server and
+// the aggregate and attaches the results to the current metrics
+func (m *Monitor) refreshPercentiles() {
+	if m.store == nil {
+		return
+	}
+
+	window := m.config.GetSettings().GetBillingWindow()
+	now := time.Now()
+
+	m.mu.RLock()
+	names := make([]string, 0, len(m.pollers))
+	for name := range m.pollers {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+
+	results := make(map[string]*PercentileUsage, len(names))
+	for _, name := range names {
+		usage, err := m.Percentiles(name, window, now)
+		if err != nil {
+			log.Printf("Failed to compute percentiles for %s: %v", name, err)
+			continue
+		}
+		results[name] = usage
+	}
+	aggregate, err := m.Percentiles(store.AggregateSeries, window, now)
+	if err != nil {
+		log.Printf("Failed to compute aggregate percentiles: %v", err)
+	}
+
+	m.mu.Lock()
+	m.percentiles = results
+	if aggregate != nil {
+		m.metrics.Percentiles = aggregate
+	}
+	// Metrics are shared with readers, so servers get updated copies
+	for name, sm := range m.metrics.ServerMetrics {
+		if usage, ok := results[name]; ok {
+			updated := *sm
+			updated.Percentiles = usage
+			m.metrics.ServerMetrics[name] = &updated
+		}
+	}
+	m.mu.Unlock()
+
+	m.notifyChanged()
+}
+
+// updatePercentiles periodically refreshes the billing percentiles
+func (m *Monitor) updatePercentiles() {
+	m.refreshPercentiles()
+
+	ticker := time.NewTicker(percentileRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+			m.refreshPercentiles()
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/store"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := make([]uint64, 100)
+	for i := range values {
+		values[i] = uint64(i + 1)
+	}
+	if got := percentile(values, 95); got != 95 {
+		t.Errorf("95th percentile of 1..100 mismatch. Got %d, want 95", got)
+	}
+	if got := percentile(values, 99); got != 99 {
+		t.Errorf("99th percentile of 1..100 mismatch. Got %d, want 99", got)
+	}
+
+	// With 10 samples the single highest one is discarded
+	if got := percentile(values[:10], 95); got != 10 {
+		t.Errorf("95th percentile of 1..10 mismatch. Got %d, want 10", got)
+	}
+	if got := percentile(values[:21], 95); got != 20 {
+		t.Errorf("95th percentile of 1..21 mismatch. Got %d, want 20", got)
+	}
+	if got := percentile(nil, 95); got != 0 {
+		t.Errorf("Percentile of no samples should be 0, got %d", got)
+	}
+}
+
+func TestBillingRange(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+
+	cases := []struct {
+		window config.BillingWindow
+		want   time.Time
+	}{
+		{config.BillingWindow{ResetDay: 1}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)},
+		{config.BillingWindow{ResetDay: 15}, time.Date(2026, 2, 15, 0, 0, 0, 0, time.Local)},
+		{config.BillingWindow{ResetDay: 31}, time.Date(2026, 2, 28, 0, 0, 0, 0, time.Local)},
+		{config.BillingWindow{Days: 30}, now.AddDate(0, 0, -30)},
+	}
+	for _, c := range cases {
+		if got := billingRange(c.window, now); !got.Equal(c.want) {
+			t.Errorf("billingRange(%+v) = %s, want %s", c.window, got, c.want)
+		}
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	historyStore, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	// 100 five-minute intervals with increasing inbound traffic
+	now := time.Now()
+	start := now.Add(-100 * percentileResolution).Truncate(percentileResolution)
+	for i := 0; i < 100; i++ {
+		at := start.Add(time.Duration(i) * percentileResolution)
+		for _, series := range []string{"web", store.AggregateSeries} {
+			if err := historyStore.Append(series, store.Sample{Timestamp: at.Unix(), Rx: uint64(i+1) * 10, Tx: 500}); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+		}
+	}
+
+	cfg := &config.Config{Settings: config.SettingsConfig{BillingWindow: "1d"}}
+	m := &Monitor{
+		config:    cfg,
+		store:     historyStore,
+		pollers:   map[string]*poller{"web": {}},
+		metrics:   &AggregateMetrics{ServerMetrics: map[string]*ServerMetrics{"web": {Name: "web"}}},
+		pollStats: make(map[string]*PollStats),
+	}
+
+	usage, err := m.Percentiles("web", cfg.Settings.GetBillingWindow(), now)
+	if err != nil {
+		t.Fatalf("Percentiles failed: %v", err)
+	}
+	if usage.Samples != 100 || usage.Rx95 != 950 || usage.Rx99 != 990 || usage.Tx95 != 500 {
+		t.Errorf("Unexpected percentiles: %+v", usage)
+	}
+	if usage.Billable95 != 950 || usage.Window != "1d" {
+		t.Errorf("Billable or window mismatch: %+v", usage)
+	}
+
+	// The configured window is attached to server and aggregate metrics
+	m.refreshPercentiles()
+	metrics := m.GetMetrics()
+	if p := metrics.ServerMetrics["web"].Percentiles; p == nil || p.Rx95 != 950 {
+		t.Errorf("Server percentiles not attached: %+v", p)
+	}
+	if p := metrics.Percentiles; p == nil || p.Tx99 != 500 {
+		t.Errorf("Aggregate percentiles not attached: %+v", p)
+	}
+
+	// New poll results keep them until the next refresh
+	m.setServerMetrics("web", &ServerMetrics{Name: "web", Online: true})
+	if m.GetServerMetrics("web").Percentiles == nil {
+		t.Error("Percentiles lost after a poll")
+	}
+}
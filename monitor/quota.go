@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"time"
+)
+
+const bytesPerGB = 1024 * 1024 * 1024
+
+// QuotaUsage is the billing-cycle-to-date usage of a server quota
+type QuotaUsage struct {
+	Direction        string
+	LimitBytes       uint64
+	UsedBytes        uint64
+	Percent          float64
+	ProjectedBytes   uint64 // Expected usage at the end of the cycle at the current pace
+	ProjectedPercent float64
+	CycleStart       time.Time
+	CycleEnd         time.Time
+}
+
+// civilDate is a calendar date in the server's local time zone, stored as
+// midnight UTC so that date arithmetic is not affected by DST
+type civilDate struct {
+	time.Time
+}
+
+func newCivilDate(year int, month time.Month, day int) civilDate {
+	return civilDate{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func bucketDate(b TrafficBucket) civilDate {
+	return newCivilDate(b.Date.Year, time.Month(b.Date.Month), b.Date.Day)
+}
+
+// daysUntil returns the number of calendar days from d to other
+func (d civilDate) daysUntil(other civilDate) int {
+	return int(other.Sub(d.Time).Hours() / 24)
+}
+
+// daysIn returns the number of days in the given month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// cycleStartDay returns the start of the billing cycle in the given month,
+// clamping reset days past the end of short months to their last day
+func cycleStartDay(year int, month time.Month, resetDay int) civilDate {
+	t := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	day := resetDay
+	if last := daysIn(t.Year(), t.Month()); day > last {
+		day = last
+	}
+	return newCivilDate(t.Year(), t.Month(), day)
+}
+
+// billingCycle returns the start of the cycle containing today and the
+// start of the next cycle
+func billingCycle(today civilDate, resetDay int) (civilDate, civilDate) {
+	if resetDay < 1 {
+		resetDay = 1
+	}
+
+	start := cycleStartDay(today.Year(), today.Month(), resetDay)
+	if today.Before(start.Time) {
+		start = cycleStartDay(today.Year(), today.Month()-1, resetDay)
+	}
+	next := cycleStartDay(start.Year(), start.Month()+1, resetDay)
+	return start, next
+}
+
+// quotaBytes returns the bytes of a bucket counted by the quota direction
+func quotaBytes(direction string, b TrafficBucket) uint64 {
+	switch direction {
+	case config.QuotaRx:
+		return b.Rx
+	case config.QuotaTx:
+		return b.Tx
+	default:
+		return b.Rx + b.Tx
+	}
+}
+
+// computeQuota calculates cycle-to-date usage from vnStat day and month
+// totals. Days are summed from the cycle start; when the cycle starts on the
+// 1st, the month total is used instead so that usage stays correct even if
+// vnStat keeps fewer days than a month.
+func computeQuota(q config.QuotaConfig, days, months []TrafficBucket, now time.Time) *QuotaUsage {
+	if len(days) == 0 || q.LimitGB <= 0 {
+		return nil
+	}
+
+	direction := q.Direction
+	if direction == "" {
+		direction = config.QuotaBoth
+	}
+
+	// The newest day bucket is "today" in the server's time zone
+	latest := days[0]
+	for _, d := range days {
+		if d.Timestamp > latest.Timestamp {
+			latest = d
+		}
+	}
+	today := bucketDate(latest)
+	start, next := billingCycle(today, q.ResetDay)
+
+	var used uint64
+	usedMonth := false
+	if start.Day() == 1 {
+		for _, m := range months {
+			if m.Date.Year == start.Year() && time.Month(m.Date.Month) == start.Month() {
+				used = quotaBytes(direction, m)
+				usedMonth = true
+				break
+			}
+		}
+	}
+	if !usedMonth {
+		for _, d := range days {
+			date := bucketDate(d)
+			if !date.Before(start.Time) && !date.After(today.Time) {
+				used += quotaBytes(direction, d)
+			}
+		}
+	}
+
+	// Day bucket timestamps are local midnight, so the cycle boundaries in
+	// absolute time are offset from today's bucket by whole days
+	cycleStart := time.Unix(latest.Timestamp, 0).AddDate(0, 0, -start.daysUntil(today))
+	cycleEnd := cycleStart.AddDate(0, 0, start.daysUntil(next))
+
+	usage := &QuotaUsage{
+		Direction:  direction,
+		LimitBytes: uint64(q.LimitGB * bytesPerGB),
+		UsedBytes:  used,
+		CycleStart: cycleStart,
+		CycleEnd:   cycleEnd,
+	}
+
+	// Projections from the first hour of a cycle are too noisy to be useful
+	usage.ProjectedBytes = used
+	elapsed := now.Sub(cycleStart)
+	length := cycleEnd.Sub(cycleStart)
+	if elapsed >= time.Hour && elapsed < length {
+		usage.ProjectedBytes = uint64(float64(used) * float64(length) / float64(elapsed))
+	}
+
+	if usage.LimitBytes > 0 {
+		usage.Percent = float64(usage.UsedBytes) / float64(usage.LimitBytes) * 100
+		usage.ProjectedPercent = float64(usage.ProjectedBytes) / float64(usage.LimitBytes) * 100
+	}
+
+	return usage
+}
@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"testing"
+	"time"
+)
+
+func dayBucket(year int, month time.Month, day int, rx, tx uint64) TrafficBucket {
+	var b TrafficBucket
+	b.Timestamp = time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+	b.Date.Year = year
+	b.Date.Month = int(month)
+	b.Date.Day = day
+	b.Rx = rx
+	b.Tx = tx
+	return b
+}
+
+func TestBillingCycle(t *testing.T) {
+	cases := []struct {
+		today     civilDate
+		resetDay  int
+		wantStart civilDate
+		wantNext  civilDate
+	}{
+		{newCivilDate(2026, 3, 20), 15, newCivilDate(2026, 3, 15), newCivilDate(2026, 4, 15)},
+		{newCivilDate(2026, 3, 10), 15, newCivilDate(2026, 2, 15), newCivilDate(2026, 3, 15)},
+		{newCivilDate(2026, 1, 5), 15, newCivilDate(2025, 12, 15), newCivilDate(2026, 1, 15)},
+		{newCivilDate(2026, 2, 28), 31, newCivilDate(2026, 2, 28), newCivilDate(2026, 3, 31)},
+		{newCivilDate(2026, 3, 30), 31, newCivilDate(2026, 2, 28), newCivilDate(2026, 3, 31)},
+		{newCivilDate(2026, 3, 1), 0, newCivilDate(2026, 3, 1), newCivilDate(2026, 4, 1)},
+	}
+
+	for _, c := range cases {
+		start, next := billingCycle(c.today, c.resetDay)
+		if !start.Equal(c.wantStart.Time) || !next.Equal(c.wantNext.Time) {
+			t.Errorf("billingCycle(%s, %d) = %s..%s, want %s..%s",
+				c.today.Format("2006-01-02"), c.resetDay,
+				start.Format("2006-01-02"), next.Format("2006-01-02"),
+				c.wantStart.Format("2006-01-02"), c.wantNext.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestComputeQuotaFromDays(t *testing.T) {
+	const gb = bytesPerGB
+	days := []TrafficBucket{
+		dayBucket(2026, 3, 9, 100*gb, 100*gb), // Previous cycle
+		dayBucket(2026, 3, 10, 2*gb, 1*gb),
+		dayBucket(2026, 3, 11, 4*gb, 2*gb),
+		dayBucket(2026, 3, 12, 3*gb, 3*gb),
+	}
+	q := config.QuotaConfig{LimitGB: 100, ResetDay: 10, Direction: config.QuotaRx}
+
+	// Noon on the third day of a 31-day cycle
+	now := time.Date(2026, 3, 12, 12, 0, 0, 0, time.UTC)
+	usage := computeQuota(q, days, nil, now)
+	if usage == nil {
+		t.Fatal("Expected quota usage, got nil")
+	}
+
+	if usage.UsedBytes != 9*gb {
+		t.Errorf("Used mismatch. Got %d, want %d", usage.UsedBytes, uint64(9*gb))
+	}
+	if usage.Percent != 9 {
+		t.Errorf("Percent mismatch. Got %f, want 9", usage.Percent)
+	}
+	if !usage.CycleStart.Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("CycleStart mismatch. Got %s", usage.CycleStart)
+	}
+	if !usage.CycleEnd.Equal(time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("CycleEnd mismatch. Got %s", usage.CycleEnd)
+	}
+
+	// 9 GB in 2.5 days of a 31-day cycle
+	wantProjected := uint64(float64(usage.UsedBytes) * 31 / 2.5)
+	if usage.ProjectedBytes != wantProjected {
+		t.Errorf("Projected mismatch. Got %d, want %d", usage.ProjectedBytes, wantProjected)
+	}
+
+	q.Direction = ""
+	if usage := computeQuota(q, days, nil, now); usage.UsedBytes != 15*gb {
+		t.Errorf("Used (both) mismatch. Got %d, want %d", usage.UsedBytes, uint64(15*gb))
+	}
+}
+
+func TestComputeQuotaFromMonth(t *testing.T) {
+	days := []TrafficBucket{dayBucket(2026, 3, 5, 10, 20)}
+	months := []TrafficBucket{dayBucket(2026, 2, 1, 999, 999), dayBucket(2026, 3, 1, 500, 700)}
+	q := config.QuotaConfig{LimitGB: 1, ResetDay: 1, Direction: config.QuotaTx}
+
+	usage := computeQuota(q, days, months, time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC))
+	if usage == nil || usage.UsedBytes != 700 {
+		t.Errorf("Expected month total of 700 tx bytes, got %+v", usage)
+	}
+
+	if computeQuota(q, nil, months, time.Now()) != nil {
+		t.Error("Expected nil usage without day data")
+	}
+}
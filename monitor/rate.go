@@ -0,0 +1,158 @@
+package monitor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// rateTimeConstant sets how quickly smoothed rates follow a change in
+	// traffic: about 63% of a step shows after this long, whatever the
+	// poll interval
+	rateTimeConstant = 15 * time.Second
+
+	// rateMaxGap is the longest interval between two counter readings
+	// that is turned into a rate. After a longer outage the next reading
+	// only becomes the new baseline.
+	rateMaxGap = 15 * time.Minute
+)
+
+// ByteCounters are the cumulative byte counters of one interface
+type ByteCounters struct {
+	Rx uint64
+	Tx uint64
+}
+
+// CounterSample is one reading of cumulative interface counters
+type CounterSample struct {
+	At       time.Time               // When the counters were read
+	Uptime   float64                 // Seconds since the host booted, 0 when unknown
+	Counters map[string]ByteCounters // By interface name
+}
+
+// counterDelta returns how much a counter grew between two readings. A
+// 32-bit counter that was close to its limit is assumed to have wrapped;
+// any other decrease means the counter was reset (e.g. the interface was
+// recreated), so everything counted since is the delta.
+func counterDelta(prev, cur uint64) uint64 {
+	if cur >= prev {
+		return cur - prev
+	}
+	if prev <= math.MaxUint32 && prev > math.MaxUint32/4*3 {
+		return cur + (math.MaxUint32 + 1 - prev)
+	}
+	return cur
+}
+
+// rateTracker turns consecutive counter samples of a server into smoothed
+// rates per interface
+type rateTracker struct {
+	mu     sync.Mutex
+	ifaces map[string]*counterRate
+}
+
+// counterRate is the smoothed rate of one interface
+type counterRate struct {
+	last   ByteCounters
+	lastAt time.Time
+	uptime float64
+	rx     float64 // Bytes per second
+	tx     float64
+	valid  bool // rx and tx hold a rate
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{ifaces: make(map[string]*counterRate)}
+}
+
+// update adds a sample and returns the rate of every interface that has one.
+// The first reading of an interface is only its baseline.
+func (t *rateTracker) update(sample *CounterSample) map[string]Rate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rates := make(map[string]Rate)
+	for name, cur := range sample.Counters {
+		r, ok := t.ifaces[name]
+		if !ok {
+			t.ifaces[name] = &counterRate{last: cur, lastAt: sample.At, uptime: sample.Uptime}
+			continue
+		}
+		r.add(cur, sample)
+		if r.valid {
+			rates[name] = Rate{Rx: uint64(math.Round(r.rx)), Tx: uint64(math.Round(r.tx))}
+		}
+	}
+
+	// Interfaces that disappeared start from a new baseline if they return
+	for name := range t.ifaces {
+		if _, ok := sample.Counters[name]; !ok {
+			delete(t.ifaces, name)
+		}
+	}
+	return rates
+}
+
+// add records a reading. The interval is taken from the host's uptime when
+// known so that polling latency does not skew the rate; an uptime going
+// backwards means the host rebooted and its counters restarted from zero.
+// Rates are smoothed with an exponentially weighted moving average whose
+// weight depends on the interval, so irregular polls are handled.
+func (r *counterRate) add(cur ByteCounters, sample *CounterSample) {
+	elapsed := sample.At.Sub(r.lastAt).Seconds()
+	rebooted := false
+	if sample.Uptime > 0 && r.uptime > 0 {
+		elapsed = sample.Uptime - r.uptime
+		if sample.Uptime < r.uptime {
+			rebooted = true
+			elapsed = sample.Uptime
+		}
+	}
+	if elapsed <= 0 {
+		return // Same reading again, e.g. vnStat has not saved since the last poll
+	}
+
+	rx := counterDelta(r.last.Rx, cur.Rx)
+	tx := counterDelta(r.last.Tx, cur.Tx)
+	if rebooted {
+		rx, tx = cur.Rx, cur.Tx
+	}
+	r.last, r.lastAt, r.uptime = cur, sample.At, sample.Uptime
+
+	if elapsed > rateMaxGap.Seconds() {
+		r.valid = false
+		return
+	}
+
+	instantRx := float64(rx) / elapsed
+	instantTx := float64(tx) / elapsed
+	if !r.valid {
+		r.rx, r.tx, r.valid = instantRx, instantTx, true
+		return
+	}
+	alpha := 1 - math.Exp(-elapsed/rateTimeConstant.Seconds())
+	r.rx += alpha * (instantRx - r.rx)
+	r.tx += alpha * (instantTx - r.tx)
+}
+
+// rateTrackerFor returns the rate tracker of a server, creating it if needed.
+// It returns nil for a server that was removed.
+func (m *Monitor) rateTrackerFor(name string) *rateTracker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pollers[name]; !ok {
+		return nil
+	}
+
+	if m.rates == nil {
+		m.rates = make(map[string]*rateTracker)
+	}
+	t, ok := m.rates[name]
+	if !ok {
+		t = newRateTracker()
+		m.rates[name] = t
+	}
+	return t
+}
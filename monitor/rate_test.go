@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCounterDelta(t *testing.T) {
+	cases := []struct {
+		name      string
+		prev, cur uint64
+		want      uint64
+	}{
+		{"increase", 1000, 1500, 500},
+		{"32-bit wrap", math.MaxUint32 - 99, 100, 200},
+		{"reset", 5000, 300, 300},
+		{"64-bit reset", 1 << 40, 300, 300},
+	}
+	for _, c := range cases {
+		if got := counterDelta(c.prev, c.cur); got != c.want {
+			t.Errorf("%s: Got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRateTracker(t *testing.T) {
+	tracker := newRateTracker()
+	start := time.Now()
+	update := func(offset time.Duration, rx, tx uint64) map[string]Rate {
+		return tracker.update(&CounterSample{At: start.Add(offset), Counters: map[string]ByteCounters{"eth0": {Rx: rx, Tx: tx}}})
+	}
+
+	// The first reading is only a baseline
+	if rates := update(0, 50000, 0); len(rates) != 0 {
+		t.Errorf("Expected no rate from the first reading, got %v", rates)
+	}
+
+	// The first rate is taken as is
+	if r := update(10*time.Second, 60000, 5000); r["eth0"] != (Rate{Rx: 1000, Tx: 500}) {
+		t.Errorf("First rate mismatch. Got %+v, want 1000/500", r["eth0"])
+	}
+
+	// Traffic stops: the rate decays with the interval, not per poll
+	want := uint64(math.Round(1000 * math.Exp(-10.0/15)))
+	if r := update(20*time.Second, 60000, 5000); r["eth0"].Rx != want {
+		t.Errorf("Smoothed rate mismatch. Got %d, want %d", r["eth0"].Rx, want)
+	}
+
+	// A reading with the same time (vnStat has not saved) changes nothing
+	if r := update(20*time.Second, 60000, 5000); r["eth0"].Rx != want {
+		t.Errorf("Repeated reading changed the rate. Got %d, want %d", r["eth0"].Rx, want)
+	}
+
+	// A counter reset counts everything since the reset
+	r := update(30*time.Second, 10000, 5000)
+	alpha := 1 - math.Exp(-10.0/15)
+	if got, wantRx := r["eth0"].Rx, uint64(math.Round(float64(want)+alpha*(1000-float64(want)))); got < wantRx-1 || got > wantRx+1 {
+		t.Errorf("Rate after reset mismatch. Got %d, want about %d", got, wantRx)
+	}
+
+	// After a long outage the next reading is a new baseline
+	if rates := update(time.Hour, 20000, 5000); len(rates) != 0 {
+		t.Errorf("Expected no rate after an outage, got %v", rates)
+	}
+	if r := update(time.Hour+10*time.Second, 30000, 5000); r["eth0"].Rx != 1000 {
+		t.Errorf("Rate after outage mismatch. Got %d, want 1000", r["eth0"].Rx)
+	}
+
+	// Interfaces that disappear are dropped
+	tracker.update(&CounterSample{At: start.Add(2 * time.Hour), Counters: map[string]ByteCounters{"eth1": {}}})
+	if _, ok := tracker.ifaces["eth0"]; ok {
+		t.Error("Removed interface still tracked")
+	}
+}
+
+func TestRateTrackerReboot(t *testing.T) {
+	tracker := newRateTracker()
+	start := time.Now()
+	update := func(offset time.Duration, uptime float64, rx uint64) map[string]Rate {
+		return tracker.update(&CounterSample{At: start.Add(offset), Uptime: uptime, Counters: map[string]ByteCounters{"eth0": {Rx: rx}}})
+	}
+
+	update(0, 1000, 1<<40)
+
+	// The host's uptime sets the interval, not the time the poll took
+	if r := update(12*time.Second, 1010, 1<<40+10000); r["eth0"].Rx != 1000 {
+		t.Errorf("Rate mismatch. Got %d, want 1000", r["eth0"].Rx)
+	}
+
+	// After a reboot the counters started from zero
+	alpha := 1 - math.Exp(-5.0/15)
+	want := uint64(math.Round(1000 + alpha*(2000-1000)))
+	if r := update(30*time.Second, 5, 10000); r["eth0"].Rx != want {
+		t.Errorf("Rate after reboot mismatch. Got %d, want %d", r["eth0"].Rx, want)
+	}
+}
+
+func TestFiveMinuteRateSkipsPartialBucket(t *testing.T) {
+	now := time.Now()
+	current := now.Truncate(fiveMinuteResolution)
+	buckets := []TrafficBucket{
+		{Timestamp: current.Add(-fiveMinuteResolution).Unix(), Rx: 30000, Tx: 60000},
+		{Timestamp: current.Unix(), Rx: 300, Tx: 300}, // Still filling
+	}
+
+	m, _ := trafficMetrics(buckets, nil, nil, now)
+	if m.FiveMinuteRx != 100 || m.FiveMinuteTx != 200 {
+		t.Errorf("Five-minute rate mismatch. Got %d/%d, want 100/200", m.FiveMinuteRx, m.FiveMinuteTx)
+	}
+	if m.Rx != m.FiveMinuteRx {
+		t.Errorf("Without counters Rx should be the five-minute rate. Got %d, want %d", m.Rx, m.FiveMinuteRx)
+	}
+}
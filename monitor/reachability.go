@@ -0,0 +1,432 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// uptimeRetention is how long ended outages are kept, and so the
+	// longest window uptime is computed over
+	uptimeRetention = 30 * 24 * time.Hour
+
+	// maxOutages bounds the outages kept per server
+	maxOutages = 1000
+
+	// uptimeFile holds the reachability history of all servers in the
+	// data directory
+	uptimeFile = "uptime.json"
+
+	// uptimeSaveInterval is how often the reachability history is saved
+	// besides on state changes, to keep last_seen and flap changes current
+	uptimeSaveInterval = 5 * time.Minute
+)
+
+// Reachability states
+const (
+	ReachabilityUnknown = "unknown" // Not yet up or down since tracking started
+	ReachabilityUp      = "up"
+	ReachabilityDown    = "down"
+)
+
+// Outage is a period in which a server was down
+type Outage struct {
+	Start time.Time `json:"start"` // First failed poll
+	End   time.Time `json:"end"`   // First successful poll after it, zero while ongoing
+}
+
+// Duration returns how long the outage lasted, or has lasted until now
+func (o Outage) Duration(now time.Time) time.Duration {
+	if o.End.IsZero() {
+		return now.Sub(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// Reachability is the up/down state of a server at its latest poll
+type Reachability struct {
+	State     string
+	Since     time.Time // When the server entered the state
+	LastSeen  time.Time // Latest successful poll
+	Failures  int       // Consecutive failed polls
+	Flapping  bool      // Changed between up and down too often within the flap window
+	Changes   int       // Up/down changes within the flap window
+	Uptime24h float64   // Percent of the window the server was not down
+	Uptime7d  float64
+	Uptime30d float64
+}
+
+// reachabilityRecord is the persisted reachability history of a server
+type reachabilityRecord struct {
+	State        string      `json:"state"`
+	Since        time.Time   `json:"since"`
+	LastSeen     time.Time   `json:"last_seen"`
+	LastPoll     time.Time   `json:"last_poll"`         // Latest poll, successful or not
+	TrackedSince time.Time   `json:"tracked_since"`     // First poll; uptime before it is not counted
+	Outages      []Outage    `json:"outages,omitempty"` // Oldest first
+	Changes      []time.Time `json:"changes,omitempty"` // Up/down changes within the flap window
+}
+
+// uptime returns the percentage of the window ending at now in which the
+// server was not down
+func (r *reachabilityRecord) uptime(window time.Duration, now time.Time) float64 {
+	from := now.Add(-window)
+	if r.TrackedSince.After(from) {
+		from = r.TrackedSince
+	}
+	total := now.Sub(from)
+	if total <= 0 {
+		return 100
+	}
+
+	var down time.Duration
+	for _, o := range r.Outages {
+		start, end := o.Start, o.End
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			down += end.Sub(start)
+		}
+	}
+	return 100 * (1 - down.Seconds()/total.Seconds())
+}
+
+// reachabilityTracker runs the up/down state machine of one server. A
+// server is marked down after DownAfter consecutive failed polls, with the
+// outage starting at the first of them, and up again at the next
+// successful poll.
+type reachabilityTracker struct {
+	mu           sync.Mutex
+	record       reachabilityRecord
+	failures     int
+	firstFailure time.Time
+	flapping     bool
+}
+
+// newReachabilityTracker continues a persisted history. An outage still
+// ongoing when the history was saved ends at the last poll before it, as
+// nothing is known about the server while the monitor wasn't running, and
+// the state is unknown until the next polls.
+func newReachabilityTracker(record reachabilityRecord) *reachabilityTracker {
+	record.Outages = slices.Clone(record.Outages)
+	record.Changes = slices.Clone(record.Changes)
+	if n := len(record.Outages); n > 0 && record.Outages[n-1].End.IsZero() {
+		end := record.LastPoll
+		if end.Before(record.Outages[n-1].Start) {
+			end = record.Outages[n-1].Start
+		}
+		record.Outages[n-1].End = end
+		record.State, record.Since = ReachabilityUnknown, end
+	}
+	if record.State == "" {
+		record.State = ReachabilityUnknown
+	}
+	return &reachabilityTracker{record: record}
+}
+
+// observe records the result of a poll. It returns the new reachability
+// and the state before the poll.
+func (t *reachabilityTracker) observe(ok bool, at time.Time, p config.ReachabilityPolicy) (Reachability, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := &t.record
+	if r.TrackedSince.IsZero() {
+		r.TrackedSince = at
+	}
+	r.LastPoll = at
+	prev := r.State
+
+	if ok {
+		t.failures = 0
+		r.LastSeen = at
+		if r.State != ReachabilityUp {
+			if n := len(r.Outages); n > 0 && r.Outages[n-1].End.IsZero() {
+				r.Outages[n-1].End = at
+			}
+			r.State, r.Since = ReachabilityUp, at
+		}
+	} else {
+		if t.failures == 0 {
+			t.firstFailure = at
+		}
+		t.failures++
+		if r.State != ReachabilityDown && t.failures >= p.DownAfter {
+			r.State, r.Since = ReachabilityDown, t.firstFailure
+			r.Outages = append(r.Outages, Outage{Start: t.firstFailure})
+		}
+	}
+
+	// The first state after tracking started is not a change
+	if r.State != prev && prev != ReachabilityUnknown {
+		r.Changes = append(r.Changes, at)
+	}
+	return t.reachability(at, p), prev
+}
+
+// current returns the reachability at a poll that says nothing about it,
+// e.g. one that failed for a reason other than reaching the server
+func (t *reachabilityTracker) current(at time.Time, p config.ReachabilityPolicy) Reachability {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.record.LastPoll = at
+	return t.reachability(at, p)
+}
+
+// reachability prunes the history and summarizes it at a time. The caller
+// must hold t.mu.
+func (t *reachabilityTracker) reachability(at time.Time, p config.ReachabilityPolicy) Reachability {
+	r := &t.record
+	t.prune(at, p.FlapWindow)
+
+	// Flapping starts at the threshold and ends once a whole window passed
+	// without changes, so that it doesn't toggle around the threshold
+	t.flapping = len(r.Changes) >= p.FlapThreshold || (t.flapping && len(r.Changes) > 0)
+
+	return Reachability{
+		State:     r.State,
+		Since:     r.Since,
+		LastSeen:  r.LastSeen,
+		Failures:  t.failures,
+		Flapping:  t.flapping,
+		Changes:   len(r.Changes),
+		Uptime24h: r.uptime(24*time.Hour, at),
+		Uptime7d:  r.uptime(7*24*time.Hour, at),
+		Uptime30d: r.uptime(30*24*time.Hour, at),
+	}
+}
+
+// prune forgets changes older than the flap window and outages that ended
+// before the retention
+func (t *reachabilityTracker) prune(now time.Time, window time.Duration) {
+	r := &t.record
+
+	i := 0
+	for i < len(r.Changes) && !r.Changes[i].After(now.Add(-window)) {
+		i++
+	}
+	r.Changes = r.Changes[i:]
+
+	cutoff := now.Add(-uptimeRetention)
+	i = 0
+	for i < len(r.Outages) && !r.Outages[i].End.IsZero() && r.Outages[i].End.Before(cutoff) {
+		i++
+	}
+	i = max(i, len(r.Outages)-maxOutages)
+	r.Outages = r.Outages[i:]
+}
+
+// persisted returns a copy of the history to save
+func (t *reachabilityTracker) persisted() reachabilityRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	record := t.record
+	record.Outages = slices.Clone(record.Outages)
+	record.Changes = slices.Clone(record.Changes)
+	return record
+}
+
+// outages returns copies of the kept outages, newest first
+func (t *reachabilityTracker) outages() []Outage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	outages := slices.Clone(t.record.Outages)
+	slices.Reverse(outages)
+	return outages
+}
+
+// uptimePath returns the file reachability history is persisted in, empty
+// without a history store
+func (m *Monitor) uptimePath() string {
+	if m.store == nil {
+		return ""
+	}
+	return filepath.Join(m.store.Dir(), uptimeFile)
+}
+
+// loadReachability reads the persisted history once, before the first
+// tracker is created or the history is saved
+func (m *Monitor) loadReachability() {
+	m.uptimeOnce.Do(func() {
+		records := make(map[string]reachabilityRecord)
+		if path := m.uptimePath(); path != "" {
+			data, err := os.ReadFile(path)
+			if err == nil {
+				err = json.Unmarshal(data, &records)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to load uptime history from %s: %v", path, err)
+				records = make(map[string]reachabilityRecord)
+			}
+		}
+
+		m.mu.Lock()
+		m.savedUptime = records
+		m.mu.Unlock()
+	})
+}
+
+// reachabilityFor returns the reachability tracker of a server. A new one
+// continues the history persisted by a previous run. It returns nil for a
+// server that was removed.
+func (m *Monitor) reachabilityFor(name string) *reachabilityTracker {
+	m.loadReachability()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pollers[name]; !ok {
+		return nil
+	}
+
+	if m.reachability == nil {
+		m.reachability = make(map[string]*reachabilityTracker)
+	}
+	t, ok := m.reachability[name]
+	if !ok {
+		t = newReachabilityTracker(m.savedUptime[name])
+		delete(m.savedUptime, name)
+		m.reachability[name] = t
+	}
+	return t
+}
+
+// saveReachability persists the reachability history of every configured
+// server, including servers not polled yet in this run
+func (m *Monitor) saveReachability() {
+	path := m.uptimePath()
+	if path == "" {
+		return
+	}
+	m.loadReachability()
+
+	m.mu.RLock()
+	records := make(map[string]reachabilityRecord, len(m.pollers))
+	for name := range m.pollers {
+		if t, ok := m.reachability[name]; ok {
+			records[name] = t.persisted()
+		} else if record, ok := m.savedUptime[name]; ok {
+			records[name] = record
+		}
+	}
+	m.mu.RUnlock()
+
+	m.uptimeMu.Lock()
+	defer m.uptimeMu.Unlock()
+	if err := writeReachability(path, records); err != nil {
+		log.Printf("Failed to save uptime history: %v", err)
+	}
+}
+
+// persistReachability saves the reachability history periodically until the
+// monitor is stopped
+func (m *Monitor) persistReachability() {
+	if m.store == nil {
+		return
+	}
+
+	ticker := time.NewTicker(uptimeSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-ticker.C:
+			m.saveReachability()
+		}
+	}
+}
+
+// writeReachability atomically replaces the uptime file
+func writeReachability(path string, records map[string]reachabilityRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode uptime history: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write uptime history: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace uptime history: %w", err)
+	}
+	return nil
+}
+
+// reachabilityPolicy returns the configured reachability settings
+func (m *Monitor) reachabilityPolicy() config.ReachabilityPolicy {
+	if m.config == nil {
+		return config.SettingsConfig{}.GetReachabilityPolicy()
+	}
+	return m.config.GetSettings().GetReachabilityPolicy()
+}
+
+// trackReachability feeds the result of a poll to the reachability state
+// machine of the server. Only successful polls and polls that could not
+// reach the server count; other errors, such as unparsable output or a bad
+// collector setting, say nothing about reachability and leave the state
+// as is. A failed poll of a server that is not down keeps its last metrics,
+// marked with the error, so that a single failed dial doesn't take it
+// offline.
+func (m *Monitor) trackReachability(name string, metrics *ServerMetrics, unreachable bool) *ServerMetrics {
+	t, policy := m.reachabilityFor(name), m.reachabilityPolicy()
+	if t == nil {
+		return metrics
+	}
+	var r Reachability
+	prev := ""
+	if metrics.Online || unreachable {
+		r, prev = t.observe(metrics.Online, metrics.UpdatedAt, policy)
+	} else {
+		r = t.current(metrics.UpdatedAt, policy)
+		prev = r.State
+	}
+
+	if !metrics.Online && r.State != ReachabilityDown {
+		if last := m.GetServerMetrics(name); last != nil && last.Online {
+			kept := *last
+			kept.Error = metrics.Error
+			metrics = &kept
+		}
+	}
+	metrics.Reachability = &r
+
+	if r.State != prev {
+		switch {
+		case r.State == ReachabilityDown:
+			log.Printf("Server %s is down after %d failed polls: %s", name, r.Failures, metrics.Error)
+		case prev == ReachabilityDown:
+			log.Printf("Server %s is back up", name)
+		}
+		m.saveReachability()
+	}
+	return metrics
+}
+
+// GetOutages returns the outages of a server in the last 30 days, newest
+// first
+func (m *Monitor) GetOutages(name string) []Outage {
+	m.mu.RLock()
+	t, ok := m.reachability[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return t.outages()
+}
@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
+	"bandwidth-monitor/store"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReachabilityStateMachine(t *testing.T) {
+	policy := config.ReachabilityPolicy{DownAfter: 3, FlapThreshold: 4, FlapWindow: time.Hour}
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	tr := newReachabilityTracker(reachabilityRecord{})
+	if r, prev := tr.observe(true, at(0), policy); r.State != ReachabilityUp || prev != ReachabilityUnknown || r.Changes != 0 {
+		t.Errorf("Expected up from unknown without a change, got %+v from %s", r, prev)
+	}
+
+	// Two failures keep the server up
+	tr.observe(false, at(1), policy)
+	r, _ := tr.observe(false, at(2), policy)
+	if r.State != ReachabilityUp || r.Failures != 2 || !r.LastSeen.Equal(at(0)) {
+		t.Errorf("Expected failing server still up, got %+v", r)
+	}
+
+	// The third marks it down since the first failure
+	r, prev := tr.observe(false, at(3), policy)
+	if r.State != ReachabilityDown || prev != ReachabilityUp || !r.Since.Equal(at(1)) {
+		t.Errorf("Expected down since the first failure, got %+v from %s", r, prev)
+	}
+
+	// A success ends the outage
+	r, prev = tr.observe(true, at(11), policy)
+	if r.State != ReachabilityUp || prev != ReachabilityDown || r.Failures != 0 || r.Changes != 2 {
+		t.Errorf("Expected back up after 2 changes, got %+v from %s", r, prev)
+	}
+	outages := tr.outages()
+	if len(outages) != 1 || !outages[0].Start.Equal(at(1)) || outages[0].Duration(at(60)) != 10*time.Minute {
+		t.Errorf("Unexpected outages: %+v", outages)
+	}
+
+	// 10 of the first 80 minutes were down
+	if r, _ := tr.observe(true, at(80), policy); r.Uptime24h != 87.5 || r.Uptime30d != 87.5 {
+		t.Errorf("Uptime mismatch. Got %g, want 87.5", r.Uptime24h)
+	}
+}
+
+func TestReachabilityFlapping(t *testing.T) {
+	policy := config.ReachabilityPolicy{DownAfter: 1, FlapThreshold: 4, FlapWindow: time.Hour}
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tr := newReachabilityTracker(reachabilityRecord{})
+	tr.observe(true, start, policy)
+
+	// Four changes within the window
+	var r Reachability
+	for i := 1; i <= 4; i++ {
+		r, _ = tr.observe(i%2 == 0, start.Add(time.Duration(i)*time.Minute), policy)
+	}
+	if !r.Flapping || r.Changes != 4 {
+		t.Fatalf("Expected flapping after 4 changes, got %+v", r)
+	}
+
+	// Still flapping while changes are in the window, below the threshold
+	if r, _ = tr.observe(true, start.Add(62*time.Minute), policy); !r.Flapping || r.Changes != 2 {
+		t.Errorf("Expected flapping until the window is quiet, got %+v", r)
+	}
+	if r, _ = tr.observe(true, start.Add(2*time.Hour), policy); r.Flapping || r.Changes != 0 {
+		t.Errorf("Expected flapping to end after a quiet window, got %+v", r)
+	}
+}
+
+func TestReachabilityPersisted(t *testing.T) {
+	historyStore, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	newMonitor := func() *Monitor {
+		return &Monitor{
+			store:     historyStore,
+			pool:      sshclient.NewPool(nil),
+			pollers:   map[string]*poller{"web": {}},
+			metrics:   &AggregateMetrics{ServerMetrics: make(map[string]*ServerMetrics)},
+			pollStats: make(map[string]*PollStats),
+			stopChan:  make(chan struct{}),
+		}
+	}
+
+	m := newMonitor()
+	fake := &fakeCollector{err: Unreachable(errors.New("connection refused"))}
+	m.SetCollector("fake", fake)
+	server := config.ServerConfig{Name: "web", Collector: "fake"}
+	for i := 0; i < config.DefaultDownAfter+1; i++ {
+		m.collectMetrics(server)
+	}
+	sm := m.GetServerMetrics("web")
+	if sm.Online || sm.Reachability == nil || sm.Reachability.State != ReachabilityDown {
+		t.Fatalf("Expected server marked down, got %+v", sm.Reachability)
+	}
+	lastPoll := sm.UpdatedAt
+	m.Stop()
+
+	// A restarted monitor ends the outage at the last poll before the stop,
+	// so the time it wasn't running doesn't count as downtime
+	m = newMonitor()
+	m.SetCollector("fake", fake)
+	fake.err = nil
+	fake.traffic = fakeTraffic(t, "eth0", time.Now(), 5000, 5000)
+	m.collectMetrics(server)
+
+	sm = m.GetServerMetrics("web")
+	if !sm.Online || sm.Reachability.State != ReachabilityUp || sm.Reachability.Changes != 0 {
+		t.Errorf("Expected server up from unknown without a change, got %+v", sm.Reachability)
+	}
+	if outages := m.GetOutages("web"); len(outages) != 1 || !outages[0].End.Equal(lastPoll) {
+		t.Errorf("Expected the persisted outage to end at %v, got %+v", lastPoll, outages)
+	}
+	if v := serverFields["uptime24h"](sm); v >= 100 {
+		t.Errorf("Alert field uptime_24h should count the outage, got %g", v)
+	}
+}
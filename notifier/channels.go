@@ -0,0 +1,137 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookChannel POSTs a JSON document describing the alert to a URL
+type WebhookChannel struct {
+	URL     string
+	Headers map[string]string
+}
+
+// webhookPayload is the JSON body sent by WebhookChannel
+type webhookPayload struct {
+	Rule      string    `json:"rule"`
+	Server    string    `json:"server,omitempty"`
+	Field     string    `json:"field"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`
+	Severity  string    `json:"severity,omitempty"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	Time      time.Time `json:"time"`
+	Subject   string    `json:"subject"`
+	Message   string    `json:"message"`
+}
+
+// Send implements Channel
+func (c *WebhookChannel) Send(msg Message) error {
+	ev := msg.Event
+	return postJSON(c.URL, c.Headers, webhookPayload{
+		Rule:      ev.Rule,
+		Server:    ev.Server,
+		Field:     ev.Field,
+		Operator:  ev.Operator,
+		Threshold: ev.Threshold,
+		Value:     ev.Value,
+		Severity:  ev.Severity,
+		State:     string(ev.State),
+		Since:     ev.Since,
+		Time:      ev.Time,
+		Subject:   msg.Subject,
+		Message:   msg.Body,
+	})
+}
+
+// SlackChannel posts to a Slack or Mattermost incoming webhook
+type SlackChannel struct {
+	URL string
+}
+
+// Send implements Channel
+func (c *SlackChannel) Send(msg Message) error {
+	return postJSON(c.URL, nil, map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Subject, msg.Body),
+	})
+}
+
+func postJSON(url string, headers map[string]string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPChannel sends plain-text email. STARTTLS is used when the server
+// supports it.
+type SMTPChannel struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Send implements Channel
+func (c *SMTPChannel) Send(msg Message) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", c.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", headerValue(msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	if err := smtp.SendMail(addr, auth, c.From, c.To, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// headerValue strips line breaks so templated subjects cannot inject headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
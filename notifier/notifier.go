@@ -0,0 +1,333 @@
+package notifier
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/monitor"
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	DefaultSubjectTemplate = `[{{upper .State}}] {{.Rule}}{{if .Server}} on {{.Server}}{{end}}`
+	DefaultBodyTemplate    = `Alert {{.Rule}} is {{.State}} for {{if .Server}}server {{.Server}}{{else}}all servers{{end}}: ` +
+		`{{.Field}} {{.Operator}} {{.Threshold}} (current value {{.Value}}){{if .Severity}}, severity {{.Severity}}{{end}}`
+
+	DefaultMaxRetries = 3
+	DefaultMinBackoff = 2 * time.Second
+	DefaultMaxBackoff = time.Minute
+
+	queueSize = 64
+)
+
+// Message is a rendered notification
+type Message struct {
+	Subject string
+	Body    string
+	Event   monitor.AlertEvent
+}
+
+// Channel delivers messages to a single destination
+type Channel interface {
+	Send(msg Message) error
+}
+
+// Notifier fans alert events out to all configured channels. Each channel
+// has its own queue, retry loop and rate limit so a slow or failing
+// destination does not delay the others.
+type Notifier struct {
+	workers  []*worker
+	wg       sync.WaitGroup
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// worker delivers queued events to one channel
+type worker struct {
+	name       string
+	channel    Channel
+	subject    *template.Template
+	body       *template.Template
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	limiter    *rateLimiter
+	queue      chan monitor.AlertEvent
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+	"bytes": formatBytes,
+	"time":  func(t time.Time) string { return t.Format(time.RFC3339) },
+}
+
+// New creates a notifier for the configured channels and starts delivering
+func New(channels []config.NotificationChannel) (*Notifier, error) {
+	n := &Notifier{stopChan: make(chan struct{})}
+	names := make(map[string]bool)
+
+	for i, c := range channels {
+		if c.Name == "" {
+			return nil, fmt.Errorf("notification channel #%d: name is required", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("notification channel '%s': duplicate name", c.Name)
+		}
+		names[c.Name] = true
+
+		w, err := newWorker(c)
+		if err != nil {
+			return nil, fmt.Errorf("notification channel '%s': %w", c.Name, err)
+		}
+		n.workers = append(n.workers, w)
+	}
+
+	for _, w := range n.workers {
+		n.wg.Add(1)
+		go func(w *worker) {
+			defer n.wg.Done()
+			w.run(n.stopChan)
+		}(w)
+	}
+
+	return n, nil
+}
+
+func newWorker(c config.NotificationChannel) (*worker, error) {
+	channel, err := newChannel(c)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectText := c.SubjectTemplate
+	if subjectText == "" {
+		subjectText = DefaultSubjectTemplate
+	}
+	subject, err := template.New("subject").Funcs(templateFuncs).Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	bodyText := c.BodyTemplate
+	if bodyText == "" {
+		bodyText = DefaultBodyTemplate
+	}
+	body, err := template.New("body").Funcs(templateFuncs).Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	maxRetries := c.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+
+	return &worker{
+		name:       c.Name,
+		channel:    channel,
+		subject:    subject,
+		body:       body,
+		maxRetries: maxRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		limiter:    newRateLimiter(c.MaxPerHour, time.Hour),
+		queue:      make(chan monitor.AlertEvent, queueSize),
+	}, nil
+}
+
+func newChannel(c config.NotificationChannel) (Channel, error) {
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return &WebhookChannel{URL: c.URL, Headers: c.Headers}, nil
+	case "slack", "mattermost":
+		if c.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return &SlackChannel{URL: c.URL}, nil
+	case "smtp", "email":
+		if c.SMTPHost == "" || c.From == "" || len(c.To) == 0 {
+			return nil, fmt.Errorf("smtp_host, from and to are required")
+		}
+		port := c.SMTPPort
+		if port == 0 {
+			port = 587
+		}
+		return &SMTPChannel{
+			Host:     c.SMTPHost,
+			Port:     port,
+			Username: c.SMTPUser,
+			Password: c.SMTPPass,
+			From:     c.From,
+			To:       c.To,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown type '%s'", c.Type)
+	}
+}
+
+// Notify queues an alert event for every channel. It never blocks; events
+// are dropped if a channel's queue is full.
+func (n *Notifier) Notify(ev monitor.AlertEvent) {
+	for _, w := range n.workers {
+		select {
+		case w.queue <- ev:
+		default:
+			log.Printf("Notification channel '%s': queue full, dropping %s alert %s", w.name, ev.State, ev.Rule)
+		}
+	}
+}
+
+// Test renders and sends an event synchronously to every channel, without
+// retries or rate limiting, and returns the errors by channel name
+func (n *Notifier) Test(ev monitor.AlertEvent) map[string]error {
+	results := make(map[string]error)
+	for _, w := range n.workers {
+		msg, err := w.render(ev)
+		if err == nil {
+			err = w.channel.Send(msg)
+		}
+		results[w.name] = err
+	}
+	return results
+}
+
+// Close stops delivery. Queued events that have not been sent are dropped.
+func (n *Notifier) Close() {
+	n.stopOnce.Do(func() {
+		close(n.stopChan)
+	})
+	n.wg.Wait()
+}
+
+func (w *worker) run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case ev := <-w.queue:
+			if !w.limiter.Allow(time.Now()) {
+				log.Printf("Notification channel '%s': rate limit reached, dropping %s alert %s", w.name, ev.State, ev.Rule)
+				continue
+			}
+
+			msg, err := w.render(ev)
+			if err != nil {
+				log.Printf("Notification channel '%s': %v", w.name, err)
+				continue
+			}
+
+			if err := w.deliver(msg, stop); err != nil {
+				log.Printf("Notification channel '%s': giving up on %s alert %s: %v", w.name, ev.State, ev.Rule, err)
+			}
+		}
+	}
+}
+
+// render executes the subject and body templates for an event
+func (w *worker) render(ev monitor.AlertEvent) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := w.subject.Execute(&subject, ev); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := w.body.Execute(&body, ev); err != nil {
+		return Message{}, fmt.Errorf("failed to render body: %w", err)
+	}
+	return Message{Subject: subject.String(), Body: body.String(), Event: ev}, nil
+}
+
+// deliver sends a message, retrying with exponential backoff
+func (w *worker) deliver(msg Message, stop <-chan struct{}) error {
+	var err error
+	for attempt := 0; attempt <= w.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-stop:
+				return err
+			case <-time.After(w.backoff(attempt)):
+			}
+		}
+
+		if err = w.channel.Send(msg); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// backoff returns the delay before the given retry attempt
+func (w *worker) backoff(attempt int) time.Duration {
+	d := w.minBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+	return d
+}
+
+// rateLimiter allows at most limit events per window (sliding window)
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	sent   []time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{limit: limit, window: window}
+}
+
+// Allow reports whether another event may be sent at now and records it
+func (r *rateLimiter) Allow(now time.Time) bool {
+	if r.limit <= 0 {
+		return true
+	}
+
+	cutoff := now.Add(-r.window)
+	kept := r.sent[:0]
+	for _, t := range r.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	r.sent = kept
+
+	if len(r.sent) >= r.limit {
+		return false
+	}
+	r.sent = append(r.sent, now)
+	return true
+}
+
+// formatBytes formats a byte rate for templates, e.g. "12.5 MB"
+func formatBytes(v interface{}) string {
+	var b float64
+	switch n := v.(type) {
+	case float64:
+		b = n
+	case uint64:
+		b = float64(n)
+	case int:
+		b = float64(n)
+	default:
+		return fmt.Sprint(v)
+	}
+
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	div, exp := float64(unit), 0
+	for n := b / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", b/div, "KMGTP"[exp])
+}
@@ -0,0 +1,185 @@
+package notifier
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/monitor"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEvent() monitor.AlertEvent {
+	now := time.Unix(1700000000, 0)
+	return monitor.AlertEvent{
+		Alert: monitor.Alert{
+			Rule:      "high-rx",
+			Server:    "web1",
+			Field:     "rx",
+			Operator:  ">",
+			Threshold: 1000,
+			Value:     2048,
+			Severity:  "critical",
+			State:     monitor.AlertFiring,
+			Since:     now,
+			FiredAt:   now,
+		},
+		Time: now,
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	w, err := newWorker(config.NotificationChannel{
+		Name:         "hook",
+		Type:         "webhook",
+		URL:          "http://localhost",
+		BodyTemplate: "{{.Server}} rx is {{bytes .Value}}",
+	})
+	if err != nil {
+		t.Fatalf("newWorker failed: %v", err)
+	}
+
+	msg, err := w.render(testEvent())
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if msg.Subject != "[FIRING] high-rx on web1" {
+		t.Errorf("Subject mismatch. Got %q", msg.Subject)
+	}
+	if msg.Body != "web1 rx is 2.0 KB" {
+		t.Errorf("Body mismatch. Got %q", msg.Body)
+	}
+}
+
+func TestInvalidChannels(t *testing.T) {
+	cases := []config.NotificationChannel{
+		{Name: "", Type: "webhook", URL: "http://localhost"},
+		{Name: "a", Type: "pager"},
+		{Name: "b", Type: "webhook"},
+		{Name: "c", Type: "smtp", SMTPHost: "mail"},
+		{Name: "d", Type: "slack", URL: "http://localhost", BodyTemplate: "{{.Missing"},
+	}
+	for _, c := range cases {
+		if _, err := New([]config.NotificationChannel{c}); err == nil {
+			t.Errorf("Expected error for channel %+v", c)
+		}
+	}
+
+	dup := []config.NotificationChannel{
+		{Name: "a", Type: "webhook", URL: "http://localhost"},
+		{Name: "a", Type: "slack", URL: "http://localhost"},
+	}
+	if _, err := New(dup); err == nil {
+		t.Error("Expected error for duplicate channel names")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	var payload webhookPayload
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("X-Token") != "secret" {
+			t.Errorf("Missing custom header")
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		close(done)
+	}))
+	defer srv.Close()
+
+	n, err := New([]config.NotificationChannel{{
+		Name:    "hook",
+		Type:    "webhook",
+		URL:     srv.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer n.Close()
+	n.workers[0].minBackoff = time.Millisecond
+
+	n.Notify(testEvent())
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("Attempts mismatch. Got %d, want 3", attempts)
+	}
+	if payload.Rule != "high-rx" || payload.Server != "web1" || payload.State != "firing" || payload.Value != 2048 {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	n, err := New([]config.NotificationChannel{{Name: "chat", Type: "slack", URL: srv.URL}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer n.Close()
+
+	results := n.Test(testEvent())
+	if results["chat"] != nil {
+		t.Fatalf("Test send failed: %v", results["chat"])
+	}
+	if !strings.HasPrefix(body["text"], "*[FIRING] high-rx on web1*\n") {
+		t.Errorf("Unexpected Slack text: %q", body["text"])
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := newRateLimiter(2, time.Hour)
+	now := time.Unix(0, 0)
+
+	if !r.Allow(now) || !r.Allow(now.Add(time.Minute)) {
+		t.Fatal("First two events should be allowed")
+	}
+	if r.Allow(now.Add(2 * time.Minute)) {
+		t.Error("Third event within the window should be dropped")
+	}
+	if !r.Allow(now.Add(time.Hour + time.Second)) {
+		t.Error("Event after the window should be allowed")
+	}
+
+	unlimited := newRateLimiter(0, time.Hour)
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow(now) {
+			t.Fatal("Unlimited limiter dropped an event")
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	w := &worker{minBackoff: time.Second, maxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, d := range want {
+		if got := w.backoff(i + 1); got != d {
+			t.Errorf("Backoff for attempt %d mismatch. Got %v, want %v", i+1, got, d)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes of the non-interactive server commands
+const (
+	exitOK       = 0
+	exitFailure  = 1 // Setup, SSH or config error
+	exitUsage    = 2 // Invalid or missing flags
+	exitNotFound = 3 // The named server does not exist
+	exitConflict = 4 // A server with that name already exists
+)
+
+// cliResult is printed by the server commands when --json is given
+type cliResult struct {
+	OK      bool                 `json:"ok"`
+	Action  string               `json:"action"`
+	Changed bool                 `json:"changed"`
+	Server  *config.ServerConfig `json:"server,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// serverCommand holds the output settings of a non-interactive server command
+type serverCommand struct {
+	action string
+	json   bool
+}
+
+// log returns where progress output goes. It is kept off stdout in JSON
+// mode so that stdout carries a single JSON document.
+func (c *serverCommand) log() io.Writer {
+	if c.json {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// fail reports an error and exits with code
+func (c *serverCommand) fail(code int, err error) {
+	if c.json {
+		json.NewEncoder(os.Stdout).Encode(cliResult{Action: c.action, Error: err.Error()})
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// succeed reports the result of the command
+func (c *serverCommand) succeed(server *config.ServerConfig, changed bool, message string) {
+	if c.json {
+		json.NewEncoder(os.Stdout).Encode(cliResult{OK: true, Action: c.action, Changed: changed, Server: server})
+		return
+	}
+	fmt.Printf("✓ %s\n", message)
+}
+
+// parseFlags parses flags, exiting with exitUsage on errors
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+}
+
+// parse parses flags that take no positional arguments
+func (c *serverCommand) parse(fs *flag.FlagSet, args []string) {
+	parseFlags(fs, args)
+	if fs.NArg() > 0 {
+		c.fail(exitUsage, fmt.Errorf("unexpected argument '%s'", fs.Arg(0)))
+	}
+}
+
+// loadConfig loads the config, exiting on errors
+func (c *serverCommand) loadConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		c.fail(exitFailure, fmt.Errorf("failed to load config: %w", err))
+	}
+	return cfg
+}
+
+// hasFlag reports whether args contain the flag -name or --name
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		arg = strings.TrimLeft(arg, "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// readPasswordFile reads an SSH password from a file, or from stdin for "-"
+func readPasswordFile(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password file is empty")
+	}
+	return password, nil
+}
+
+// authFlags are the SSH login flags shared by add and update
+type authFlags struct {
+	passwordFile string
+	keyFile      string
+	iface        string
+	noInstall    bool
+	collector    string
+	url          string
+	group        string
+	tags         string
+}
+
+func (a *authFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&a.passwordFile, "password-file", "", "File containing the SSH password (- for stdin)")
+	fs.StringVar(&a.keyFile, "key", "", "Existing private key used to log in instead of a password")
+	fs.StringVar(&a.iface, "interface", "", "Comma-separated interfaces to monitor (detected when empty)")
+	fs.BoolVar(&a.noInstall, "no-install", false, "Do not install vnStat; it must already be installed")
+	fs.StringVar(&a.collector, "collector", "", "How traffic is read: vnstat (default), procnetdev, local or http")
+	fs.StringVar(&a.url, "url", "", "vnStat JSON endpoint read by the http collector")
+	fs.StringVar(&a.group, "group", "", "Group the server belongs to, e.g. a customer or region")
+	fs.StringVar(&a.tags, "tags", "", "Comma-separated tags, e.g. eu,tier=gold")
+}
+
+// labels validates the group and tags flags
+func (a *authFlags) labels() (string, []string, error) {
+	if a.group != "" && !config.ValidTag(a.group) {
+		return "", nil, fmt.Errorf("invalid group '%s' (use letters, digits and - _ . : = /)", a.group)
+	}
+	tags, err := config.ParseTags(a.tags)
+	if err != nil {
+		return "", nil, err
+	}
+	return a.group, tags, nil
+}
+
+// options converts the flags into setup options
+func (a *authFlags) options(log io.Writer) (setupOptions, error) {
+	opts := setupOptions{KeyFile: a.keyFile, NoInstall: a.noInstall, Log: log}
+	if a.passwordFile != "" && a.keyFile != "" {
+		return opts, fmt.Errorf("--password-file and --key cannot be combined")
+	}
+	if a.passwordFile != "" {
+		password, err := readPasswordFile(a.passwordFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Password = password
+	}
+	if a.collector != "" {
+		collector, err := config.ParseCollector(a.collector)
+		if err != nil {
+			return opts, err
+		}
+		opts.Collector = collector
+	}
+	if a.iface != "" {
+		interfaces, err := parseInterfaces(a.iface)
+		if err != nil {
+			return opts, err
+		}
+		opts.Interfaces = interfaces
+	}
+	return opts, nil
+}
+
+// runAddCommand handles "bandwidth-monitor add --name ... --ip ..."
+func runAddCommand(args []string) {
+	c := &serverCommand{action: "add"}
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	name := fs.String("name", "", "Server name (required)")
+	ip := fs.String("ip", "", "Server IP address or hostname (required)")
+	user := fs.String("user", "root", "SSH user")
+	port := fs.Int("port", 22, "SSH port")
+	var auth authFlags
+	auth.register(fs)
+	fs.BoolVar(&c.json, "json", false, "Print the result as JSON")
+	c.parse(fs, args)
+
+	if *port < 1 || *port > 65535 {
+		c.fail(exitUsage, fmt.Errorf("invalid port %d", *port))
+	}
+	opts, err := auth.options(c.log())
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+	group, tags, err := auth.labels()
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+
+	server := config.ServerConfig{
+		Name:  *name,
+		IP:    *ip,
+		User:  *user,
+		Port:  *port,
+		URL:   auth.url,
+		Group: group,
+		Tags:  tags,
+	}
+	if opts.Collector != config.CollectorVnStat {
+		server.Collector = opts.Collector
+	}
+	if err := server.ValidateSource(); err != nil {
+		c.fail(exitUsage, err)
+	}
+	if server.IP == "" {
+		server.IP = server.SourceHost()
+	}
+	if *name == "" || server.IP == "" {
+		c.fail(exitUsage, fmt.Errorf("--name and --ip are required"))
+	}
+
+	cfg := c.loadConfig()
+	if cfg.GetServer(*name) != nil {
+		c.fail(exitConflict, fmt.Errorf("server with name '%s' already exists", *name))
+	}
+
+	// Servers read locally or over HTTP need no setup
+	server.Interfaces = opts.Interfaces
+	if server.UsesSSH() {
+		server.Interfaces, err = runServerSetup(server.IP, server.Port, server.User, opts)
+		if err != nil {
+			c.fail(exitFailure, fmt.Errorf("setup failed: %w", err))
+		}
+	}
+
+	if err := cfg.AddServer(server); err != nil {
+		c.fail(exitConflict, err)
+	}
+	if err := cfg.Save(); err != nil {
+		c.fail(exitFailure, fmt.Errorf("failed to save config: %w", err))
+	}
+
+	c.succeed(&server, true, fmt.Sprintf("Server '%s' added (%s)", server.Name, strings.Join(server.Interfaces, ",")))
+}
+
+// runUpdateCommand handles "bandwidth-monitor update --name ... [changes]".
+// Changing the IP, user or port, or passing --password-file or --key, re-runs
+// the SSH setup. Changing only the interfaces adds them to vnStat.
+func runUpdateCommand(args []string) {
+	c := &serverCommand{action: "update"}
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	name := fs.String("name", "", "Server to update (required)")
+	newName := fs.String("new-name", "", "Rename the server")
+	ip := fs.String("ip", "", "New IP address or hostname")
+	user := fs.String("user", "", "New SSH user")
+	port := fs.Int("port", 0, "New SSH port")
+	setup := fs.Bool("setup", false, "Re-run the SSH setup with the monitor's key")
+	var auth authFlags
+	auth.register(fs)
+	fs.BoolVar(&c.json, "json", false, "Print the result as JSON")
+	c.parse(fs, args)
+
+	if *name == "" {
+		c.fail(exitUsage, fmt.Errorf("--name is required"))
+	}
+	if *port < 0 || *port > 65535 {
+		c.fail(exitUsage, fmt.Errorf("invalid port %d", *port))
+	}
+	opts, err := auth.options(c.log())
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+	group, tags, err := auth.labels()
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+
+	cfg := c.loadConfig()
+	current := cfg.GetServer(*name)
+	if current == nil {
+		c.fail(exitNotFound, fmt.Errorf("server '%s' not found", *name))
+	}
+	server := *current
+
+	if *newName != "" {
+		server.Name = *newName
+	}
+	if *ip != "" {
+		server.IP = *ip
+	}
+	if *user != "" {
+		server.User = *user
+	}
+	if *port != 0 {
+		server.Port = *port
+	}
+	if opts.Collector == config.CollectorVnStat {
+		server.Collector = ""
+	} else if opts.Collector != "" {
+		server.Collector = opts.Collector
+	}
+	opts.Collector = server.GetCollector()
+	if auth.url != "" {
+		server.URL = auth.url
+	}
+	// An empty --group or --tags clears them
+	if hasFlag(args, "group") {
+		server.Group = group
+	}
+	if hasFlag(args, "tags") {
+		server.Tags = tags
+	}
+	if err := server.ValidateSource(); err != nil {
+		c.fail(exitUsage, err)
+	}
+
+	connectionChanged := server.IP != current.IP || server.User != current.User || server.Port != current.Port
+	switch {
+	case !server.UsesSSH():
+		if len(opts.Interfaces) > 0 {
+			server.Interfaces = opts.Interfaces
+			server.Interface = ""
+		}
+	case *setup || connectionChanged || opts.Password != "" || opts.KeyFile != "":
+		detected, err := runServerSetup(server.IP, server.Port, server.User, opts)
+		if err != nil {
+			c.fail(exitFailure, fmt.Errorf("setup failed: %w", err))
+		}
+		if len(opts.Interfaces) > 0 {
+			server.Interfaces = opts.Interfaces
+		} else {
+			server.Interfaces = mergeInterfaces(detected[0], server.GetInterfaces())
+		}
+		server.Interface = ""
+	case len(opts.Interfaces) > 0:
+		if opts.Collector == config.CollectorVnStat {
+			if err := trackInterfaces(server, opts.Interfaces); err != nil {
+				c.fail(exitFailure, err)
+			}
+		}
+		server.Interfaces = opts.Interfaces
+		server.Interface = ""
+	}
+
+	changed := !sameServer(server, *current)
+	if changed {
+		if err := cfg.UpdateServer(*name, server); err != nil {
+			c.fail(exitConflict, err)
+		}
+		if err := cfg.Save(); err != nil {
+			c.fail(exitFailure, fmt.Errorf("failed to save config: %w", err))
+		}
+	}
+
+	c.succeed(&server, changed, fmt.Sprintf("Server '%s' updated", server.Name))
+}
+
+// runRemoveCommand handles "bandwidth-monitor remove --name ... --yes"
+func runRemoveCommand(args []string) {
+	c := &serverCommand{action: "remove"}
+	fs := flag.NewFlagSet("remove", flag.ContinueOnError)
+	name := fs.String("name", "", "Server to remove (required)")
+	cleanup := fs.Bool("cleanup", false, "Remove the monitor's SSH key and disable vnStat on the server")
+	yes := fs.Bool("yes", false, "Confirm the removal")
+	fs.BoolVar(&c.json, "json", false, "Print the result as JSON")
+	c.parse(fs, args)
+
+	if *name == "" {
+		c.fail(exitUsage, fmt.Errorf("--name is required"))
+	}
+	if !*yes {
+		c.fail(exitUsage, fmt.Errorf("refusing to remove '%s' without --yes", *name))
+	}
+
+	cfg := c.loadConfig()
+	server := cfg.GetServer(*name)
+	if server == nil {
+		c.fail(exitNotFound, fmt.Errorf("server '%s' not found", *name))
+	}
+
+	if *cleanup && server.UsesSSH() {
+		fmt.Fprintf(c.log(), "Cleaning up remote server %s (%s)...\n", server.Name, server.IP)
+		if err := sshclient.CleanupRemoteServer(server.IP, server.Port, server.User); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to cleanup remote server: %v\n", err)
+		}
+	}
+
+	if !cfg.RemoveServer(*name) {
+		c.fail(exitNotFound, fmt.Errorf("server '%s' not found", *name))
+	}
+	if err := cfg.Save(); err != nil {
+		c.fail(exitFailure, fmt.Errorf("failed to save config: %w", err))
+	}
+
+	c.succeed(server, true, fmt.Sprintf("Server '%s' removed", *name))
+}
+
+// trackInterfaces makes sure vnStat on the server tracks every interface
+func trackInterfaces(server config.ServerConfig, interfaces []string) error {
+	privateKey, err := sshclient.LoadPrivateKey()
+	if err != nil {
+		return fmt.Errorf("failed to load SSH private key: %w", err)
+	}
+
+	client, err := sshclient.NewClientWithKey(server.IP, server.Port, server.User, []byte(privateKey))
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer client.Close()
+
+	for _, iface := range interfaces {
+		if err := client.AddVnStatInterface(iface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameServer reports whether an update left a server unchanged
+func sameServer(a, b config.ServerConfig) bool {
+	return a.Name == b.Name && a.IP == b.IP && a.User == b.User && a.Port == b.Port && a.Collector == b.Collector && a.URL == b.URL &&
+		a.Interface == b.Interface && strings.Join(a.Interfaces, ",") == strings.Join(b.Interfaces, ",") &&
+		a.Group == b.Group && strings.Join(a.Tags, ",") == strings.Join(b.Tags, ",")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bandwidth-monitor/config"
+	"bandwidth-monitor/monitor"
 	"bandwidth-monitor/sshclient"
 	"bufio"
 	"fmt"
@@ -44,6 +45,7 @@ After=network.target
 Type=simple
 User=root
 ExecStart=%s
+ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=5
 
@@ -138,11 +140,22 @@ func uninstallService() {
 	fmt.Println("Reloading systemd daemon...")
 	runCommand("systemctl", "daemon-reload")
 
-	fmt.Printf("Do you want to remove the configuration file (config.json) and SSH keys? [y/N]: ")
+	fmt.Printf("Do you want to remove the configuration file (config.json), history data and SSH keys? [y/N]: ")
 	configResp, _ := reader.ReadString('\n')
 	configResp = strings.TrimSpace(strings.ToLower(configResp))
 
 	if configResp == "y" || configResp == "yes" {
+		// Remove history data (location comes from the config, so do it first)
+		dataDir := config.DefaultDataDir
+		if cfg, err := config.Load(); err == nil {
+			dataDir = cfg.GetSettings().GetDataDir()
+		}
+		if err := monitor.RemoveData(dataDir); err != nil {
+			fmt.Printf("Error removing history data: %v\n", err)
+		} else {
+			fmt.Println("✓ History data removed.")
+		}
+
 		// Remove config file
 		configPath := config.GetConfigPath()
 		if err := os.Remove(configPath); err != nil {
@@ -159,6 +172,9 @@ func uninstallService() {
 		if err := os.Remove(keyPath + ".pub"); err != nil && !os.IsNotExist(err) {
 			fmt.Printf("Error removing public key: %v\n", err)
 		}
+		if err := os.Remove(sshclient.KnownHostsPath); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Error removing known hosts file: %v\n", err)
+		}
 
 		// Try to remove directory if empty
 		if err := os.Remove("/etc/bandwidth-monitor"); err == nil {
@@ -0,0 +1,211 @@
+package sshclient
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	// knownHostsMu serializes access to the known_hosts file between pollers
+	knownHostsMu sync.Mutex
+
+	errHostKeyScanned = errors.New("host key scanned")
+)
+
+// HostKeyChangedError is returned when a server presents a host key that
+// differs from the one pinned on first use
+type HostKeyChangedError struct {
+	Host        string
+	Fingerprint string
+	Pinned      []string
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf("host key for %s has CHANGED (got %s, pinned %s). This could be a man-in-the-middle attack. "+
+		"If the server was reinstalled, re-pin it with: bandwidth-monitor update <name> --repin",
+		e.Host, e.Fingerprint, strings.Join(e.Pinned, ", "))
+}
+
+// hostKeyCallback verifies host keys against KnownHostsPath, pinning the key
+// of any host that has not been seen before (trust on first use)
+func hostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	if err := ensureKnownHostsFile(); err != nil {
+		return err
+	}
+
+	callback, err := knownhosts.New(KnownHostsPath)
+	if err != nil {
+		return fmt.Errorf("failed to read known hosts from %s: %w", KnownHostsPath, err)
+	}
+
+	err = callback(hostname, remote, key)
+	if err == nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return err
+	}
+
+	// Unknown host: pin its key
+	if len(keyErr.Want) == 0 {
+		return appendHostKey(hostname, key)
+	}
+
+	pinned := make([]string, 0, len(keyErr.Want))
+	for _, want := range keyErr.Want {
+		pinned = append(pinned, ssh.FingerprintSHA256(want.Key))
+	}
+
+	return &HostKeyChangedError{
+		Host:        hostname,
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Pinned:      pinned,
+	}
+}
+
+// ensureKnownHostsFile creates an empty known_hosts file if it doesn't exist
+func ensureKnownHostsFile() error {
+	if _, err := os.Stat(KnownHostsPath); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(KnownHostsPath), 0755); err != nil {
+		return fmt.Errorf("failed to create known hosts directory: %w", err)
+	}
+
+	f, err := os.OpenFile(KnownHostsPath, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create known hosts file: %w", err)
+	}
+	return f.Close()
+}
+
+// appendHostKey adds a host key line to the known_hosts file
+func appendHostKey(hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(KnownHostsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open known hosts file: %w", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("failed to write known hosts file: %w", err)
+	}
+
+	return nil
+}
+
+// hostAddress returns the host:port string used as the known_hosts key
+func hostAddress(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// RemoveHostKey removes any pinned host key for the given server
+func RemoveHostKey(host string, port int) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	return removeHostKey(hostAddress(host, port))
+}
+
+func removeHostKey(hostname string) error {
+	data, err := os.ReadFile(KnownHostsPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read known hosts file: %w", err)
+	}
+
+	normalized := knownhosts.Normalize(hostname)
+
+	var kept []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 && containsHost(fields[0], normalized) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	content := strings.Join(kept, "\n")
+	if len(kept) > 0 {
+		content += "\n"
+	}
+
+	if err := os.WriteFile(KnownHostsPath, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write known hosts file: %w", err)
+	}
+
+	return nil
+}
+
+// containsHost reports whether a comma-separated known_hosts host field
+// contains the normalized address
+func containsHost(field, normalized string) bool {
+	for _, h := range strings.Split(field, ",") {
+		if h == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanHostKey connects to the server and returns its host key without
+// authenticating or consulting the known_hosts file
+func ScanHostKey(host string, port int) (ssh.PublicKey, error) {
+	var scanned ssh.PublicKey
+
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			scanned = key
+			return errHostKeyScanned
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	client, err := ssh.Dial("tcp", hostAddress(host, port), config)
+	if client != nil {
+		client.Close()
+	}
+	if scanned != nil {
+		return scanned, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	return nil, fmt.Errorf("server did not present a host key")
+}
+
+// PinHostKey replaces any pinned host key for the server with the given key
+func PinHostKey(host string, port int, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	hostname := hostAddress(host, port)
+	if err := removeHostKey(hostname); err != nil {
+		return err
+	}
+
+	return appendHostKey(hostname, key)
+}
@@ -0,0 +1,80 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to convert key: %v", err)
+	}
+	return key
+}
+
+func TestHostKeyTrustOnFirstUse(t *testing.T) {
+	origPath := KnownHostsPath
+	defer func() { KnownHostsPath = origPath }()
+	KnownHostsPath = filepath.Join(t.TempDir(), "known_hosts")
+
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 2222}
+	hostname := "10.0.0.1:2222"
+	key := newTestHostKey(t)
+
+	// Test 1: Unknown host is pinned
+	if err := hostKeyCallback(hostname, remote, key); err != nil {
+		t.Fatalf("First connection should pin the key, got: %v", err)
+	}
+
+	// Test 2: Same key is accepted
+	if err := hostKeyCallback(hostname, remote, key); err != nil {
+		t.Errorf("Pinned key should be accepted, got: %v", err)
+	}
+
+	// Test 3: Changed key is rejected
+	otherKey := newTestHostKey(t)
+	err := hostKeyCallback(hostname, remote, otherKey)
+	var changed *HostKeyChangedError
+	if !errors.As(err, &changed) {
+		t.Fatalf("Expected HostKeyChangedError, got: %v", err)
+	}
+	if changed.Fingerprint != ssh.FingerprintSHA256(otherKey) {
+		t.Errorf("Unexpected fingerprint in error: %s", changed.Fingerprint)
+	}
+
+	// Test 4: Other hosts are unaffected
+	if err := hostKeyCallback("10.0.0.2:22", remote, otherKey); err != nil {
+		t.Errorf("Different host should be pinned independently, got: %v", err)
+	}
+
+	// Test 5: Re-pinning accepts the new key
+	if err := PinHostKey("10.0.0.1", 2222, otherKey); err != nil {
+		t.Fatalf("PinHostKey failed: %v", err)
+	}
+	if err := hostKeyCallback(hostname, remote, otherKey); err != nil {
+		t.Errorf("Re-pinned key should be accepted, got: %v", err)
+	}
+	if err := hostKeyCallback(hostname, remote, key); err == nil {
+		t.Errorf("Old key should be rejected after re-pin")
+	}
+
+	// Test 6: Removing the key makes the host unknown again
+	if err := RemoveHostKey("10.0.0.1", 2222); err != nil {
+		t.Fatalf("RemoveHostKey failed: %v", err)
+	}
+	if err := hostKeyCallback(hostname, remote, key); err != nil {
+		t.Errorf("Host should be re-pinned after removal, got: %v", err)
+	}
+}
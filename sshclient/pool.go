@@ -0,0 +1,262 @@
+package sshclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultKeepAliveInterval = 30 * time.Second
+	DefaultIdleTimeout       = 5 * time.Minute
+	DefaultMinBackoff        = 5 * time.Second
+	DefaultMaxBackoff        = 5 * time.Minute
+)
+
+// Pool keeps one persistent key-authenticated SSH connection per host so
+// that pollers don't perform a full handshake on every tick
+type Pool struct {
+	privateKey []byte
+
+	keepAliveInterval time.Duration
+	idleTimeout       time.Duration
+	minBackoff        time.Duration
+	maxBackoff        time.Duration
+
+	mu       sync.Mutex
+	conns    map[string]*pooledConn
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// pooledConn holds the connection state for a single host
+type pooledConn struct {
+	mu          sync.Mutex
+	client      *Client
+	lastUsed    time.Time
+	failures    int
+	nextAttempt time.Time
+	lastErr     error
+}
+
+// NewPool creates a connection pool using the given private key and starts
+// its keepalive/eviction loop
+func NewPool(privateKey []byte) *Pool {
+	p := &Pool{
+		privateKey:        privateKey,
+		keepAliveInterval: DefaultKeepAliveInterval,
+		idleTimeout:       DefaultIdleTimeout,
+		minBackoff:        DefaultMinBackoff,
+		maxBackoff:        DefaultMaxBackoff,
+		conns:             make(map[string]*pooledConn),
+		stopChan:          make(chan struct{}),
+	}
+
+	go p.maintain()
+
+	return p
+}
+
+func poolKey(host string, port int, user string) string {
+	return fmt.Sprintf("%s@%s:%d", user, host, port)
+}
+
+// entry returns the pool entry for a host, creating it if needed
+func (p *Pool) entry(key string) *pooledConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc, ok := p.conns[key]
+	if !ok {
+		pc = &pooledConn{}
+		p.conns[key] = pc
+	}
+	return pc
+}
+
+// Get returns a live connection for the host, dialing a new one if there is
+// none. After a failed dial, further attempts are refused until the backoff
+// period has elapsed.
+//
+// The returned client is shared and stays owned by the pool: a failed
+// keepalive, idle eviction, Invalidate or Remove may close it while a command
+// is running, in which case the command fails and the next Get dials again.
+// Callers must not close it themselves.
+func (p *Pool) Get(host string, port int, user string) (*Client, error) {
+	pc := p.entry(poolKey(host, port, user))
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	now := time.Now()
+	if pc.client != nil {
+		pc.lastUsed = now
+		return pc.client, nil
+	}
+
+	if now.Before(pc.nextAttempt) {
+		return nil, fmt.Errorf("reconnect backoff (retry in %s): %w", pc.nextAttempt.Sub(now).Round(time.Second), pc.lastErr)
+	}
+
+	client, err := NewClientWithKey(host, port, user, p.privateKey)
+	if err != nil {
+		pc.failures++
+		pc.lastErr = err
+		pc.nextAttempt = now.Add(p.backoff(pc.failures))
+		return nil, err
+	}
+
+	pc.client = client
+	pc.lastUsed = now
+	pc.failures = 0
+	pc.lastErr = nil
+	pc.nextAttempt = time.Time{}
+
+	return client, nil
+}
+
+// Invalidate closes and drops the connection for a host. Callers should use it
+// when a command fails, since the underlying connection may be broken.
+func (p *Pool) Invalidate(host string, port int, user string) {
+	p.mu.Lock()
+	pc, ok := p.conns[poolKey(host, port, user)]
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.client != nil {
+		pc.client.Close()
+		pc.client = nil
+	}
+}
+
+// Remove closes the connection for a host and forgets its backoff state
+func (p *Pool) Remove(host string, port int, user string) {
+	key := poolKey(host, port, user)
+
+	p.mu.Lock()
+	pc, ok := p.conns[key]
+	delete(p.conns, key)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.client != nil {
+		pc.client.Close()
+		pc.client = nil
+	}
+}
+
+// Close stops the maintenance loop and closes all pooled connections
+func (p *Pool) Close() {
+	p.stopOnce.Do(func() {
+		close(p.stopChan)
+	})
+
+	p.mu.Lock()
+	conns := p.conns
+	p.conns = make(map[string]*pooledConn)
+	p.mu.Unlock()
+
+	for _, pc := range conns {
+		pc.mu.Lock()
+		if pc.client != nil {
+			pc.client.Close()
+			pc.client = nil
+		}
+		pc.mu.Unlock()
+	}
+}
+
+// backoff returns the exponential reconnect delay for the given failure count
+func (p *Pool) backoff(failures int) time.Duration {
+	d := p.minBackoff
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+	return d
+}
+
+// maintain periodically sends keepalives and evicts idle connections
+func (p *Pool) maintain() {
+	ticker := time.NewTicker(p.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			conns := make([]*pooledConn, 0, len(p.conns))
+			for _, pc := range p.conns {
+				conns = append(conns, pc)
+			}
+			p.mu.Unlock()
+
+			// Hosts are checked in parallel so that one unresponsive
+			// connection doesn't delay the others
+			var wg sync.WaitGroup
+			for _, pc := range conns {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					p.checkConn(pc)
+				}()
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// checkConn evicts an idle connection or verifies a live one with a
+// keepalive. The keepalive runs without holding the entry lock and gives up
+// after one keepalive interval, so a half-open connection neither blocks
+// Get nor survives until the kernel times it out.
+func (p *Pool) checkConn(pc *pooledConn) {
+	pc.mu.Lock()
+	client := pc.client
+	if client == nil {
+		pc.mu.Unlock()
+		return
+	}
+	if time.Since(pc.lastUsed) > p.idleTimeout {
+		client.Close()
+		pc.client = nil
+		pc.mu.Unlock()
+		return
+	}
+	pc.mu.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- client.SendKeepAlive()
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(p.keepAliveInterval):
+		err = fmt.Errorf("keepalive timed out")
+	}
+	if err == nil {
+		return
+	}
+
+	// Closing the client also ends a keepalive that is still waiting
+	client.Close()
+	pc.mu.Lock()
+	if pc.client == client {
+		pc.client = nil
+	}
+	pc.mu.Unlock()
+}
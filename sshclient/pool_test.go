@@ -0,0 +1,148 @@
+package sshclient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPoolBackoff(t *testing.T) {
+	p := &Pool{
+		minBackoff: 5 * time.Second,
+		maxBackoff: time.Minute,
+	}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{5, time.Minute},
+		{10, time.Minute},
+	}
+
+	for _, c := range cases {
+		if got := p.backoff(c.failures); got != c.want {
+			t.Errorf("backoff(%d) = %s, want %s", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestPoolGetRespectsBackoff(t *testing.T) {
+	// Grab a free port and close the listener so dialing it is refused
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	p := NewPool(pem.EncodeToMemory(block))
+	defer p.Close()
+
+	if _, err := p.Get("127.0.0.1", port, "root"); err == nil {
+		t.Fatal("Expected first Get to fail")
+	}
+
+	_, err = p.Get("127.0.0.1", port, "root")
+	if err == nil || !strings.Contains(err.Error(), "reconnect backoff") {
+		t.Errorf("Expected backoff error on second Get, got %v", err)
+	}
+
+	// Remove forgets the backoff state
+	p.Remove("127.0.0.1", port, "root")
+	_, err = p.Get("127.0.0.1", port, "root")
+	if err == nil || strings.Contains(err.Error(), "reconnect backoff") {
+		t.Errorf("Expected a fresh dial attempt after Remove, got %v", err)
+	}
+}
+
+func TestPoolKeepAliveTimeout(t *testing.T) {
+	// An SSH server that accepts the connection but never answers requests,
+	// like a peer behind a half-open TCP connection
+	hostKey, err := ssh.NewSignerFromKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("Failed to create host key: %v", err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		serverSide, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		_, chans, reqs, err := ssh.NewServerConn(serverSide, serverConfig)
+		if err != nil {
+			return
+		}
+		go func() {
+			for range reqs {
+			}
+		}()
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "no channels")
+		}
+	}()
+
+	clientSide, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	conn, chans, reqs, err := ssh.NewClientConn(clientSide, ln.Addr().String(), &ssh.ClientConfig{
+		User:            "root",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	client := &Client{client: ssh.NewClient(conn, chans, reqs)}
+
+	p := &Pool{keepAliveInterval: 200 * time.Millisecond, idleTimeout: time.Hour}
+	pc := &pooledConn{client: client, lastUsed: time.Now()}
+
+	done := make(chan struct{})
+	go func() {
+		p.checkConn(pc)
+		close(done)
+	}()
+
+	// The entry stays usable while the keepalive is pending
+	time.Sleep(50 * time.Millisecond)
+	if !pc.mu.TryLock() {
+		t.Fatal("Entry locked during keepalive")
+	}
+	pc.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("checkConn did not give up on an unanswered keepalive")
+	}
+	if pc.client != nil {
+		t.Error("Expected the unresponsive connection to be dropped")
+	}
+}
@@ -16,6 +16,7 @@ var (
 	KeyPath        = "/etc/bandwidth-monitor/id_ed25519"
 	PublicKeyPath  = "/etc/bandwidth-monitor/id_ed25519.pub"
 	OldKeyName     = "bandwidth_monitor_ed25519"
+	KnownHostsPath = "/etc/bandwidth-monitor/known_hosts"
 )
 
 // Client represents an SSH client
@@ -31,7 +32,7 @@ func NewClient(host string, port int, user, password string) (*Client, error) {
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 		Timeout:         10 * time.Second,
 	}
 
@@ -58,7 +59,7 @@ func NewClientWithKey(host string, port int, user string, privateKey []byte) (*C
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(signer),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 		Timeout:         10 * time.Second,
 	}
 
@@ -78,6 +79,12 @@ func (c *Client) Close() error {
 	return c.client.Close()
 }
 
+// SendKeepAlive sends an OpenSSH keepalive request to verify the connection is alive
+func (c *Client) SendKeepAlive() error {
+	_, _, err := c.client.SendRequest("keepalive@openssh.com", true, nil)
+	return err
+}
+
 // RunCommand executes a command on the remote server and returns output
 func (c *Client) RunCommand(cmd string) (string, error) {
 	session, err := c.client.NewSession()
@@ -161,9 +168,23 @@ func (c *Client) DetectInterface() (string, error) {
 	return iface, nil
 }
 
-// GetVnStatData retrieves vnStat JSON data for a specific interface
-func (c *Client) GetVnStatData(iface string) (string, error) {
-	cmd := fmt.Sprintf("vnstat -i %s --json", iface)
+// ListInterfaces returns the names of the network interfaces on the server
+func (c *Client) ListInterfaces() ([]string, error) {
+	output, err := c.RunCommand("ls /sys/class/net")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	return strings.Fields(output), nil
+}
+
+// GetVnStatData retrieves vnStat JSON data for the given interfaces. With
+// more than one interface, the data of all interfaces in the vnStat database
+// is returned and the caller picks the ones it needs.
+func (c *Client) GetVnStatData(ifaces ...string) (string, error) {
+	cmd := "vnstat --json"
+	if len(ifaces) == 1 {
+		cmd = fmt.Sprintf("vnstat -i %s --json", ifaces[0])
+	}
 	output, err := c.RunCommand(cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get vnStat data: %w", err)
@@ -172,6 +193,27 @@ func (c *Client) GetVnStatData(iface string) (string, error) {
 	return output, nil
 }
 
+// ReadNetDev returns /proc/uptime followed by /proc/net/dev, for sampling
+// interface counters on hosts without vnStat
+func (c *Client) ReadNetDev() (string, error) {
+	output, err := c.RunCommand("cat /proc/uptime /proc/net/dev")
+	if err != nil {
+		return "", fmt.Errorf("failed to read /proc/net/dev: %w", err)
+	}
+
+	return output, nil
+}
+
+// AddVnStatInterface adds an interface to the vnStat database unless it is
+// already being tracked
+func (c *Client) AddVnStatInterface(iface string) error {
+	cmd := fmt.Sprintf("vnstat -i %s --json >/dev/null 2>&1 || vnstat --add -i %s", iface, iface)
+	if _, err := c.RunCommand(cmd); err != nil {
+		return fmt.Errorf("failed to add interface %s to vnStat: %w", iface, err)
+	}
+	return nil
+}
+
 // CopySSHKey copies the SSH public key to the remote server
 func (c *Client) CopySSHKey(publicKey string) error {
 	// Ensure .ssh directory exists
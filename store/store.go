@@ -0,0 +1,586 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AggregateSeries is the series name used for the sum of all servers
+const AggregateSeries = "_aggregate"
+
+// recordSize is the on-disk size of a sample: timestamp, rx, tx (little endian)
+const recordSize = 24
+
+// filePrefix keeps escaped series names from colliding with "." or ".."
+const filePrefix = "s_"
+
+// Sample is a single rate observation in bytes per second
+type Sample struct {
+	Timestamp int64  `json:"timestamp"` // Unix seconds
+	Rx        uint64 `json:"rx"`
+	Tx        uint64 `json:"tx"`
+}
+
+// Tier describes one retention level of the store. The first tier holds raw
+// samples; later tiers hold averages over Resolution-sized buckets.
+type Tier struct {
+	Name       string
+	Resolution time.Duration // Zero for raw samples
+	Retention  time.Duration // Zero keeps data forever
+}
+
+// DefaultTiers keeps raw samples for 48h, 5-minute rollups for 90 days and
+// hourly rollups forever
+var DefaultTiers = []Tier{
+	{Name: "raw", Resolution: 0, Retention: 48 * time.Hour},
+	{Name: "5m", Resolution: 5 * time.Minute, Retention: 90 * 24 * time.Hour},
+	{Name: "1h", Resolution: time.Hour, Retention: 0},
+}
+
+// accumulator collects raw samples for the rollup bucket currently in progress
+type accumulator struct {
+	bucket int64
+	sumRx  uint64
+	sumTx  uint64
+	count  uint64
+}
+
+func (a *accumulator) sample() Sample {
+	return Sample{
+		Timestamp: a.bucket,
+		Rx:        a.sumRx / a.count,
+		Tx:        a.sumTx / a.count,
+	}
+}
+
+// Store is an append-only time-series store with one file per series and tier
+type Store struct {
+	dir   string
+	tiers []Tier
+	now   func() time.Time
+
+	mu      sync.Mutex
+	rollups map[string][]*accumulator // series -> accumulator per rollup tier
+	last    map[string]int64          // series -> timestamp of the newest raw sample
+}
+
+// Open opens (or creates) a store in dir using DefaultTiers
+func Open(dir string) (*Store, error) {
+	return OpenWithTiers(dir, DefaultTiers)
+}
+
+// OpenWithTiers opens (or creates) a store in dir using custom tiers. The first
+// tier must be the raw tier.
+func OpenWithTiers(dir string, tiers []Tier) (*Store, error) {
+	if len(tiers) == 0 || tiers[0].Resolution != 0 {
+		return nil, fmt.Errorf("first tier must be a raw tier")
+	}
+
+	for _, t := range tiers {
+		if err := os.MkdirAll(filepath.Join(dir, t.Name), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+	}
+
+	return &Store{
+		dir:     dir,
+		tiers:   tiers,
+		now:     time.Now,
+		rollups: make(map[string][]*accumulator),
+		last:    make(map[string]int64),
+	}, nil
+}
+
+// Dir returns the directory the store writes to
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) path(tier Tier, series string) string {
+	return filepath.Join(s.dir, tier.Name, filePrefix+url.PathEscape(series)+".dat")
+}
+
+// Append records a raw sample and updates the rollup tiers. Samples not
+// newer than the last one of the series, e.g. after the clock was stepped
+// back, are dropped so that files stay sorted for binary search.
+func (s *Store) Append(series string, sample Sample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accs, err := s.accumulators(series)
+	if err != nil {
+		return err
+	}
+
+	last, ok := s.last[series]
+	if !ok {
+		record, found, err := lastRecord(s.path(s.tiers[0], series))
+		if err != nil {
+			return err
+		}
+		if found {
+			last, ok = record.Timestamp, true
+		}
+	}
+	if ok && sample.Timestamp <= last {
+		s.last[series] = last
+		return nil
+	}
+
+	if err := appendRecords(s.path(s.tiers[0], series), sample); err != nil {
+		return err
+	}
+	s.last[series] = sample.Timestamp
+
+	for i, tier := range s.tiers[1:] {
+		if err := s.addToRollup(tier, series, accs[i], sample); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addToRollup feeds a sample into a rollup accumulator, flushing the previous
+// bucket to disk once a sample for a newer bucket arrives
+func (s *Store) addToRollup(tier Tier, series string, acc *accumulator, sample Sample) error {
+	res := int64(tier.Resolution / time.Second)
+	bucket := sample.Timestamp - sample.Timestamp%res
+
+	if acc.count > 0 {
+		if bucket < acc.bucket {
+			// Clock went backwards; the bucket is already closed
+			return nil
+		}
+		if bucket > acc.bucket {
+			if err := appendRecords(s.path(tier, series), acc.sample()); err != nil {
+				return err
+			}
+			*acc = accumulator{}
+		}
+	}
+
+	acc.bucket = bucket
+	acc.sumRx += sample.Rx
+	acc.sumTx += sample.Tx
+	acc.count++
+
+	return nil
+}
+
+// accumulators returns the rollup accumulators of a series. On first use they
+// are rebuilt from raw samples newer than the last flushed rollup bucket, so
+// a restart doesn't lose the bucket that was in progress.
+func (s *Store) accumulators(series string) ([]*accumulator, error) {
+	if accs, ok := s.rollups[series]; ok {
+		return accs, nil
+	}
+
+	raw, err := readRange(s.path(s.tiers[0], series), 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	accs := make([]*accumulator, len(s.tiers)-1)
+	for i, tier := range s.tiers[1:] {
+		accs[i] = &accumulator{}
+
+		last, ok, err := lastRecord(s.path(tier, series))
+		if err != nil {
+			return nil, err
+		}
+
+		res := int64(tier.Resolution / time.Second)
+		for _, sample := range raw {
+			if ok && sample.Timestamp < last.Timestamp+res {
+				continue
+			}
+			if err := s.addToRollup(tier, series, accs[i], sample); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.rollups[series] = accs
+	return accs, nil
+}
+
+// Query returns samples of a series between from and to (inclusive). It reads
+// the finest tier that still covers from and whose resolution fits step, and
+// averages samples into step-sized buckets when step is coarser than the tier.
+// A zero step returns the selected tier's native resolution.
+func (s *Store) Query(series string, from, to time.Time, step time.Duration) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tierIdx := s.selectTier(from, step)
+	tier := s.tiers[tierIdx]
+
+	samples, err := readRange(s.path(tier, series), from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the rollup bucket still in progress
+	if tierIdx > 0 {
+		if accs, ok := s.rollups[series]; ok {
+			acc := accs[tierIdx-1]
+			if acc.count > 0 && acc.bucket >= from.Unix() && acc.bucket <= to.Unix() {
+				samples = append(samples, acc.sample())
+			}
+		}
+	}
+
+	if step > tier.Resolution && step >= time.Second {
+		samples = downsample(samples, int64(step/time.Second))
+	}
+
+	return samples, nil
+}
+
+// selectTier picks the finest tier whose retention covers from and whose
+// resolution is not coarser than step. If step is finer than every covering
+// tier, the finest covering tier is used.
+func (s *Store) selectTier(from time.Time, step time.Duration) int {
+	now := s.now()
+	covering := -1
+	for i, t := range s.tiers {
+		if t.Retention > 0 && from.Before(now.Add(-t.Retention)) {
+			continue
+		}
+		if covering < 0 {
+			covering = i
+		}
+		if step == 0 || t.Resolution <= step {
+			return i
+		}
+	}
+	if covering >= 0 {
+		return covering
+	}
+	return len(s.tiers) - 1
+}
+
+// downsample averages samples into step-aligned buckets
+func downsample(samples []Sample, step int64) []Sample {
+	var result []Sample
+	var acc accumulator
+
+	for _, sample := range samples {
+		bucket := sample.Timestamp - sample.Timestamp%step
+		if acc.count > 0 && bucket != acc.bucket {
+			result = append(result, acc.sample())
+			acc = accumulator{}
+		}
+		acc.bucket = bucket
+		acc.sumRx += sample.Rx
+		acc.sumTx += sample.Tx
+		acc.count++
+	}
+	if acc.count > 0 {
+		result = append(result, acc.sample())
+	}
+
+	return result
+}
+
+// Series returns the names of all series that have raw data
+func (s *Store) Series() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, s.tiers[0].Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, ".dat") {
+			continue
+		}
+		series, err := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), ".dat"))
+		if err != nil {
+			continue
+		}
+		names = append(names, series)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Compact drops samples older than each tier's retention
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, tier := range s.tiers {
+		if tier.Retention == 0 {
+			continue
+		}
+
+		dir := filepath.Join(s.dir, tier.Name)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to list %s tier: %w", tier.Name, err)
+		}
+
+		cutoff := now.Add(-tier.Retention).Unix()
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".dat") {
+				continue
+			}
+			if err := truncateBefore(filepath.Join(dir, e.Name()), cutoff); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Purge deletes the series files and tier directories of a store opened in
+// dir with DefaultTiers, and dir itself once nothing else is left in it.
+// Files the store did not create are kept.
+func Purge(dir string) error {
+	for _, tier := range DefaultTiers {
+		tierDir := filepath.Join(dir, tier.Name)
+		entries, err := os.ReadDir(tierDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list %s tier: %w", tier.Name, err)
+		}
+
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() || !strings.HasPrefix(name, filePrefix) ||
+				!(strings.HasSuffix(name, ".dat") || strings.HasSuffix(name, ".dat.tmp")) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(tierDir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove series file: %w", err)
+			}
+		}
+		removeIfEmpty(tierDir)
+	}
+
+	removeIfEmpty(dir)
+	return nil
+}
+
+// removeIfEmpty removes a directory unless something is left in it
+func removeIfEmpty(dir string) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 0 {
+		os.Remove(dir)
+	}
+}
+
+// Remove deletes all data of a series
+func (s *Store) Remove(series string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.rollups, series)
+	delete(s.last, series)
+	for _, tier := range s.tiers {
+		if err := os.Remove(s.path(tier, series)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove series: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func encodeRecord(buf []byte, sample Sample) {
+	binary.LittleEndian.PutUint64(buf[0:8], uint64(sample.Timestamp))
+	binary.LittleEndian.PutUint64(buf[8:16], sample.Rx)
+	binary.LittleEndian.PutUint64(buf[16:24], sample.Tx)
+}
+
+func decodeRecord(buf []byte) Sample {
+	return Sample{
+		Timestamp: int64(binary.LittleEndian.Uint64(buf[0:8])),
+		Rx:        binary.LittleEndian.Uint64(buf[8:16]),
+		Tx:        binary.LittleEndian.Uint64(buf[16:24]),
+	}
+}
+
+// appendRecords appends samples to a series file
+func appendRecords(path string, samples ...Sample) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open series file: %w", err)
+	}
+	defer f.Close()
+
+	// Drop a torn trailing record left by a crash so records stay aligned
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat series file: %w", err)
+	}
+	if rem := info.Size() % recordSize; rem != 0 {
+		if err := f.Truncate(info.Size() - rem); err != nil {
+			return fmt.Errorf("failed to repair series file: %w", err)
+		}
+	}
+
+	buf := make([]byte, recordSize*len(samples))
+	for i, sample := range samples {
+		encodeRecord(buf[i*recordSize:], sample)
+	}
+
+	if _, err := f.Write(buf); err != nil {
+		return fmt.Errorf("failed to write series file: %w", err)
+	}
+
+	return nil
+}
+
+// recordCount returns the number of complete records in a file, ignoring a
+// torn trailing write
+func recordCount(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size() / recordSize, nil
+}
+
+// searchRecords returns the index of the first record with Timestamp >= ts.
+// Records are appended in time order, so the file is sorted by timestamp.
+func searchRecords(f *os.File, n int64, ts int64) (int64, error) {
+	var readErr error
+	buf := make([]byte, 8)
+
+	idx := sort.Search(int(n), func(i int) bool {
+		if _, err := f.ReadAt(buf, int64(i)*recordSize); err != nil {
+			readErr = err
+			return true
+		}
+		return int64(binary.LittleEndian.Uint64(buf)) >= ts
+	})
+
+	return int64(idx), readErr
+}
+
+// readRange reads records with from <= Timestamp <= to. A negative to reads
+// until the end of the file.
+func readRange(path string, from, to int64) ([]Sample, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open series file: %w", err)
+	}
+	defer f.Close()
+
+	n, err := recordCount(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat series file: %w", err)
+	}
+
+	start, err := searchRecords(f, n, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search series file: %w", err)
+	}
+
+	end := n
+	if to >= 0 {
+		end, err = searchRecords(f, n, to+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search series file: %w", err)
+		}
+	}
+
+	if end <= start {
+		return nil, nil
+	}
+
+	buf := make([]byte, (end-start)*recordSize)
+	if _, err := f.ReadAt(buf, start*recordSize); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read series file: %w", err)
+	}
+
+	samples := make([]Sample, 0, end-start)
+	for i := int64(0); i < end-start; i++ {
+		samples = append(samples, decodeRecord(buf[i*recordSize:]))
+	}
+
+	return samples, nil
+}
+
+// lastRecord returns the newest record in a file
+func lastRecord(path string) (Sample, bool, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Sample{}, false, nil
+	}
+	if err != nil {
+		return Sample{}, false, fmt.Errorf("failed to open series file: %w", err)
+	}
+	defer f.Close()
+
+	n, err := recordCount(f)
+	if err != nil {
+		return Sample{}, false, fmt.Errorf("failed to stat series file: %w", err)
+	}
+	if n == 0 {
+		return Sample{}, false, nil
+	}
+
+	buf := make([]byte, recordSize)
+	if _, err := f.ReadAt(buf, (n-1)*recordSize); err != nil {
+		return Sample{}, false, fmt.Errorf("failed to read series file: %w", err)
+	}
+
+	return decodeRecord(buf), true, nil
+}
+
+// truncateBefore rewrites a file without records older than cutoff
+func truncateBefore(path string, cutoff int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open series file: %w", err)
+	}
+
+	n, err := recordCount(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat series file: %w", err)
+	}
+
+	start, err := searchRecords(f, n, cutoff)
+	if err != nil || start == 0 {
+		f.Close()
+		return err
+	}
+
+	buf := make([]byte, (n-start)*recordSize)
+	_, err = f.ReadAt(buf, start*recordSize)
+	f.Close()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read series file: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf, 0644); err != nil {
+		return fmt.Errorf("failed to write series file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace series file: %w", err)
+	}
+
+	return nil
+}
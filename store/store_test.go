@@ -0,0 +1,196 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndRollup(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	// Start on an hour boundary so buckets are easy to reason about
+	base := time.Date(2026, 2, 6, 14, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return base.Add(time.Hour) }
+
+	// 12 minutes of samples every 5 seconds: rx=100 in the first 5 minutes,
+	// rx=200 in the second, rx=300 in the third (partial) bucket
+	for i := 0; i < 12*12; i++ {
+		ts := base.Add(time.Duration(i) * 5 * time.Second)
+		rx := uint64(100 * (1 + i/60))
+		if err := s.Append("server1", Sample{Timestamp: ts.Unix(), Rx: rx, Tx: rx * 2}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// Test 1: Raw query returns every sample in range
+	raw, err := s.Query("server1", base, base.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(raw) != 13 {
+		t.Errorf("Expected 13 raw samples, got %d", len(raw))
+	}
+
+	// Test 2: 5-minute rollups (two flushed + one in progress)
+	rollups, err := s.Query("server1", base, base.Add(time.Hour), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rollups) != 3 {
+		t.Fatalf("Expected 3 rollup samples, got %d", len(rollups))
+	}
+	for i, want := range []uint64{100, 200, 300} {
+		if rollups[i].Rx != want {
+			t.Errorf("Rollup %d Rx = %d, want %d", i, rollups[i].Rx, want)
+		}
+		if rollups[i].Tx != want*2 {
+			t.Errorf("Rollup %d Tx = %d, want %d", i, rollups[i].Tx, want*2)
+		}
+	}
+
+	// Test 3: Step coarser than the tier is downsampled
+	downsampled, err := s.Query("server1", base, base.Add(time.Hour), 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(downsampled) != 2 || downsampled[0].Rx != 150 {
+		t.Errorf("Unexpected downsampled result: %+v", downsampled)
+	}
+
+	// Test 4: Reopening rebuilds the in-progress bucket from raw samples.
+	// Pretend three days have passed so the raw tier no longer covers the
+	// range and the query is served from the 5-minute tier.
+	s2, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	s2.now = func() time.Time { return base.Add(72 * time.Hour) }
+	next := base.Add(15 * time.Minute)
+	if err := s2.Append("server1", Sample{Timestamp: next.Unix(), Rx: 999, Tx: 999}); err != nil {
+		t.Fatalf("Append after reopen failed: %v", err)
+	}
+	rollups, err = s2.Query("server1", base, base.Add(time.Hour), 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(rollups) != 4 || rollups[2].Rx != 300 || rollups[3].Rx != 999 {
+		t.Errorf("Unexpected rollups after reopen: %+v", rollups)
+	}
+
+	series, err := s2.Series()
+	if err != nil || len(series) != 1 || series[0] != "server1" {
+		t.Errorf("Series() = %v, %v", series, err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := OpenWithTiers(dir, []Tier{{Name: "raw", Retention: time.Hour}})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	base := time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		ts := base.Add(time.Duration(i) * 30 * time.Minute)
+		if err := s.Append("a/../b", Sample{Timestamp: ts.Unix(), Rx: uint64(i)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// Series names must not escape the data directory
+	entries, _ := os.ReadDir(filepath.Join(dir, "raw"))
+	if len(entries) != 1 {
+		t.Fatalf("Expected a single series file, got %d", len(entries))
+	}
+
+	s.now = func() time.Time { return base.Add(2 * time.Hour) }
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+
+	samples, err := s.Query("a/../b", base, base.Add(3*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(samples) != 2 || samples[0].Rx != 2 {
+		t.Errorf("Unexpected samples after compaction: %+v", samples)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := s.Append("server1", Sample{Timestamp: time.Now().Unix()}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	// Files the store didn't create survive, and so does the directory
+	other := filepath.Join(dir, "raw", "notes.txt")
+	if err := os.WriteFile(other, nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := Purge(dir); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Purge removed a foreign file: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "raw", "s_server1.dat")); !os.IsNotExist(err) {
+		t.Errorf("Series file not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "5m")); !os.IsNotExist(err) {
+		t.Errorf("Empty tier directory not removed: %v", err)
+	}
+
+	os.Remove(other)
+	if err := Purge(dir); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Empty data directory not removed: %v", err)
+	}
+}
+
+func TestAppendDropsOutOfOrderSamples(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenWithTiers(dir, []Tier{{Name: "raw"}})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	base := time.Date(2026, 2, 6, 0, 0, 0, 0, time.UTC).Unix()
+	for _, ts := range []int64{base, base + 60, base + 30, base + 60, base + 120} {
+		if err := s.Append("server1", Sample{Timestamp: ts, Rx: uint64(ts - base)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A reopened store knows the last timestamp from the file
+	s, err = OpenWithTiers(dir, []Tier{{Name: "raw"}})
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if err := s.Append("server1", Sample{Timestamp: base + 90, Rx: 90}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	samples, err := s.Query("server1", time.Unix(base, 0), time.Unix(base+300, 0), 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(samples) != 3 || samples[1].Rx != 60 || samples[2].Rx != 120 {
+		t.Errorf("Expected the samples going back in time to be dropped, got %+v", samples)
+	}
+}
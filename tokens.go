@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bandwidth-monitor/config"
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// runTokenCommand handles "bandwidth-monitor token <action> ..."
+func runTokenCommand(args []string) {
+	if len(args) == 0 {
+		printTokenUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		listTokens(loadTokenConfig())
+	case "create":
+		fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+		readOnly := fs.Bool("read-only", false, "Allow only GET requests")
+		expires := fs.String("expires", "", "Lifetime such as 90d (never expires if empty)")
+
+		// The name may come before or after the flags
+		name, rest := "", args[1:]
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+			name, rest = rest[0], rest[1:]
+		}
+		parseFlags(fs, rest)
+		extra := fs.Args()
+		if name == "" && len(extra) > 0 {
+			name, extra = extra[0], extra[1:]
+		}
+		if len(extra) > 0 {
+			fmt.Printf("Error: unexpected argument '%s'\n\n", extra[0])
+			printTokenUsage()
+			os.Exit(exitUsage)
+		}
+		if name == "" {
+			printTokenUsage()
+			os.Exit(exitUsage)
+		}
+		if err := createToken(loadTokenConfig(), name, *readOnly, *expires); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "revoke":
+		if len(args) < 2 {
+			printTokenUsage()
+			os.Exit(1)
+		}
+		cfg := loadTokenConfig()
+		err := cfg.RemoveToken(args[1])
+		if err == nil {
+			err = cfg.Save()
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Token '%s' revoked\n", args[1])
+	default:
+		fmt.Printf("Unknown token action: %s\n\n", args[0])
+		printTokenUsage()
+		os.Exit(1)
+	}
+}
+
+// loadTokenConfig loads the config, exiting on errors
+func loadTokenConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg
+}
+
+func printTokenUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  bandwidth-monitor token list")
+	fmt.Println("  bandwidth-monitor token create <name> [--read-only] [--expires 90d]")
+	fmt.Println("  bandwidth-monitor token revoke <name>")
+}
+
+// createToken adds an API token, saves the config and prints the secret once
+func createToken(cfg *config.Config, name string, readOnly bool, expires string) error {
+	lifetime, err := config.ParseExpiry(expires)
+	if err != nil {
+		return err
+	}
+	secret, err := cfg.AddToken(name, readOnly, lifetime, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Token '%s' created. Copy it now, it will not be shown again:\n", name)
+	fmt.Println()
+	fmt.Printf("  %s\n", secret)
+	fmt.Println()
+	fmt.Println("Use it with: Authorization: Bearer <token>")
+	return nil
+}
+
+// listTokens prints all API tokens with their scope and expiry
+func listTokens(cfg *config.Config) {
+	tokens := cfg.GetTokens()
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens configured.")
+		return
+	}
+
+	now := time.Now()
+	fmt.Println("API tokens:")
+	for _, t := range tokens {
+		scope := "full"
+		if t.ReadOnly {
+			scope = "read-only"
+		}
+		expiry := "never expires"
+		if t.ExpiresAt != nil {
+			expiry = "expires " + t.ExpiresAt.Local().Format("2006-01-02 15:04")
+			if t.Expired(now) {
+				expiry = "EXPIRED " + t.ExpiresAt.Local().Format("2006-01-02 15:04")
+			}
+		}
+		fmt.Printf("  - %-20s %-10s created %s, %s\n", t.Name, scope, t.CreatedAt.Local().Format("2006-01-02"), expiry)
+	}
+}
+
+// manageTokensMenu is the interactive API token management screen
+func manageTokensMenu(cfg *config.Config) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println()
+	listTokens(cfg)
+	fmt.Println()
+	fmt.Println("1. Create Token")
+	fmt.Println("2. Revoke Token")
+	fmt.Print("Select option: ")
+
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	var err error
+	switch input {
+	case "1":
+		fmt.Print("Token name: ")
+		name, _ := reader.ReadString('\n')
+		fmt.Print("Read-only? (y/n) [y]: ")
+		ro, _ := reader.ReadString('\n')
+		ro = strings.TrimSpace(strings.ToLower(ro))
+		fmt.Print("Expires in (e.g. 90d, 12h, empty = never): ")
+		expires, _ := reader.ReadString('\n')
+		err = createToken(cfg, strings.TrimSpace(name), ro != "n" && ro != "no", strings.TrimSpace(expires))
+	case "2":
+		fmt.Print("Token name: ")
+		name, _ := reader.ReadString('\n')
+		if err = cfg.RemoveToken(strings.TrimSpace(name)); err == nil {
+			err = cfg.Save()
+		}
+		if err == nil {
+			fmt.Println("Token revoked.")
+		}
+	default:
+		fmt.Println("Invalid option")
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	pressEnterToContinue()
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bandwidth-monitor/config"
+	"bandwidth-monitor/sshclient"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// defaultImportConcurrency is how many servers are set up at once
+const defaultImportConcurrency = 5
+
+// Import result statuses
+const (
+	importAdded     = "added"
+	importExists    = "exists"
+	importReachable = "reachable" // Dry run succeeded
+	importFailed    = "failed"
+)
+
+// importResult is the outcome of importing one server
+type importResult struct {
+	Name       string   `json:"name"`
+	IP         string   `json:"ip"`
+	Status     string   `json:"status"`
+	Interfaces []string `json:"interfaces,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// parseWithFile parses flags that may come before or after a single
+// positional file argument and returns the file
+func (c *serverCommand) parseWithFile(fs *flag.FlagSet, args []string) string {
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		return ""
+	}
+	file := fs.Arg(0)
+	parseFlags(fs, fs.Args()[1:])
+	if fs.NArg() > 0 {
+		c.fail(exitUsage, fmt.Errorf("unexpected argument '%s'", fs.Arg(0)))
+	}
+	return file
+}
+
+// runImportCommand handles "bandwidth-monitor import <file> ..."
+func runImportCommand(args []string) {
+	c := &serverCommand{action: "import"}
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	formatName := fs.String("format", "", "csv, yaml or ansible (guessed from the file extension)")
+	concurrency := fs.Int("concurrency", defaultImportConcurrency, "Servers to set up in parallel")
+	dryRun := fs.Bool("dry-run", false, "Only check that every server is reachable; change nothing")
+	var auth authFlags
+	auth.register(fs)
+	fs.BoolVar(&c.json, "json", false, "Print the results as JSON")
+	file := c.parseWithFile(fs, args)
+
+	if file == "" {
+		c.fail(exitUsage, fmt.Errorf("no file given"))
+	}
+	if *concurrency < 1 {
+		c.fail(exitUsage, fmt.Errorf("--concurrency must be at least 1"))
+	}
+	if auth.iface != "" {
+		c.fail(exitUsage, fmt.Errorf("--interface cannot be used with import, set interfaces per server"))
+	}
+	defaults, err := auth.options(io.Discard)
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+
+	format, err := importFormat(*formatName, file)
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+	entries, err := readImportFile(file, format)
+	if err != nil {
+		c.fail(exitFailure, err)
+	}
+
+	cfg := c.loadConfig()
+	if !*dryRun {
+		// Generate the monitor's key once instead of racing in every setup
+		if _, _, err := sshclient.GenerateSSHKey(); err != nil {
+			c.fail(exitFailure, fmt.Errorf("failed to generate SSH key: %w", err))
+		}
+	}
+
+	results := importServers(cfg, entries, defaults, *concurrency, *dryRun, c.log())
+
+	failed := 0
+	added := false
+	for i, r := range results {
+		switch r.Status {
+		case importFailed:
+			failed++
+		case importAdded:
+			server := entries[i].Server
+			server.Interfaces = r.Interfaces
+			if server.Collector == "" && defaults.Collector != config.CollectorVnStat {
+				server.Collector = defaults.Collector
+			}
+			if err := cfg.AddServer(server); err != nil {
+				results[i].Status, results[i].Error = importFailed, err.Error()
+				failed++
+				continue
+			}
+			added = true
+		}
+	}
+	if added {
+		if err := cfg.Save(); err != nil {
+			c.fail(exitFailure, fmt.Errorf("failed to save config: %w", err))
+		}
+	}
+
+	if c.json {
+		json.NewEncoder(os.Stdout).Encode(struct {
+			OK      bool           `json:"ok"`
+			Action  string         `json:"action"`
+			Changed bool           `json:"changed"`
+			DryRun  bool           `json:"dry_run"`
+			Results []importResult `json:"results"`
+		}{failed == 0, c.action, added, *dryRun, results})
+	} else {
+		printImportSummary(results)
+	}
+	if failed > 0 {
+		os.Exit(exitFailure)
+	}
+}
+
+// importFormat returns the format given by --format, or guesses it from the file
+func importFormat(name, file string) (config.ServerFormat, error) {
+	if name != "" {
+		return config.ParseServerFormat(name)
+	}
+	return config.FormatFromPath(file)
+}
+
+// readImportFile reads servers from a file, or from stdin for "-"
+func readImportFile(file string, format config.ServerFormat) ([]config.ImportEntry, error) {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	entries, err := config.ReadServers(r, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no servers found in %s", file)
+	}
+	return entries, nil
+}
+
+// importServers sets up every new server with at most concurrency setups
+// running at once. In a dry run it only connects to each server. Results
+// are in the order of entries.
+func importServers(cfg *config.Config, entries []config.ImportEntry, defaults setupOptions, concurrency int, dryRun bool, log io.Writer) []importResult {
+	results := make([]importResult, len(entries))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var logMu sync.Mutex
+
+	for i, entry := range entries {
+		s := entry.Server
+		results[i] = importResult{Name: s.Name, IP: s.IP}
+		if cfg.GetServer(s.Name) != nil {
+			results[i].Status = importExists
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *importResult, entry config.ImportEntry) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			interfaces, err := importServer(entry, defaults, dryRun)
+			if err != nil {
+				r.Status, r.Error = importFailed, err.Error()
+			} else {
+				r.Interfaces = interfaces
+				r.Status = importAdded
+				if dryRun {
+					r.Status = importReachable
+				}
+			}
+
+			logMu.Lock()
+			defer logMu.Unlock()
+			if err != nil {
+				fmt.Fprintf(log, "✗ %s: %v\n", r.Name, err)
+			} else {
+				fmt.Fprintf(log, "✓ %s (%s)\n", r.Name, strings.Join(interfaces, ","))
+			}
+		}(&results[i], entry)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// importServer sets up a single server, or only connects to it in a dry run
+func importServer(entry config.ImportEntry, defaults setupOptions, dryRun bool) ([]string, error) {
+	s := entry.Server
+	opts := defaults
+	opts.Log = io.Discard
+	opts.Interfaces = s.Interfaces
+	if s.Collector != "" {
+		opts.Collector = s.Collector
+	}
+
+	// Servers read locally or over HTTP need no setup
+	s.Collector = opts.Collector
+	if !s.UsesSSH() {
+		return s.Interfaces, s.ValidateSource()
+	}
+
+	// Credentials in the file take precedence over the command line
+	switch {
+	case entry.Password != "":
+		opts.Password, opts.KeyFile = entry.Password, ""
+	case entry.PasswordFile != "":
+		password, err := readPasswordFile(entry.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.Password, opts.KeyFile = password, ""
+	case entry.KeyFile != "":
+		opts.Password, opts.KeyFile = "", entry.KeyFile
+	}
+
+	if dryRun {
+		return checkServer(s.IP, s.Port, s.User, opts)
+	}
+	return runServerSetup(s.IP, s.Port, s.User, opts)
+}
+
+// checkServer logs in to a server and returns the interfaces that would be
+// monitored, without installing anything
+func checkServer(ip string, port int, user string, opts setupOptions) ([]string, error) {
+	var monitorKey []byte
+	if opts.Password == "" && opts.KeyFile == "" {
+		key, err := sshclient.LoadPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("no credentials given and no monitor SSH key: %w", err)
+		}
+		monitorKey = []byte(key)
+	}
+
+	client, err := dialSetup(ip, port, user, opts, monitorKey)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	if len(opts.Interfaces) == 0 {
+		iface, err := client.DetectInterface()
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect network interface: %w", err)
+		}
+		return []string{iface}, nil
+	}
+
+	available, err := client.ListInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, want := range opts.Interfaces {
+		found := false
+		for _, a := range available {
+			if a == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("interface %s not found (available: %s)", want, strings.Join(available, ", "))
+		}
+	}
+	return opts.Interfaces, nil
+}
+
+// printImportSummary prints a table of import results and totals
+func printImportSummary(results []importResult) {
+	counts := make(map[string]int)
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tIP\tSTATUS\tDETAIL")
+	for _, r := range results {
+		counts[r.Status]++
+		detail := strings.Join(r.Interfaces, ",")
+		if r.Error != "" {
+			detail = r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, r.IP, r.Status, detail)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Printf("Added: %d, reachable: %d, already configured: %d, failed: %d\n",
+		counts[importAdded], counts[importReachable], counts[importExists], counts[importFailed])
+}
+
+// runExportCommand handles "bandwidth-monitor export [file] [--format ...]"
+func runExportCommand(args []string) {
+	c := &serverCommand{action: "export"}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	formatName := fs.String("format", "", "csv, yaml or ansible (guessed from the file extension, yaml for stdout)")
+	file := c.parseWithFile(fs, args)
+
+	toStdout := file == "" || file == "-"
+	var format config.ServerFormat
+	var err error
+	switch {
+	case *formatName != "":
+		format, err = config.ParseServerFormat(*formatName)
+	case toStdout:
+		format = config.FormatYAML
+	default:
+		format, err = config.FormatFromPath(file)
+	}
+	if err != nil {
+		c.fail(exitUsage, err)
+	}
+
+	servers := c.loadConfig().GetServers()
+
+	if toStdout {
+		if err := config.WriteServers(os.Stdout, format, servers); err != nil {
+			c.fail(exitFailure, err)
+		}
+		return
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		c.fail(exitFailure, fmt.Errorf("failed to create export file: %w", err))
+	}
+	if err := config.WriteServers(f, format, servers); err != nil {
+		f.Close()
+		c.fail(exitFailure, fmt.Errorf("failed to write export file: %w", err))
+	}
+	if err := f.Close(); err != nil {
+		c.fail(exitFailure, fmt.Errorf("failed to write export file: %w", err))
+	}
+	fmt.Fprintf(os.Stderr, "✓ Exported %d server(s) to %s\n", len(servers), file)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bandwidth-monitor/config"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runUserCommand handles "bandwidth-monitor user <action> ..."
+func runUserCommand(args []string) {
+	if len(args) == 0 {
+		printUserUsage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	reader := bufio.NewReader(os.Stdin)
+
+	action, rest := args[0], args[1:]
+	if action == "list" {
+		listUsers(cfg)
+		return
+	}
+	if len(rest) == 0 {
+		printUserUsage()
+		os.Exit(1)
+	}
+	username := rest[0]
+
+	switch action {
+	case "add":
+		role := config.RoleViewer
+		if len(rest) > 1 {
+			if role, err = config.ParseRole(rest[1]); err != nil {
+				break
+			}
+		}
+		err = cfg.AddUser(username, promptPassword(reader), role)
+	case "passwd":
+		err = cfg.SetUserPassword(username, promptPassword(reader))
+	case "role":
+		if len(rest) < 2 {
+			printUserUsage()
+			os.Exit(1)
+		}
+		var role config.Role
+		if role, err = config.ParseRole(rest[1]); err == nil {
+			err = cfg.SetUserRole(username, role)
+		}
+	case "remove":
+		err = cfg.RemoveUser(username)
+	default:
+		fmt.Printf("Unknown user action: %s\n\n", action)
+		printUserUsage()
+		os.Exit(1)
+	}
+
+	if err == nil {
+		err = cfg.Save()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ User '%s' updated\n", username)
+}
+
+func printUserUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  bandwidth-monitor user list")
+	fmt.Println("  bandwidth-monitor user add <name> [viewer|operator|admin]")
+	fmt.Println("  bandwidth-monitor user passwd <name>")
+	fmt.Println("  bandwidth-monitor user role <name> <viewer|operator|admin>")
+	fmt.Println("  bandwidth-monitor user remove <name>")
+}
+
+// listUsers prints all dashboard users and their roles
+func listUsers(cfg *config.Config) {
+	users := cfg.GetUsers()
+	if len(users) == 0 {
+		fmt.Println("No dashboard users configured.")
+		return
+	}
+
+	fmt.Println("Dashboard users:")
+	for _, u := range users {
+		fmt.Printf("  - %-20s %s\n", u.Username, u.Role)
+	}
+}
+
+// promptPassword asks for a non-empty password
+func promptPassword(reader *bufio.Reader) string {
+	for {
+		fmt.Print("Enter password: ")
+		input, _ := reader.ReadString('\n')
+		if pass := strings.TrimSpace(input); pass != "" {
+			return pass
+		}
+		fmt.Println("Password cannot be empty.")
+	}
+}
+
+// manageUsersMenu is the interactive user management screen
+func manageUsersMenu(cfg *config.Config) {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Println()
+	listUsers(cfg)
+	fmt.Println()
+	fmt.Println("1. Add User")
+	fmt.Println("2. Reset Password")
+	fmt.Println("3. Change Role")
+	fmt.Println("4. Remove User")
+	fmt.Print("Select option: ")
+
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	promptRole := func() (config.Role, error) {
+		fmt.Print("Role (viewer/operator/admin) [viewer]: ")
+		role, _ := reader.ReadString('\n')
+		if role = strings.TrimSpace(role); role == "" {
+			return config.RoleViewer, nil
+		}
+		return config.ParseRole(role)
+	}
+
+	var err error
+	switch input {
+	case "1", "2", "3", "4":
+		fmt.Print("Username: ")
+		username, _ := reader.ReadString('\n')
+		username = strings.TrimSpace(username)
+
+		switch input {
+		case "1":
+			var role config.Role
+			if role, err = promptRole(); err == nil {
+				err = cfg.AddUser(username, promptPassword(reader), role)
+			}
+		case "2":
+			err = cfg.SetUserPassword(username, promptPassword(reader))
+		case "3":
+			var role config.Role
+			if role, err = promptRole(); err == nil {
+				err = cfg.SetUserRole(username, role)
+			}
+		case "4":
+			err = cfg.RemoveUser(username)
+		}
+	default:
+		fmt.Println("Invalid option")
+		pressEnterToContinue()
+		return
+	}
+
+	if err == nil {
+		err = cfg.Save()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	} else {
+		fmt.Println("Users saved.")
+	}
+	pressEnterToContinue()
+}
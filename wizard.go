@@ -81,11 +81,15 @@ func runFirstTimeWizard() error {
 			ListenPort:       port,
 			PollInterval:     5, // Default
 			AuthUser:         authUser,
-			AuthPass:         authPass,
 			AuthEnabled:      authEnabled,
 		},
 		Servers: []config.ServerConfig{},
 	}
+	if authPass != "" {
+		if err := cfg.AddUser(authUser, authPass, config.RoleAdmin); err != nil {
+			return fmt.Errorf("failed to create admin user: %v", err)
+		}
+	}
 
 	fmt.Println()
 	fmt.Println("Saving configuration...")